@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/diagnostics"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/check"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+)
+
+// ReportedError reports that a command failed and already wrote why, so yap
+// exits with code 1 without reporting it again
+type ReportedError struct {
+	Reason string
+}
+
+func (e *ReportedError) Error() string {
+	return e.Reason
+}
+
+// CheckCmd parses and type checks the .yap files in args, or the current
+// directory if args is empty, without running them. All diagnostics are
+// written to stdout as selected by opts, and a ReportedError is returned if
+// any of them is an error
+func CheckCmd(args []string, opts diagnostics.Options, stdout io.Writer) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	files, err := collectYAPFiles(args)
+	if err != nil {
+		return err
+	}
+
+	diags := []*yaperror.YapError{}
+	for _, file := range files {
+		diags = append(diags, checkFile(file)...)
+	}
+
+	if err := diagnostics.Write(stdout, opts, diags); err != nil {
+		return err
+	}
+	list := yaperror.NewErrorList()
+	for _, d := range diags {
+		list.Add(d)
+	}
+	if opts.Format == diagnostics.FormatText && list.Len() > 0 {
+		fmt.Fprintf(stdout, "\n%s\n", list.Summary())
+	}
+	if list.HasErrors() {
+		return &ReportedError{Reason: "check failed: " + list.Summary()}
+	}
+	return nil
+}
+
+// checkFile returns the lexer and parser error of file, or the errors and
+// warnings of the type checker and the errors of its imports if it parses
+func checkFile(file string) []*yaperror.YapError {
+	if _, err := os.Stat(file); err != nil {
+		return diagnostics.FromError(fmt.Errorf("error finding file: %w", err))
+	}
+	prog, err := parser.NewParser(file).Parse()
+	if err != nil {
+		return diagnostics.FromError(err)
+	}
+	info := check.Check(prog)
+	diags := info.Errors.Errors()
+	for _, err := range build.NewLoader(build.SearchPathFromEnv()).CheckImports(info) {
+		diags = append(diags, diagnostics.FromError(err)...)
+	}
+	return diags
+}
+
+// ReportError writes an error returned by a command to w as selected by opts.
+// YAP errors in the chain of err are written with their source lines,
+// anything else as a diagnostic without a code or position
+func ReportError(w io.Writer, err error, opts diagnostics.Options) {
+	diagnostics.Write(w, opts, diagnostics.FromError(err))
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/coverage"
+)
+
+// startCoverage returns the option that collects coverage for a run. The
+// returned finish function merges the counts into the profile at path
+func startCoverage(path string) (vm.Option, func() error) {
+	collector := coverage.NewCollector()
+	finish := func() error {
+		if err := collector.Profile().MergeInto(path); err != nil {
+			return fmt.Errorf("error writing coverage profile: %w", err)
+		}
+		return nil
+	}
+	return vm.WithHook(collector), finish
+}
+
+// CoverageOptions selects the reports yap coverage writes
+type CoverageOptions struct {
+	LCOV string // Write an LCOV tracefile to this path
+}
+
+// CoverageCmd writes the annotated source report of the profile in args[0]
+// to stdout, and an LCOV file if requested
+func CoverageCmd(args []string, opts CoverageOptions, stdout io.Writer) error {
+	profile, err := coverage.Load(args[0])
+	if err != nil {
+		return fmt.Errorf("error reading coverage profile: %w", err)
+	}
+
+	if opts.LCOV != "" {
+		f, err := os.Create(opts.LCOV)
+		if err != nil {
+			return fmt.Errorf("error creating LCOV file: %w", err)
+		}
+		err = coverage.WriteLCOV(f, profile)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("error writing LCOV file: %w", err)
+		}
+	}
+
+	return coverage.WriteText(stdout, profile)
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/debugger"
+)
+
+// DebugCmd runs the file in args[0] under the debugger, reading commands
+// from in. The program's output and the debugger's share out, its input
+// statements find an empty input
+func DebugCmd(args []string, breakpoints []int, in io.Reader, out io.Writer, opts ...vm.Option) error {
+	ast, program, err := compile(args[0])
+	if err != nil {
+		return err
+	}
+
+	d := debugger.New(ast.File, program, in, out)
+	for _, line := range breakpoints {
+		if err := d.Break(line); err != nil {
+			return err
+		}
+	}
+
+	opts = append([]vm.Option{vm.WithStdin(strings.NewReader("")), vm.WithStdout(out)}, opts...)
+	opts = append(opts, vm.WithHook(d))
+	if err := vm.New(program, opts...).Run(); err != nil {
+		return fmt.Errorf("error running program: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// ExplainCmd writes the explanation of the error code in args, e.g. E2001,
+// with an example program that reports it and its fix. Without args it
+// lists every code with its title
+func ExplainCmd(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		for _, code := range yaperror.ErrorCodes() {
+			e, _ := yaperror.Explain(code)
+			fmt.Fprintf(stdout, "%s  %s\n", code, e.Title)
+		}
+		return nil
+	}
+
+	code, err := yaperror.ParseErrorCode(args[0])
+	if err != nil {
+		return err
+	}
+	e, ok := yaperror.Explain(code)
+	if !ok {
+		return fmt.Errorf("unknown error code %s, run yap explain to list all codes", code)
+	}
+
+	fmt.Fprintf(stdout, "%s: %s\n\n%s\n", e.Code, e.Title, e.Text)
+	if e.Erroneous != "" {
+		fmt.Fprintf(stdout, "\nErroneous example:\n\n%s", indent(e.Erroneous))
+	}
+	if e.Corrected != "" {
+		fmt.Fprintf(stdout, "\nCorrected example:\n\n%s", indent(e.Corrected))
+	}
+	return nil
+}
+
+// indent indents every line of an example by four spaces
+func indent(src string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(src, "\n") {
+		if line != "" {
+			sb.WriteString("    ")
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/rlamalama/YAP/internal/frontend/format"
+)
+
+// FmtOptions selects what yap fmt does with the formatted source.
+// With no option set the formatted source is written to stdout
+type FmtOptions struct {
+	Write bool // Write the result back to the source file
+	Check bool // Report unformatted files and fail if there are any
+	Diff  bool // Print a unified diff instead of the formatted source
+}
+
+// FmtCmd formats the .yap files and directories in args
+func FmtCmd(args []string, opts FmtOptions, stdout io.Writer) error {
+	files, err := collectYAPFiles(args)
+	if err != nil {
+		return err
+	}
+
+	unformatted := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error reading file: %w", err)
+		}
+
+		out, err := format.Source(src, file)
+		if err != nil {
+			return fmt.Errorf("error formatting %s: %w", file, err)
+		}
+
+		changed := string(src) != string(out)
+		if changed {
+			unformatted++
+		}
+
+		switch {
+		case opts.Diff:
+			if changed {
+				if err := writeDiff(stdout, file, src, out); err != nil {
+					return err
+				}
+			}
+		case opts.Check:
+			if changed {
+				fmt.Fprintln(stdout, file)
+			}
+		case !opts.Write:
+			stdout.Write(out)
+		}
+
+		if opts.Write && changed {
+			info, err := os.Stat(file)
+			if err != nil {
+				return fmt.Errorf("error finding file: %w", err)
+			}
+			if err := os.WriteFile(file, out, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("error writing file: %w", err)
+			}
+		}
+	}
+
+	if opts.Check && unformatted > 0 {
+		return fmt.Errorf("%d file(s) are not formatted", unformatted)
+	}
+	return nil
+}
+
+// collectYAPFiles expands directories in paths to the .yap files they contain
+func collectYAPFiles(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error finding file: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(strings.ToLower(p), FileExtYAP) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error reading directory: %w", err)
+		}
+	}
+	return files, nil
+}
+
+func writeDiff(w io.Writer, file string, before, after []byte) error {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: file + ".orig",
+		ToFile:   file,
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Errorf("error computing diff: %w", err)
+	}
+	_, err = io.WriteString(w, diff)
+	return err
+}
+
+// splitLines splits src after each newline. A last line without a newline
+// is terminated and followed by a marker, like diff(1) does
+func splitLines(src []byte) []string {
+	lines := strings.SplitAfter(string(src), "\n")
+	last := lines[len(lines)-1]
+	lines = lines[:len(lines)-1]
+	if last != "" {
+		lines = append(lines, last+"\n", "\\ No newline at end of file\n")
+	}
+	return lines
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/profile"
+)
+
+// startProfile returns the option that profiles a run. The returned finish
+// function writes the pprof profile to path and the text table to table
+func startProfile(path string, table io.Writer) (vm.Option, func() error) {
+	profiler := profile.New()
+	finish := func() error {
+		profiler.Stop()
+		if table != nil {
+			if err := profiler.WriteText(table); err != nil {
+				return err
+			}
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("error creating profile: %w", err)
+		}
+		err = profiler.WritePprof(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("error writing profile: %w", err)
+		}
+		return nil
+	}
+	return vm.WithHook(profiler), finish
+}
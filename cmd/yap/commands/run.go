@@ -1,44 +1,159 @@
 package commands
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/ir"
 	"github.com/rlamalama/YAP/internal/backend/vm"
 	"github.com/rlamalama/YAP/internal/frontend/parser"
 )
 
 const FileExtYAP = ".yap"
 
-func RunCmd(args []string) {
-	// Accessing flags
-	file := args[0]
+const (
+	// StdinArg is the file argument that reads the program from stdin
+	StdinArg = "-"
 
-	_, err := os.Stat(file)
+	// Display names of programs that are not read from a file
+	StdinName  = "<stdin>"
+	InlineName = "<inline>"
+)
+
+// ExitError reports that the program ended with an exit statement and a
+// non-zero code, which yap run exits with
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("program exited with code %d", e.Code)
+}
+
+func RunCmd(args []string, opts ...vm.Option) error {
+	return RunCmdContext(context.Background(), args, opts...)
+}
+
+// RunCmdContext runs the file in args[0], stopping the program once ctx is
+// done. The other arguments are passed to the program as args
+func RunCmdContext(ctx context.Context, args []string, opts ...vm.Option) error {
+	return runProgram(ctx, args, RunOptions{}, opts)
+}
+
+// RunOptions selects the program yap run runs and the tools it attaches to the run
+type RunOptions struct {
+	Eval        string        // Run this source instead of a file, empty to run args[0]
+	Stdin       io.Reader     // Read the program from here if args[0] is "-", nil for os.Stdin
+	Trace       *TraceOptions // Write a trace, nil to disable
+	Coverage    string        // Merge coverage into this profile, empty to disable
+	Profile     string        // Write a pprof profile to this path, empty to disable
+	ProfileText io.Writer     // Also write the profile as a text table, nil to skip
+}
+
+// RunCmdWithOptions runs the program selected by args and opts like
+// RunCmdContext with the tools in opts attached. Their output is written even
+// if the run fails
+func RunCmdWithOptions(ctx context.Context, args []string, opts RunOptions, vmOpts ...vm.Option) error {
+	if opts.Coverage != "" && (opts.Eval != "" || args[0] == StdinArg) {
+		return errors.New("coverage can only be recorded for a program file")
+	}
+
+	var finishers []func() error
+	if opts.Trace != nil {
+		opt, finish, err := startTrace(*opts.Trace)
+		if err != nil {
+			return err
+		}
+		vmOpts = append(vmOpts, opt)
+		finishers = append(finishers, finish)
+	}
+	if opts.Coverage != "" {
+		opt, finish := startCoverage(opts.Coverage)
+		vmOpts = append(vmOpts, opt)
+		finishers = append(finishers, finish)
+	}
+
+	if opts.Profile != "" {
+		opt, finish := startProfile(opts.Profile, opts.ProfileText)
+		vmOpts = append(vmOpts, opt)
+		finishers = append(finishers, finish)
+	}
+
+	return finishAll(runProgram(ctx, args, opts, vmOpts), finishers)
+}
+
+func runProgram(ctx context.Context, args []string, opts RunOptions, vmOpts []vm.Option) error {
+	_, program, err := compileArgs(args, opts)
 	if err != nil {
-		log.Fatalf("error finding file: %+v", err)
+		return err
 	}
-	if !strings.HasSuffix(strings.ToLower(file), FileExtYAP) {
-		log.Fatalf("file %s must be a .yap or .YAP file", file)
+
+	// Options of the caller come last, so they may replace the arguments
+	vmOpts = append([]vm.Option{vm.WithArgs(programArgs(args, opts))}, vmOpts...)
+	vm := vm.New(program, vmOpts...)
+	if err := vm.RunContext(ctx); err != nil {
+		return fmt.Errorf("error running program: %w", err)
+	}
+	if code := vm.ExitCode(); code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+// programArgs returns the arguments yap run passes to the program: those
+// after the file, or all of them for inline source
+func programArgs(args []string, opts RunOptions) []string {
+	if opts.Eval != "" {
+		return args
 	}
+	return args[1:]
+}
 
-	parser := parser.NewParser(file)
-	ast, err := parser.Parse()
+// compileArgs compiles the program yap run was asked to run: inline source,
+// stdin or a file
+func compileArgs(args []string, opts RunOptions) (*parser.Program, []ir.Instruction, error) {
+	switch {
+	case opts.Eval != "":
+		return compileSource(parser.NewParserFromBytes([]byte(opts.Eval), InlineName))
+	case args[0] == StdinArg:
+		stdin := opts.Stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		return compileSource(parser.NewParserFromReader(stdin, StdinName))
+	default:
+		return compile(args[0])
+	}
+}
+
+// compile parses and builds the .yap file at path
+func compile(file string) (*parser.Program, []ir.Instruction, error) {
+	_, err := os.Stat(file)
 	if err != nil {
-		log.Fatalf("error parsing program: %+v", err)
+		return nil, nil, fmt.Errorf("error finding file: %w", err)
+	}
+	if !strings.HasSuffix(strings.ToLower(file), FileExtYAP) {
+		return nil, nil, fmt.Errorf("file %s must be a .yap or .YAP file", file)
 	}
 
-	builder := build.New()
-	program, err := builder.Build(ast.Statements)
+	return compileSource(parser.NewParser(file))
+}
 
+// compileSource parses and builds the program read by p
+func compileSource(p *parser.Parser) (*parser.Program, []ir.Instruction, error) {
+	ast, err := p.Parse()
 	if err != nil {
-		log.Fatalf("error building program: %+v", err)
+		return nil, nil, fmt.Errorf("error parsing program: %w", err)
 	}
 
-	vm := vm.New(program)
-	if err := vm.Run(); err != nil {
-		log.Fatalf("error running program: %+v", err)
+	program, err := build.New().Build(ast.Statements)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error building program: %w", err)
 	}
+	return ast, program, nil
 }
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/testrunner"
+)
+
+// TestOptions selects the tests yap test runs and the reports it writes
+type TestOptions struct {
+	Run   string // Only run test files whose path matches this regexp
+	JUnit string // Also write a JUnit XML report to this path
+}
+
+// TestCmd runs the *_test.yap files found in args, the current directory if
+// args is empty, and reports the results to stdout
+func TestCmd(args []string, opts TestOptions, stdout io.Writer, vmOpts ...vm.Option) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	var filter *regexp.Regexp
+	if opts.Run != "" {
+		var err error
+		if filter, err = regexp.Compile(opts.Run); err != nil {
+			return fmt.Errorf("invalid --run pattern: %w", err)
+		}
+	}
+
+	files, err := testrunner.Discover(args, filter)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(stdout, "no test files")
+		return nil
+	}
+
+	results := make([]*testrunner.Result, 0, len(files))
+	failed := 0
+	for _, file := range files {
+		r := testrunner.Run(file, vmOpts...)
+		results = append(results, r)
+		if r.Passed() {
+			fmt.Fprintf(stdout, "ok   %s (%.3fs)\n", r.File, r.Duration.Seconds())
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(stdout, "FAIL %s (%.3fs)\n", r.File, r.Duration.Seconds())
+		fmt.Fprintf(stdout, "    %s: %s\n", r.Location(), r.Message())
+		if r.Output != "" {
+			fmt.Fprintln(stdout, "    output:")
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(r.Output, "\n"), "\n") {
+				fmt.Fprintf(stdout, "        %s", line)
+			}
+			fmt.Fprintln(stdout)
+		}
+	}
+
+	if opts.JUnit != "" {
+		if err := writeJUnit(opts.JUnit, results); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(stdout, "\n%d passed, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test file(s) failed", failed, len(results))
+	}
+	return nil
+}
+
+func writeJUnit(path string, results []*testrunner.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating JUnit report: %w", err)
+	}
+	err = testrunner.WriteJUnit(f, results)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing JUnit report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/trace"
+)
+
+// TraceOptions configures the trace written by yap run --trace
+type TraceOptions struct {
+	Path   string // File the trace is written to
+	Format trace.Format
+	Lines  string // Line range to trace, e.g. "10-20"
+}
+
+// startTrace creates the trace file and returns the option that attaches the
+// tracer to a VM. The returned finish function must be called after the run.
+// The options are validated first so an invalid trace leaves an existing file
+// untouched
+func startTrace(opts TraceOptions) (vm.Option, func() error, error) {
+	if err := opts.Format.Validate(); err != nil {
+		return nil, nil, err
+	}
+	lines, err := trace.ParseLineRange(opts.Lines)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Create(opts.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating trace file: %w", err)
+	}
+
+	tracer, err := trace.New(f, opts.Format, lines)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	finish := func() error {
+		err := tracer.Flush()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("error writing trace: %w", err)
+		}
+		return nil
+	}
+	return vm.WithHook(tracer), finish, nil
+}
+
+// finishAll runs the finish functions of tools attached to a run, joining
+// their errors with the error of the run. If they all succeed the error of
+// the run is returned as is, e.g. an *ExitError
+func finishAll(runErr error, finishers []func() error) error {
+	var errs []error
+	for _, finish := range finishers {
+		if err := finish(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return runErr
+	}
+	return errors.Join(append([]error{runErr}, errs...)...)
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"os"
 
 	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/dap"
+	"github.com/rlamalama/YAP/internal/diagnostics"
+	"github.com/rlamalama/YAP/internal/lsp"
+	"github.com/rlamalama/YAP/internal/trace"
 	"github.com/spf13/cobra"
 )
 
@@ -14,27 +20,217 @@ func main() {
 		Use:   "YAP",
 		Short: "YAP is the YAML to Programming CLI",
 		Long:  `YAP is the YAML to Programming CLI`,
+		// Errors are reported once by main, without the usage text
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			color, _ := cmd.Flags().GetString("color")
+			_, err := diagnostics.ParseColorMode(color)
+			return err
+		},
 	}
+	rootCmd.PersistentFlags().String("color", string(diagnostics.ColorAuto), "Colour diagnostics: auto (if the output is a terminal), always or never")
 
 	// 2. Subcommand (e.g., 'hello')
 	var runCmd = &cobra.Command{
-		Use:   "run [file]",
-		Short: "Runs a particular .YAP file",
-		Args:  cobra.MinimumNArgs(1), // Requires at least one argument
-		Run: func(cmd *cobra.Command, args []string) {
-			commands.RunCmd(args)
+		Use:   "run [flags] [file | - | -e source] [args...]",
+		Short: "Runs a particular .YAP file, stdin (-) or inline source (-e)",
+		Long: "Runs a particular .YAP file, stdin (-) or inline source (-e).\n\n" +
+			"The arguments after the file, or all arguments with -e, are passed to the program as args. " +
+			"Flags of yap run go before the file.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			eval, _ := cmd.Flags().GetString("eval")
+			if err := checkDiagnosticsFormat(cmd); err != nil {
+				return err
+			}
+			if eval == "" && len(args) == 0 {
+				return errors.New("run requires a file, - to read stdin, or -e with source")
+			}
+			maxSteps, _ := cmd.Flags().GetInt("max-steps")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+
+			ctx := context.Background()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			opts := commands.RunOptions{Eval: eval}
+			if tracePath, _ := cmd.Flags().GetString("trace"); tracePath != "" {
+				format, _ := cmd.Flags().GetString("trace-format")
+				lines, _ := cmd.Flags().GetString("trace-lines")
+				opts.Trace = &commands.TraceOptions{Path: tracePath, Format: trace.Format(format), Lines: lines}
+			}
+			opts.Coverage, _ = cmd.Flags().GetString("coverage")
+			if opts.Profile, _ = cmd.Flags().GetString("profile"); opts.Profile != "" {
+				opts.ProfileText = os.Stderr
+			}
+
+			vmOpts := []vm.Option{vm.WithStdin(os.Stdin), vm.WithMaxSteps(maxSteps)}
+			if allowEnv, _ := cmd.Flags().GetBool("allow-env"); allowEnv {
+				vmOpts = append(vmOpts, vm.WithEnvLookup(os.LookupEnv))
+			}
+			return commands.RunCmdWithOptions(ctx, args, opts, vmOpts...)
+		},
+	}
+
+	// 3. Define Flags
+	runCmd.Flags().StringP("eval", "e", "", "Run this source instead of a file, e.g. -e '- print: 1 + 2'")
+	runCmd.Flags().Int("max-steps", 0, "Maximum number of instructions to execute (0 means unlimited)")
+	runCmd.Flags().Duration("timeout", 0, "Maximum wall-clock time the program may run, e.g. 5s (0 means unlimited)")
+	runCmd.Flags().String("trace", "", "Write a trace of every executed instruction to this file")
+	runCmd.Flags().String("trace-format", string(trace.FormatText), "Trace format: text or json (one object per line)")
+	runCmd.Flags().String("trace-lines", "", "Only trace instructions from these source lines, e.g. 10-20, 10- or -20")
+	runCmd.Flags().String("coverage", "", "Record statement and branch coverage, merged into this profile")
+	runCmd.Flags().String("profile", "", "Write a pprof profile of time per line to this file and a summary to stderr")
+	runCmd.Flags().Bool("allow-env", false, "Let the program read environment variables with env and env_or")
+	addDiagnosticsFormatFlag(runCmd)
+	// Flags after the file are arguments of the program
+	runCmd.Flags().SetInterspersed(false)
+
+	var checkCmd = &cobra.Command{
+		Use:   "check [files or directories]",
+		Short: "Parses and type checks .YAP files without running them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkDiagnosticsFormat(cmd); err != nil {
+				return err
+			}
+			return commands.CheckCmd(args, diagnosticsOptions(cmd, os.Stdout), os.Stdout)
+		},
+	}
+	addDiagnosticsFormatFlag(checkCmd)
+
+	var fmtCmd = &cobra.Command{
+		Use:   "fmt [files or directories]",
+		Short: "Formats .YAP files",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			write, _ := cmd.Flags().GetBool("write")
+			check, _ := cmd.Flags().GetBool("check")
+			diff, _ := cmd.Flags().GetBool("diff")
+
+			opts := commands.FmtOptions{Write: write, Check: check, Diff: diff}
+			return commands.FmtCmd(args, opts, os.Stdout)
+		},
+	}
+	fmtCmd.Flags().BoolP("write", "w", false, "Write the result to the source file instead of stdout")
+	fmtCmd.Flags().Bool("check", false, "List files that are not formatted and exit with a non-zero status")
+	fmtCmd.Flags().Bool("diff", false, "Print a diff of the changes instead of the formatted source")
+
+	var coverageCmd = &cobra.Command{
+		Use:   "coverage [profile]",
+		Short: "Reports the coverage recorded by yap run --coverage",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lcov, _ := cmd.Flags().GetString("lcov")
+			return commands.CoverageCmd(args, commands.CoverageOptions{LCOV: lcov}, os.Stdout)
+		},
+	}
+	coverageCmd.Flags().String("lcov", "", "Also write an LCOV tracefile to this path")
+
+	var debugCmd = &cobra.Command{
+		Use:   "debug [file]",
+		Short: "Runs a .YAP file under the step debugger",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			breakpoints, _ := cmd.Flags().GetIntSlice("break")
+			return commands.DebugCmd(args, breakpoints, os.Stdin, os.Stdout)
+		},
+	}
+	debugCmd.Flags().IntSliceP("break", "b", nil, "Set breakpoints on these lines before starting")
+
+	var testCmd = &cobra.Command{
+		Use:   "test [files or directories]",
+		Short: "Runs the *_test.yap files in the current or the given directories",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run, _ := cmd.Flags().GetString("run")
+			junit, _ := cmd.Flags().GetString("junit")
+			maxSteps, _ := cmd.Flags().GetInt("max-steps")
+
+			opts := commands.TestOptions{Run: run, JUnit: junit}
+			return commands.TestCmd(args, opts, os.Stdout, vm.WithMaxSteps(maxSteps))
+		},
+	}
+	testCmd.Flags().String("run", "", "Only run test files whose path matches this regular expression")
+	testCmd.Flags().String("junit", "", "Also write a JUnit XML report to this file")
+	testCmd.Flags().Int("max-steps", 0, "Maximum number of instructions each test may execute (0 means unlimited)")
+
+	var explainCmd = &cobra.Command{
+		Use:   "explain [code]",
+		Short: "Explains an error code such as E2001, or lists all codes",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commands.ExplainCmd(args, os.Stdout)
 		},
 	}
 
-	// // 3. Define Flags (e.g., '--loud' or '-l')
-	// runCmd.Flags().BoolP("loud", "l", false, "Shout the greeting")
+	var dapCmd = &cobra.Command{
+		Use:   "dap",
+		Short: "Runs the YAP debug adapter over stdio",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dap.NewServer(os.Stdin, os.Stdout).Serve()
+		},
+	}
 
-	// 4. Add subcommand to root
+	var lspCmd = &cobra.Command{
+		Use:   "lsp",
+		Short: "Runs the YAP language server over stdio",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lsp.NewServer(os.Stdin, os.Stdout).Serve()
+		},
+	}
+
+	// 4. Add subcommands to root
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(fmtCmd)
+	rootCmd.AddCommand(coverageCmd)
+	rootCmd.AddCommand(debugCmd)
+	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(dapCmd)
+	rootCmd.AddCommand(lspCmd)
 
 	// 5. Execute
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		// The program reported its failure itself
+		if exit, ok := err.(*commands.ExitError); ok {
+			os.Exit(exit.Code)
+		}
+		// The command wrote its diagnostics itself
+		if _, ok := err.(*commands.ReportedError); ok {
+			os.Exit(1)
+		}
+		commands.ReportError(os.Stderr, err, diagnosticsOptions(cmd, os.Stderr))
 		os.Exit(1)
 	}
 }
+
+// diagnosticsOptions returns how cmd writes diagnostics to out. Commands
+// without --diagnostics-format write text
+func diagnosticsOptions(cmd *cobra.Command, out *os.File) diagnostics.Options {
+	format, _ := cmd.Flags().GetString("diagnostics-format")
+	color, err := cmd.Flags().GetString("color")
+	if _, parseErr := diagnostics.ParseColorMode(color); err != nil || parseErr != nil {
+		color = string(diagnostics.ColorNever)
+	}
+	return diagnostics.Options{
+		Format: diagnostics.Format(format),
+		Color:  diagnostics.ColorMode(color).Enabled(out),
+	}
+}
+
+func addDiagnosticsFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String("diagnostics-format", string(diagnostics.FormatText), "Diagnostics format: text, json or sarif")
+}
+
+// checkDiagnosticsFormat rejects unknown --diagnostics-format values before
+// the command runs
+func checkDiagnosticsFormat(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("diagnostics-format")
+	_, err := diagnostics.ParseFormat(format)
+	return err
+}
@@ -5,16 +5,36 @@ import (
 
 	"github.com/rlamalama/YAP/internal/backend/ir"
 	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/source"
 )
 
 type Builder struct {
 	instructions []ir.Instruction
+	depth        int     // nesting depth of the statement being built
+	loader       *Loader // loads imported modules, created on the first import if nil
 }
 
-func New() *Builder {
-	return &Builder{}
+// Option configures a Builder created with New
+type Option func(*Builder)
+
+// WithLoader sets the loader of imported modules, e.g. to share the modules
+// between builds or to search other directories than YAP_PATH
+func WithLoader(l *Loader) Option {
+	return func(b *Builder) {
+		b.loader = l
+	}
+}
+
+func New(opts ...Option) *Builder {
+	b := &Builder{}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
 }
 
+// Build compiles stmts into instructions. The compiled program is never
+// modified by a VM, so it can be shared by VMs running in many goroutines
 func (b *Builder) Build(stmts []parser.Stmt) ([]ir.Instruction, error) {
 	for _, stmt := range stmts {
 		if err := b.buildStmt(stmt); err != nil {
@@ -27,10 +47,20 @@ func (b *Builder) Build(stmts []parser.Stmt) ([]ir.Instruction, error) {
 func (b *Builder) buildStmt(stmt parser.Stmt) error {
 	switch s := stmt.(type) {
 	case parser.PrintStmt:
-		b.instructions = append(b.instructions, ir.Instruction{
-			Op:   ir.OpPrint,
-			Expr: s.Expr,
-		})
+		instr := ir.Instruction{
+			Op:    ir.OpPrint,
+			Expr:  s.Expr,
+			Span:  s.Span(),
+			Depth: b.depth,
+		}
+		if s.Sep != nil || s.NoNewline || s.Stderr {
+			instr.Print = &ir.PrintOptions{
+				Sep:       s.Sep,
+				NoNewline: s.NoNewline,
+				Stderr:    s.Stderr,
+			}
+		}
+		b.instructions = append(b.instructions, instr)
 
 	case parser.SetStmt:
 		for _, assignment := range s.Assignment {
@@ -40,7 +70,9 @@ func (b *Builder) buildStmt(stmt parser.Stmt) error {
 					Kind:  ir.OperandIdentifier,
 					Value: assignment.Name,
 				},
-				Expr: assignment.Expr,
+				Expr:  assignment.Expr,
+				Span:  assignmentSpan(assignment),
+				Depth: b.depth,
 			})
 		}
 
@@ -49,6 +81,78 @@ func (b *Builder) buildStmt(stmt parser.Stmt) error {
 			return err
 		}
 
+	case parser.AssertStmt:
+		instr := ir.Instruction{
+			Op:    ir.OpAssert,
+			Expr:  s.Condition,
+			Span:  s.Span(),
+			Depth: b.depth,
+		}
+		if s.Message != nil {
+			instr.Msg = s.Message
+		}
+		b.instructions = append(b.instructions, instr)
+
+	case parser.ExpectErrorStmt:
+		if err := b.buildExpectErrorStmt(s); err != nil {
+			return err
+		}
+
+	case parser.ImportStmt:
+		if b.loader == nil {
+			b.loader = NewLoader(SearchPathFromEnv())
+		}
+		mod, err := b.loader.load(s)
+		if err != nil {
+			return err
+		}
+		b.instructions = append(b.instructions, ir.Instruction{
+			Op: ir.OpImport,
+			Arg: ir.Operand{
+				Kind:  ir.OperandIdentifier,
+				Value: s.Alias,
+			},
+			Module: mod,
+			Span:   s.Span(),
+			Depth:  b.depth,
+		})
+
+	case parser.ForStmt:
+		if err := b.buildForStmt(s); err != nil {
+			return err
+		}
+
+	case parser.CallStmt:
+		b.instructions = append(b.instructions, ir.Instruction{
+			Op:    ir.OpCall,
+			Expr:  s.Call,
+			Span:  s.Span(),
+			Depth: b.depth,
+		})
+
+	case parser.InputStmt:
+		instr := ir.Instruction{
+			Op: ir.OpInput,
+			Arg: ir.Operand{
+				Kind:  ir.OperandIdentifier,
+				Value: s.Name,
+			},
+			Span:  s.Span(),
+			Depth: b.depth,
+		}
+		if s.Prompt != nil {
+			instr.Expr = s.Prompt
+		}
+		b.instructions = append(b.instructions, instr)
+
+	case parser.ExitStmt:
+		b.instructions = append(b.instructions, ir.Instruction{
+			Op:    ir.OpExit,
+			Expr:  s.Code,
+			Span:  s.Span(),
+			Depth: b.depth,
+		})
+
 	default:
 		return fmt.Errorf("unsupported statement %T", stmt)
 	}
@@ -59,11 +163,16 @@ func (b *Builder) buildIfStmt(s parser.IfStmt) error {
 	// Emit jump-if-false instruction with placeholder offset
 	jumpIfFalseIdx := len(b.instructions)
 	b.instructions = append(b.instructions, ir.Instruction{
-		Op:   ir.OpJumpIfFalse,
-		Arg:  ir.Operand{Kind: ir.OperandOffset, Offset: 0}, // placeholder
-		Expr: s.Condition,
+		Op:    ir.OpJumpIfFalse,
+		Arg:   ir.Operand{Kind: ir.OperandOffset, Offset: 0}, // placeholder
+		Expr:  s.Condition,
+		Span:  s.Span(),
+		Depth: b.depth,
 	})
 
+	b.depth++
+	defer func() { b.depth-- }()
+
 	// Build the "then" block
 	for _, stmt := range s.Then {
 		if err := b.buildStmt(stmt); err != nil {
@@ -98,3 +207,78 @@ func (b *Builder) buildIfStmt(s parser.IfStmt) error {
 
 	return nil
 }
+
+func (b *Builder) buildExpectErrorStmt(s parser.ExpectErrorStmt) error {
+	// Emit the start of the block with a placeholder recovery offset
+	startIdx := len(b.instructions)
+	b.instructions = append(b.instructions, ir.Instruction{
+		Op:    ir.OpExpectError,
+		Arg:   ir.Operand{Kind: ir.OperandOffset, Offset: 0}, // placeholder
+		Span:  s.Span(),
+		Depth: b.depth,
+	})
+
+	b.depth++
+	for _, stmt := range s.Body {
+		if err := b.buildStmt(stmt); err != nil {
+			return err
+		}
+	}
+	b.depth--
+
+	// Reached only if no statement of the block failed
+	b.instructions = append(b.instructions, ir.Instruction{
+		Op:  ir.OpEndExpectError,
+		Arg: ir.Operand{Kind: ir.OperandOffset, Offset: startIdx},
+	})
+
+	// A failure in the block recovers after its end
+	b.instructions[startIdx].Arg.Offset = len(b.instructions)
+	return nil
+}
+
+func (b *Builder) buildForStmt(s parser.ForStmt) error {
+	b.instructions = append(b.instructions, ir.Instruction{
+		Op:    ir.OpForStart,
+		Expr:  s.List,
+		Span:  s.Span(),
+		Depth: b.depth,
+	})
+
+	// Every run of the body starts here, with a placeholder offset to leave
+	// the loop after the last item
+	nextIdx := len(b.instructions)
+	b.instructions = append(b.instructions, ir.Instruction{
+		Op: ir.OpForNext,
+		Arg: ir.Operand{
+			Kind:  ir.OperandIdentifier,
+			Value: s.Var,
+		},
+	})
+
+	b.depth++
+	for _, stmt := range s.Body {
+		if err := b.buildStmt(stmt); err != nil {
+			return err
+		}
+	}
+	b.depth--
+
+	b.instructions = append(b.instructions, ir.Instruction{
+		Op:  ir.OpJump,
+		Arg: ir.Operand{Kind: ir.OperandOffset, Offset: nextIdx},
+	})
+
+	// Patch the exit of the loop to after the jump back
+	b.instructions[nextIdx].Arg.Offset = len(b.instructions)
+	return nil
+}
+
+// assignmentSpan covers an assignment from its name to the end of its value
+func assignmentSpan(a *parser.Assignment) source.Span {
+	span := a.Loc
+	if a.Expr != nil {
+		span.End = a.Expr.Span().End
+	}
+	return span
+}
@@ -375,3 +375,93 @@ func TestBuildNestedIf(t *testing.T) {
 	require.Equal(t, ir.OpJumpIfFalse, irs[1].Op)
 	require.Equal(t, 4, irs[1].Arg.Offset)
 }
+
+func TestBuildPrintOptions(t *testing.T) {
+	sep := &parser.StringLiteral{Value: ", "}
+	stmts := []parser.Stmt{
+		parser.PrintStmt{Expr: &parser.StringLiteral{Value: "plain"}},
+		parser.PrintStmt{Expr: &parser.StringLiteral{Value: "custom"}, Sep: sep, NoNewline: true},
+	}
+
+	builder := build.New()
+	irs, err := builder.Build(stmts)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(irs))
+
+	// Default settings leave the print options empty
+	require.Nil(t, irs[0].Print)
+
+	require.NotNil(t, irs[1].Print)
+	require.Equal(t, sep, irs[1].Print.Sep)
+	require.True(t, irs[1].Print.NoNewline)
+	require.False(t, irs[1].Print.Stderr)
+}
+
+func TestBuildSpansAndDepth(t *testing.T) {
+	src := "- set:\n  - x: 1 + 2\n- if: x > 1\n  then:\n    - print: x\n  else:\n    - print: 0\n"
+	prog, err := parser.NewParserFromBytes([]byte(src), "spans.yap").Parse()
+	require.NoError(t, err)
+
+	irs, err := build.New().Build(prog.Statements)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(irs))
+
+	// The assignment spans from its name to the end of its value
+	require.Equal(t, 2, irs[0].Span.Start.Line)
+	require.Equal(t, 5, irs[0].Span.Start.Column)
+	require.Equal(t, 13, irs[0].Span.End.Column)
+	require.Equal(t, 0, irs[0].Depth)
+
+	require.Equal(t, ir.OpJumpIfFalse, irs[1].Op)
+	require.Equal(t, 3, irs[1].Span.Start.Line)
+	require.Equal(t, 0, irs[1].Depth)
+
+	require.Equal(t, 5, irs[2].Span.Start.Line)
+	require.Equal(t, 1, irs[2].Depth)
+
+	// The jump over the else block has no source
+	require.Equal(t, ir.OpJump, irs[3].Op)
+	require.Equal(t, 0, irs[3].Span.Start.Line)
+
+	require.Equal(t, 7, irs[4].Span.Start.Line)
+	require.Equal(t, 1, irs[4].Depth)
+}
+
+func TestBuildAssertAndExpectError(t *testing.T) {
+	cond := &parser.BooleanLiteral{Value: true}
+	msg := &parser.StringLiteral{Value: "must hold"}
+	stmts := []parser.Stmt{
+		parser.AssertStmt{Condition: cond},
+		parser.ExpectErrorStmt{Body: []parser.Stmt{
+			parser.AssertStmt{Condition: cond, Message: msg},
+		}},
+		parser.PrintStmt{Expr: &parser.StringLiteral{Value: "after"}},
+	}
+
+	irs, err := build.New().Build(stmts)
+	require.NoError(t, err)
+
+	// Expected instructions:
+	// 0: Assert (no message)
+	// 1: ExpectError (recover at 4)
+	// 2: Assert "must hold"
+	// 3: EndExpectError (block at 1)
+	// 4: Print "after"
+	require.Equal(t, 5, len(irs))
+
+	require.Equal(t, ir.OpAssert, irs[0].Op)
+	require.Equal(t, cond, irs[0].Expr)
+	require.Nil(t, irs[0].Msg)
+
+	require.Equal(t, ir.OpExpectError, irs[1].Op)
+	require.Equal(t, 4, irs[1].Arg.Offset)
+
+	require.Equal(t, ir.OpAssert, irs[2].Op)
+	require.Equal(t, msg, irs[2].Msg)
+	require.Equal(t, 1, irs[2].Depth)
+
+	require.Equal(t, ir.OpEndExpectError, irs[3].Op)
+	require.Equal(t, 1, irs[3].Arg.Offset)
+
+	require.Equal(t, ir.OpPrint, irs[4].Op)
+}
@@ -0,0 +1,197 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/check"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/suggest"
+)
+
+// SearchPathEnv is the environment variable listing directories searched for
+// imports that are not found next to the importing file, separated like PATH
+const SearchPathEnv = "YAP_PATH"
+
+// SearchPathFromEnv returns the directories listed in YAP_PATH
+func SearchPathFromEnv() []string {
+	return filepath.SplitList(os.Getenv(SearchPathEnv))
+}
+
+// Loader finds, parses and builds the modules a program imports. Every file
+// is built once, later imports of it share the module
+type Loader struct {
+	SearchPath []string // Searched in order after the directory of the importing file
+
+	modules map[string]*ir.Module // By absolute path
+	loading []string              // Absolute paths of the files being built, innermost last
+}
+
+func NewLoader(searchPath []string) *Loader {
+	return &Loader{
+		SearchPath: searchPath,
+		modules:    map[string]*ir.Module{},
+	}
+}
+
+// ImportError is an import that cannot be loaded. Err may be located in the
+// imported module, e.g. a cycle closed by one of its imports
+type ImportError struct {
+	Import *parser.ImportStmt
+	Err    error
+}
+
+func (e *ImportError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+// CheckImports loads the modules imported by a checked program like Build
+// would and returns the imports that cannot be loaded, e.g. a missing module
+// or a cycle. Tools report these without building the program
+func (l *Loader) CheckImports(info *check.Info) []*ImportError {
+	var errs []*ImportError
+	for _, sym := range info.Symbols {
+		if sym.Import == nil {
+			continue
+		}
+		if _, err := l.load(*sym.Import); err != nil {
+			errs = append(errs, &ImportError{Import: sym.Import, Err: err})
+		}
+	}
+	return errs
+}
+
+// load returns the module imported by s, building it on first use
+func (l *Loader) load(s parser.ImportStmt) (*ir.Module, error) {
+	importer := ""
+	if s.Loc.File != nil {
+		importer = s.Loc.File.Path
+	}
+
+	if len(l.loading) == 0 && importer != "" {
+		// An import of the program itself, put it on the stack for the
+		// duration so a module importing it back is a cycle
+		root, _ := filepath.Abs(importer)
+		l.loading = []string{root}
+		defer func() { l.loading = nil }()
+	}
+
+	if filepath.Ext(s.Path) == "" {
+		return l.loadBuiltin(importer, s)
+	}
+
+	path, err := l.resolve(importer, s)
+	if err != nil {
+		return nil, err
+	}
+	if mod, ok := l.modules[path]; ok {
+		return mod, nil
+	}
+
+	for i, loading := range l.loading {
+		if loading != path {
+			continue
+		}
+		cycle := []string{}
+		for _, p := range append(l.loading[i:], path) {
+			cycle = append(cycle, displayPath(p))
+		}
+		err := yaperror.NewImportCycleError(importer, s.PathLoc.Start.Line, s.PathLoc.Start.Column, cycle)
+		return nil, withPathSpan(err, s)
+	}
+
+	prog, err := parser.NewParser(displayPath(path)).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	l.loading = append(l.loading, path)
+	instructions, err := New(WithLoader(l)).Build(prog.Statements)
+	l.loading = l.loading[:len(l.loading)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	mod := &ir.Module{Path: path, Instructions: instructions}
+	l.modules[path] = mod
+	return mod, nil
+}
+
+// loadBuiltin returns the builtin module named by s, e.g. math. Paths without
+// a file extension always name builtin modules
+func (l *Loader) loadBuiltin(importer string, s parser.ImportStmt) (*ir.Module, error) {
+	if mod, ok := l.modules[s.Path]; ok {
+		return mod, nil
+	}
+	if _, ok := stdlib.Lookup(s.Path); !ok {
+		err := yaperror.NewModuleNotFoundError(importer, s.PathLoc.Start.Line, s.PathLoc.Start.Column, s.Path, nil)
+		err.AddNote("paths without an extension name builtin modules: " + strings.Join(stdlib.Names(), ", "))
+		if hint := suggest.Hint(s.Path, stdlib.Names()); hint != "" {
+			err.AddHint(hint)
+		}
+		return nil, withPathSpan(err, s)
+	}
+
+	mod := &ir.Module{Path: s.Path, Builtin: true}
+	l.modules[s.Path] = mod
+	return mod, nil
+}
+
+// resolve returns the absolute path of the file imported by s: relative to
+// the directory of the importing file, or else to one of the search path
+func (l *Loader) resolve(importer string, s parser.ImportStmt) (string, error) {
+	dirs := []string{filepath.Dir(importer)}
+	if filepath.IsAbs(s.Path) {
+		dirs = []string{""}
+	} else {
+		dirs = append(dirs, l.SearchPath...)
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, filepath.FromSlash(s.Path))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return filepath.Abs(path)
+		}
+	}
+
+	searched := []string{}
+	if !filepath.IsAbs(s.Path) {
+		searched = dirs
+	}
+	err := yaperror.NewModuleNotFoundError(importer, s.PathLoc.Start.Line, s.PathLoc.Start.Column, s.Path, searched)
+	return "", withPathSpan(err, s)
+}
+
+// withPathSpan points err at the path of the import
+func withPathSpan(err *yaperror.YapError, s parser.ImportStmt) *yaperror.YapError {
+	if f := s.PathLoc.File; f != nil {
+		if line, ok := f.Line(s.PathLoc.Start.Line); ok {
+			err.Context = line
+		}
+	}
+	start := yaperror.Position{File: err.Position.File, Line: s.PathLoc.Start.Line, Column: s.PathLoc.Start.Column}
+	end := yaperror.Position{File: err.Position.File, Line: s.PathLoc.End.Line, Column: s.PathLoc.End.Column}
+	return err.WithSpan(start, end)
+}
+
+// displayPath shortens an absolute path to one relative to the working
+// directory for diagnostics, if it is below it
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
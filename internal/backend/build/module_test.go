@@ -0,0 +1,114 @@
+package build_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates the files in a temporary directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	}
+	return dir
+}
+
+func buildFile(t *testing.T, path string, loader *build.Loader) ([]ir.Instruction, error) {
+	t.Helper()
+	prog, err := parser.NewParser(path).Parse()
+	require.NoError(t, err)
+	return build.New(build.WithLoader(loader)).Build(prog.Statements)
+}
+
+func TestBuildImport(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.yap":     "- import: \"lib/util.yap\"\n- import: \"lib/util.yap\"\n  as: u\n",
+		"lib/util.yap": "- set:\n  - answer: 42\n",
+	})
+
+	irs, err := buildFile(t, filepath.Join(dir, "main.yap"), build.NewLoader(nil))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(irs))
+
+	require.Equal(t, ir.OpImport, irs[0].Op)
+	assert.Equal(t, "util", irs[0].Arg.Value)
+	assert.Equal(t, filepath.Join(dir, "lib", "util.yap"), irs[0].Module.Path)
+	assert.Equal(t, ir.OpSet, irs[0].Module.Instructions[0].Op)
+
+	// The module is built once and shared by both imports
+	assert.Equal(t, "u", irs[1].Arg.Value)
+	assert.Same(t, irs[0].Module, irs[1].Module)
+}
+
+func TestBuildImportSearchPath(t *testing.T) {
+	lib := writeFiles(t, map[string]string{"util.yap": "- set:\n  - answer: 42\n"})
+	dir := writeFiles(t, map[string]string{"main.yap": "- import: \"util.yap\"\n"})
+
+	irs, err := buildFile(t, filepath.Join(dir, "main.yap"), build.NewLoader([]string{lib}))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(lib, "util.yap"), irs[0].Module.Path)
+}
+
+func TestBuildImportNotFound(t *testing.T) {
+	lib := t.TempDir()
+	dir := writeFiles(t, map[string]string{"main.yap": "- print: 1\n- import: \"util.yap\"\n"})
+
+	_, err := buildFile(t, filepath.Join(dir, "main.yap"), build.NewLoader([]string{lib}))
+
+	var yerr *yaperror.YapError
+	require.ErrorAs(t, err, &yerr)
+	assert.Equal(t, yaperror.ErrModuleNotFound, yerr.Code)
+	assert.Equal(t, 2, yerr.Position.Line)
+	assert.Equal(t, 11, yerr.Position.Column)
+	assert.Equal(t, []string{"searched " + dir, "searched " + lib}, yerr.Notes)
+}
+
+func TestBuildImportCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yap": "- import: \"b.yap\"\n",
+		"b.yap": "- import: \"c.yap\"\n",
+		"c.yap": "- import: \"a.yap\"\n",
+	})
+
+	_, err := buildFile(t, filepath.Join(dir, "a.yap"), build.NewLoader(nil))
+
+	var yerr *yaperror.YapError
+	require.ErrorAs(t, err, &yerr)
+	assert.Equal(t, yaperror.ErrImportCycle, yerr.Code)
+	assert.Equal(t, filepath.Join(dir, "c.yap"), yerr.Position.File)
+	cycle := []string{"a.yap", "b.yap", "c.yap", "a.yap"}
+	for i, name := range cycle {
+		cycle[i] = filepath.Join(dir, name)
+	}
+	assert.Contains(t, yerr.Message, cycle[0]+" -> "+cycle[1]+" -> "+cycle[2]+" -> "+cycle[3])
+}
+
+func TestBuildImportBuiltin(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"main.yap": "- import: \"math\"\n- import: \"math\"\n  as: m\n- import: \"maths\"\n"})
+
+	_, err := buildFile(t, filepath.Join(dir, "main.yap"), build.NewLoader(nil))
+
+	var yerr *yaperror.YapError
+	require.ErrorAs(t, err, &yerr)
+	assert.Equal(t, yaperror.ErrModuleNotFound, yerr.Code)
+	assert.Equal(t, 4, yerr.Position.Line)
+	assert.Equal(t, []string{`did you mean "math"?`}, yerr.Hints)
+
+	dir = writeFiles(t, map[string]string{"main.yap": "- import: \"math\"\n- import: \"math\"\n  as: m\n"})
+	irs, err := buildFile(t, filepath.Join(dir, "main.yap"), build.NewLoader(nil))
+	require.NoError(t, err)
+	assert.Equal(t, &ir.Module{Path: "math", Builtin: true}, irs[0].Module)
+	assert.Same(t, irs[0].Module, irs[1].Module)
+}
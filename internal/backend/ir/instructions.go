@@ -1,16 +1,72 @@
 package ir
 
+import "github.com/rlamalama/YAP/internal/frontend/source"
+
 type OpCode int
 
 const (
 	OpPrint OpCode = iota
 	OpSet
-	OpJumpIfFalse // Jump to Arg.Offset if Expr evaluates to false
-	OpJump        // Unconditional jump to Arg.Offset
+	OpJumpIfFalse    // Jump to Arg.Offset if Expr evaluates to false
+	OpJump           // Unconditional jump to Arg.Offset
+	OpAssert         // Fail unless Expr evaluates to true
+	OpExpectError    // Start a block that must fail, recovering at Arg.Offset
+	OpEndExpectError // End of an expect_error block that did not fail
+	OpImport         // Run Module once and bind its variables to the namespace Arg.Value
+	OpForStart       // Start iterating over the list Expr for the OpForNext that follows
+	OpForNext        // Assign the next item to Arg.Value, or jump to Arg.Offset after the last
+	OpCall           // Call the function of Expr and discard the result
+	OpInput          // Write the prompt Expr, if any, and read a line into Arg.Value
+	OpExit           // End the program with the exit code Expr
 )
 
+var opCodeNames = map[OpCode]string{
+	OpPrint:          "PRINT",
+	OpSet:            "SET",
+	OpJumpIfFalse:    "JUMP_IF_FALSE",
+	OpJump:           "JUMP",
+	OpAssert:         "ASSERT",
+	OpExpectError:    "EXPECT_ERROR",
+	OpEndExpectError: "END_EXPECT_ERROR",
+	OpImport:         "IMPORT",
+	OpForStart:       "FOR_START",
+	OpForNext:        "FOR_NEXT",
+	OpCall:           "CALL",
+	OpInput:          "INPUT",
+	OpExit:           "EXIT",
+}
+
+func (op OpCode) String() string {
+	if name, ok := opCodeNames[op]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
 type Instruction struct {
-	Op   OpCode
-	Arg  Operand
-	Expr interface{} // Holds parser.Value for expression evaluation
+	Op    OpCode
+	Arg   Operand
+	Expr  interface{}   // Holds parser.Value for expression evaluation
+	Print *PrintOptions // Settings of an OpPrint, nil for the defaults
+	Msg   interface{}   // Holds parser.Value for the message of an OpAssert, nil for none
+
+	Module *Module // The module of an OpImport
+
+	Span  source.Span // Source of the statement, zero for instructions the builder adds
+	Depth int         // Nesting depth of the statement, 0 at the top level
+}
+
+// PrintOptions holds the optional settings of an OpPrint instruction
+type PrintOptions struct {
+	Sep       interface{} // Holds parser.Value for the separator, nil for a single space
+	NoNewline bool        // Omit the trailing newline
+	Stderr    bool        // Write to the error writer instead of the output writer
+}
+
+// Module is a compiled .yap file imported by another, or a builtin module
+// implemented in Go. Every import of the same module shares one Module
+type Module struct {
+	Path         string // Absolute path of the file, or the name of a builtin module
+	Builtin      bool   // Implemented by package stdlib, without instructions
+	Instructions []Instruction
 }
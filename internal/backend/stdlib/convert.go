@@ -0,0 +1,60 @@
+package stdlib
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// The conversions are available without an import
+func init() {
+	registerGlobal(newFunc("str", 1, 1, convertStr).returns("string"))
+	registerGlobal(newFunc("int", 1, 1, convertInt).returns("int"))
+	registerGlobal(newFunc("bool", 1, 1, convertBool).returns("bool"))
+}
+
+// convertStr returns its argument as print writes it
+func convertStr(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	return Format(args[0]), nil
+}
+
+// convertInt parses a string as an int, e.g. " 42 ", and truncates floats
+func convertInt(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	switch v := args[0].(type) {
+	case int:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) || v < math.MinInt || v >= math.MaxInt {
+			return nil, yaperror.NewOverflowError(name)
+		}
+		return int(v), nil
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, yaperror.NewConversionError(v, "int")
+		}
+		return n, nil
+	default:
+		return nil, yaperror.NewArgTypeError(name, 1, "string or number", TypeName(v))
+	}
+}
+
+// convertBool parses True or False, also in lower case
+func convertBool(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	switch v := args[0].(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.TrimSpace(v) {
+		case "True", "true":
+			return true, nil
+		case "False", "false":
+			return false, nil
+		}
+		return nil, yaperror.NewConversionError(v, "bool")
+	default:
+		return nil, yaperror.NewArgTypeError(name, 1, "string or bool", TypeName(v))
+	}
+}
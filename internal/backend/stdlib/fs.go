@@ -0,0 +1,153 @@
+package stdlib
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// The functions of the fs module use the filesystem of the running program,
+// see Env. Relative paths are resolved by the filesystem, for the default one
+// against the working directory. Strings have no escape sequences, so lines
+// are written with write_lines and append_line
+func init() {
+	m := &Module{Name: "fs", Members: map[string]interface{}{}}
+	add := func(short string, n int, call func(fsys FS, name, path string, args []interface{}) (interface{}, *yaperror.YapError)) {
+		name := m.Name + "." + short
+		m.Members[short] = &Func{Name: name, Call: func(env Env, args []interface{}) (interface{}, *yaperror.YapError) {
+			if err := arity(name, args, n, n); err != nil {
+				return nil, err
+			}
+			path, ok := args[0].(string)
+			if !ok {
+				return nil, yaperror.NewArgTypeError(name, 1, "string", TypeName(args[0]))
+			}
+			return call(env.FS(), name, path, args)
+		}}
+	}
+
+	add("read_file", 1, fsReadFile)
+	add("read_lines", 1, fsReadLines)
+	add("write_file", 2, fsWriteFile)
+	add("write_lines", 2, fsWriteLines)
+	add("append_file", 2, fsAppendFile)
+	add("append_line", 2, fsAppendLine)
+	add("exists", 1, fsExists)
+	add("list_dir", 1, fsListDir)
+	register(m)
+}
+
+// fileError reports that op failed on the file at path. The path is part of
+// the message, so it is dropped from the errors of package os
+func fileError(op, path string, err error) *yaperror.YapError {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return yaperror.NewFileError(op, path, err)
+}
+
+func fsReadFile(fsys FS, name, path string, args []interface{}) (interface{}, *yaperror.YapError) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, fileError("read", path, err)
+	}
+	return string(data), nil
+}
+
+// fsReadLines returns the lines of a file without their line endings, "\n"
+// or "\r\n". A final line ending does not start another line
+func fsReadLines(fsys FS, name, path string, args []interface{}) (interface{}, *yaperror.YapError) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, fileError("read", path, err)
+	}
+	lines := []interface{}{}
+	if len(data) == 0 {
+		return lines, nil
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		lines = append(lines, strings.TrimSuffix(line, "\r"))
+	}
+	return lines, nil
+}
+
+// fsWriteFile replaces the contents of a file with a string, creating it if
+// needed. Like the other writing functions it returns no value, so it is
+// called with a call statement
+func fsWriteFile(fsys FS, name, path string, args []interface{}) (interface{}, *yaperror.YapError) {
+	strs, err := stringArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := fsys.WriteFile(path, []byte(strs[1])); err != nil {
+		return nil, fileError("write", path, err)
+	}
+	return nil, nil
+}
+
+// fsWriteLines replaces the contents of a file with a list of lines, each
+// written as print writes it and followed by "\n"
+func fsWriteLines(fsys FS, name, path string, args []interface{}) (interface{}, *yaperror.YapError) {
+	items, ok := args[1].([]interface{})
+	if !ok {
+		return nil, yaperror.NewArgTypeError(name, 2, "list", TypeName(args[1]))
+	}
+	var b strings.Builder
+	for _, item := range items {
+		b.WriteString(Format(item))
+		b.WriteByte('\n')
+	}
+	if err := fsys.WriteFile(path, []byte(b.String())); err != nil {
+		return nil, fileError("write", path, err)
+	}
+	return nil, nil
+}
+
+func fsAppendFile(fsys FS, name, path string, args []interface{}) (interface{}, *yaperror.YapError) {
+	strs, err := stringArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := fsys.AppendFile(path, []byte(strs[1])); err != nil {
+		return nil, fileError("append to", path, err)
+	}
+	return nil, nil
+}
+
+// fsAppendLine appends a value as print writes it, followed by "\n"
+func fsAppendLine(fsys FS, name, path string, args []interface{}) (interface{}, *yaperror.YapError) {
+	if err := fsys.AppendFile(path, []byte(Format(args[1])+"\n")); err != nil {
+		return nil, fileError("append to", path, err)
+	}
+	return nil, nil
+}
+
+// fsExists reports whether a file or directory exists. Other failures, e.g.
+// missing permissions, are errors
+func fsExists(fsys FS, name, path string, args []interface{}) (interface{}, *yaperror.YapError) {
+	_, err := fsys.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return nil, fileError("stat", path, err)
+	}
+	return true, nil
+}
+
+// fsListDir returns the sorted names of the files and directories in a
+// directory
+func fsListDir(fsys FS, name, path string, args []interface{}) (interface{}, *yaperror.YapError) {
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		return nil, fileError("list", path, err)
+	}
+	names := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names, nil
+}
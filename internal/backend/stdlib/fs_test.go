@@ -0,0 +1,186 @@
+package stdlib_test
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// env provides the running program to the functions of the stdlib
+type env struct {
+	fs     stdlib.FS
+	stdin  io.Reader
+	args   []string
+	lookup stdlib.EnvLookup
+	memory int
+}
+
+func (e env) FS() stdlib.FS               { return e.fs }
+func (e env) Stdin() io.Reader            { return e.stdin }
+func (e env) Args() []string              { return e.args }
+func (e env) EnvLookup() stdlib.EnvLookup { return e.lookup }
+func (e env) MaxMemory() int              { return e.memory }
+
+func callFS(t *testing.T, fsys stdlib.FS, name string, args ...interface{}) (interface{}, *yaperror.YapError) {
+	t.Helper()
+	m, ok := stdlib.Lookup("fs")
+	require.True(t, ok)
+	fn, ok := m.Members[name].(*stdlib.Func)
+	require.True(t, ok, name)
+	return fn.Call(env{fs: fsys}, args)
+}
+
+func TestFSResults(t *testing.T) {
+	fsys := stdlib.NewMemFS(map[string]string{
+		"in.txt":         "a\r\nb\n\nc\n",
+		"empty.txt":      "",
+		"dir/one.txt":    "1",
+		"dir/sub/two.md": "2",
+	})
+	tests := []struct {
+		name     string
+		args     []interface{}
+		expected interface{}
+	}{
+		{"read_file", []interface{}{"./dir//one.txt"}, "1"},
+		{"read_lines", []interface{}{"in.txt"}, []interface{}{"a", "b", "", "c"}},
+		{"read_lines", []interface{}{"empty.txt"}, []interface{}{}},
+		{"exists", []interface{}{"in.txt"}, true},
+		{"exists", []interface{}{"dir/sub"}, true},
+		{"exists", []interface{}{"nope.txt"}, false},
+		{"list_dir", []interface{}{"dir"}, []interface{}{"one.txt", "sub"}},
+		{"list_dir", []interface{}{"."}, []interface{}{"dir", "empty.txt", "in.txt"}},
+	}
+	for _, tt := range tests {
+		got, err := callFS(t, fsys, tt.name, tt.args...)
+		require.Nil(t, err, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.expected, got, "%s%v", tt.name, tt.args)
+	}
+}
+
+func TestFSWrite(t *testing.T) {
+	fsys := stdlib.NewMemFS(nil)
+
+	val, err := callFS(t, fsys, "write_file", "out/report.txt", "total: ")
+	require.Nil(t, err)
+	assert.Nil(t, val)
+	_, err = callFS(t, fsys, "append_file", "out/report.txt", "3")
+	require.Nil(t, err)
+	_, err = callFS(t, fsys, "append_line", "out/report.txt", 1.0)
+	require.Nil(t, err)
+	data, readErr := fsys.ReadFile("out/report.txt")
+	require.NoError(t, readErr)
+	assert.Equal(t, "total: 31.0\n", string(data))
+
+	_, err = callFS(t, fsys, "write_lines", "out/list.txt", []interface{}{"a", 2, true})
+	require.Nil(t, err)
+	lines, err := callFS(t, fsys, "read_lines", "out/list.txt")
+	require.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "2", "true"}, lines)
+
+	names, err := callFS(t, fsys, "list_dir", "out")
+	require.Nil(t, err)
+	assert.Equal(t, []interface{}{"list.txt", "report.txt"}, names)
+}
+
+func TestFSErrors(t *testing.T) {
+	fsys := stdlib.NewMemFS(map[string]string{"dir/a.txt": "a"})
+	tests := []struct {
+		name string
+		args []interface{}
+		code yaperror.ErrorCode
+		msg  string
+	}{
+		{"read_file", []interface{}{"missing.txt"}, yaperror.ErrIOError, `cannot read "missing.txt": file does not exist`},
+		{"read_lines", []interface{}{"dir"}, yaperror.ErrIOError, `cannot read "dir": is a directory`},
+		{"write_file", []interface{}{"dir", "x"}, yaperror.ErrIOError, `cannot write "dir": is a directory`},
+		{"append_line", []interface{}{"dir", "x"}, yaperror.ErrIOError, `cannot append to "dir": is a directory`},
+		{"write_file", []interface{}{"dir/a.txt/b.txt", "x"}, yaperror.ErrIOError, `cannot write "dir/a.txt/b.txt": not a directory`},
+		{"append_file", []interface{}{"dir/a.txt/sub/b.txt", "x"}, yaperror.ErrIOError, `cannot append to "dir/a.txt/sub/b.txt": not a directory`},
+		{"list_dir", []interface{}{"dir/a.txt"}, yaperror.ErrIOError, `cannot list "dir/a.txt": not a directory`},
+		{"list_dir", []interface{}{"nope"}, yaperror.ErrIOError, `cannot list "nope": file does not exist`},
+		{"read_file", []interface{}{1}, yaperror.ErrInvalidType, "argument 1 of fs.read_file must be a string, got int"},
+		{"write_file", []interface{}{"a.txt", 1}, yaperror.ErrInvalidType, "argument 2 of fs.write_file must be a string, got int"},
+		{"write_lines", []interface{}{"a.txt", "x"}, yaperror.ErrInvalidType, "argument 2 of fs.write_lines must be a list, got string"},
+		{"exists", []interface{}{}, yaperror.ErrInvalidArgCount, "fs.exists expects 1 argument, got 0"},
+	}
+	for _, tt := range tests {
+		_, err := callFS(t, fsys, tt.name, tt.args...)
+		require.NotNil(t, err, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.code, err.Code, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.msg, err.Message)
+	}
+}
+
+// A path of a MemFS is a file or a directory, and a failed write leaves the
+// files as they were
+func TestMemFSPathConflicts(t *testing.T) {
+	fsys := stdlib.NewMemFS(map[string]string{"dir/a.txt": "a"})
+
+	err := fsys.WriteFile("./dir", []byte("x"))
+	require.Error(t, err)
+	assert.Equal(t, "open ./dir: is a directory", err.Error())
+	err = fsys.AppendFile("dir/a.txt/b.txt", []byte("x"))
+	require.Error(t, err)
+	assert.Equal(t, "open dir/a.txt/b.txt: not a directory", err.Error())
+
+	info, statErr := fsys.Stat("dir/a.txt")
+	require.NoError(t, statErr)
+	assert.False(t, info.IsDir())
+	_, statErr = fsys.Stat("dir/a.txt/b.txt")
+	assert.ErrorIs(t, statErr, fs.ErrNotExist)
+	entries, dirErr := fsys.ReadDir("dir")
+	require.NoError(t, dirErr)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "a.txt", entries[0].Name())
+}
+
+func TestOSFS(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+
+	_, err := callFS(t, stdlib.OSFS{}, "write_lines", path, []interface{}{"a", "b"})
+	require.Nil(t, err)
+	_, err = callFS(t, stdlib.OSFS{}, "append_line", path, "c")
+	require.Nil(t, err)
+	lines, err := callFS(t, stdlib.OSFS{}, "read_lines", path)
+	require.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b", "c"}, lines)
+
+	_, err = callFS(t, stdlib.OSFS{}, "read_file", filepath.Join(dir, "missing.txt"))
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrIOError, err.Code)
+	assert.Contains(t, err.Message, `missing.txt": no such file or directory`)
+}
+
+// A RootFS keeps programs in its directory
+func TestRootFS(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "in.txt"), []byte("hi"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	fsys, err := stdlib.NewRootFS(dir)
+	require.NoError(t, err)
+	defer fsys.Close()
+
+	val, yerr := callFS(t, fsys, "read_file", "sub/../in.txt")
+	require.Nil(t, yerr)
+	assert.Equal(t, "hi", val)
+	val, yerr = callFS(t, fsys, "list_dir", ".")
+	require.Nil(t, yerr)
+	assert.Equal(t, []interface{}{"in.txt", "sub"}, val)
+
+	for _, path := range []string{"../outside.txt", filepath.Join(dir, "in.txt")} {
+		_, yerr = callFS(t, fsys, "write_file", path, "x")
+		require.NotNil(t, yerr, path)
+		assert.Equal(t, yaperror.ErrIOError, yerr.Code)
+	}
+	_, statErr := os.Stat(filepath.Join(filepath.Dir(dir), "outside.txt"))
+	assert.True(t, os.IsNotExist(statErr))
+}
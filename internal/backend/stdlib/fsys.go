@@ -0,0 +1,280 @@
+package stdlib
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is the filesystem the fs module reads and writes. Embedders run programs
+// in a sandbox or in memory by passing their own to vm.WithFS. Errors should
+// be *fs.PathError, like those of package os, so the path is reported
+type FS interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte) error  // Creates or truncates the file
+	AppendFile(name string, data []byte) error // Creates the file if needed
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error) // Sorted by name
+}
+
+// OSFS is the filesystem of the operating system, with relative paths
+// resolved against the working directory
+type OSFS struct{}
+
+func (OSFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (OSFS) WriteFile(name string, data []byte) error {
+	return os.WriteFile(name, data, 0o644)
+}
+
+func (OSFS) AppendFile(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (OSFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// RootFS is the part of the operating system's filesystem below a directory.
+// Paths are resolved against the directory and may not leave it, neither
+// with ".." nor through symbolic links
+type RootFS struct {
+	root *os.Root
+}
+
+// NewRootFS opens dir as the root of a RootFS
+func NewRootFS(dir string) (*RootFS, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &RootFS{root: root}, nil
+}
+
+// Close releases the directory
+func (r *RootFS) Close() error {
+	return r.root.Close()
+}
+
+func (r *RootFS) ReadFile(name string) ([]byte, error) {
+	return r.root.ReadFile(name)
+}
+
+func (r *RootFS) WriteFile(name string, data []byte) error {
+	return r.root.WriteFile(name, data, 0o644)
+}
+
+func (r *RootFS) AppendFile(name string, data []byte) error {
+	f, err := r.root.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (r *RootFS) Stat(name string) (fs.FileInfo, error) {
+	return r.root.Stat(name)
+}
+
+func (r *RootFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := r.root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := f.ReadDir(-1)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, err
+}
+
+// MemFS is a filesystem held in memory, e.g. for tests. Directories exist
+// while they contain a file and are created by writing one, so a path is
+// either a file or a directory, never both. It is safe for concurrent use
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string][]byte // Keyed by cleaned path
+}
+
+// NewMemFS returns a MemFS holding files, keyed by path
+func NewMemFS(files map[string]string) *MemFS {
+	m := &MemFS{files: map[string][]byte{}}
+	for name, data := range files {
+		m.files[cleanPath(name)] = []byte(data)
+	}
+	return m
+}
+
+// cleanPath makes equivalent paths equal, e.g. "./a//b" and "a/b"
+func cleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// isDir reports whether a file lies below the directory name
+func (m *MemFS) isDir(name string) bool {
+	if name == "" {
+		return true
+	}
+	for file := range m.files {
+		if strings.HasPrefix(file, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// canCreate reports an error unless a file can be written at clean. It may
+// not be a directory, nor lie below a file
+func (m *MemFS) canCreate(name, clean string) error {
+	if m.isDir(clean) {
+		return &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+	}
+	for dir := path.Dir(clean); dir != "."; dir = path.Dir(dir) {
+		if _, ok := m.files[dir]; ok {
+			return &fs.PathError{Op: "open", Path: name, Err: errNotDir}
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clean := cleanPath(name)
+	data, ok := m.files[clean]
+	if !ok {
+		return nil, m.missing("open", name, clean)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clean := cleanPath(name)
+	if err := m.canCreate(name, clean); err != nil {
+		return err
+	}
+	m.files[clean] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *MemFS) AppendFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clean := cleanPath(name)
+	if err := m.canCreate(name, clean); err != nil {
+		return err
+	}
+	m.files[clean] = append(m.files[clean], data...)
+	return nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clean := cleanPath(name)
+	if data, ok := m.files[clean]; ok {
+		return memEntry{name: path.Base(clean), size: len(data)}, nil
+	}
+	if m.isDir(clean) {
+		return memEntry{name: path.Base(clean), dir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clean := cleanPath(name)
+	if !m.isDir(clean) {
+		if _, ok := m.files[clean]; ok {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	prefix := clean + "/"
+	if clean == "" {
+		prefix = ""
+	}
+	seen := map[string]bool{}
+	var entries []fs.DirEntry
+	for file, data := range m.files {
+		rest, ok := strings.CutPrefix(file, prefix)
+		if !ok {
+			continue
+		}
+		child, _, isDir := strings.Cut(rest, "/")
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+		entry := memEntry{name: child, dir: isDir}
+		if !isDir {
+			entry.size = len(data)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// missing returns the error for a file that does not exist, or is a directory
+func (m *MemFS) missing(op, name, clean string) error {
+	if m.isDir(clean) {
+		return &fs.PathError{Op: "read", Path: name, Err: errIsDir}
+	}
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+var (
+	errIsDir  = errors.New("is a directory")
+	errNotDir = errors.New("not a directory")
+)
+
+// memEntry describes a file or directory of a MemFS
+type memEntry struct {
+	name string
+	size int
+	dir  bool
+}
+
+func (e memEntry) Name() string       { return e.name }
+func (e memEntry) Size() int64        { return int64(e.size) }
+func (e memEntry) ModTime() time.Time { return time.Time{} }
+func (e memEntry) IsDir() bool        { return e.dir }
+func (e memEntry) Sys() interface{}   { return nil }
+
+func (e memEntry) Info() (fs.FileInfo, error) { return e, nil }
+
+func (e memEntry) Mode() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+func (e memEntry) Type() fs.FileMode { return e.Mode().Type() }
@@ -0,0 +1,34 @@
+package stdlib
+
+import (
+	"io"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// read_all_stdin is available without an import, like the conversions
+func init() {
+	registerGlobal(&Func{Name: "read_all_stdin", Call: readAllStdin, Result: "string"})
+}
+
+// readAllStdin returns the rest of the program's input, e.g. the data piped
+// into a script. At the end of the input it returns "". Reading stops once the
+// input exceeds the memory limit
+func readAllStdin(env Env, args []interface{}) (interface{}, *yaperror.YapError) {
+	if err := arity("read_all_stdin", args, 0, 0); err != nil {
+		return nil, err
+	}
+	r := env.Stdin()
+	limit := env.MaxMemory()
+	if limit > 0 {
+		r = io.LimitReader(r, int64(limit)+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, yaperror.NewIOError(err)
+	}
+	if err := checkMemory(env, len(data)); err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,44 @@
+package stdlib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAllStdin(t *testing.T) {
+	val, ok := stdlib.Global("read_all_stdin")
+	require.True(t, ok)
+	fn := val.(*stdlib.Func)
+	var err *yaperror.YapError
+	in := env{stdin: strings.NewReader("a\nb\n")}
+
+	val, err = fn.Call(in, nil)
+	require.Nil(t, err)
+	assert.Equal(t, "a\nb\n", val)
+	val, err = fn.Call(in, nil)
+	require.Nil(t, err)
+	assert.Equal(t, "", val)
+
+	_, err = fn.Call(in, []interface{}{"x"})
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrInvalidArgCount, err.Code)
+}
+
+func TestReadAllStdinMemoryLimit(t *testing.T) {
+	val, ok := stdlib.Global("read_all_stdin")
+	require.True(t, ok)
+	fn := val.(*stdlib.Func)
+
+	val, err := fn.Call(env{stdin: strings.NewReader("abcd"), memory: 4}, nil)
+	require.Nil(t, err)
+	assert.Equal(t, "abcd", val)
+
+	_, err = fn.Call(env{stdin: strings.NewReader("abcde"), memory: 4}, nil)
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+}
@@ -0,0 +1,208 @@
+package stdlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// json_parse and json_stringify convert between JSON text and values: objects
+// are maps, arrays lists and null is Null. Numbers without a fraction or
+// exponent are ints if they fit, other numbers floats
+func init() {
+	registerGlobal(&Func{Name: "json_parse", Call: jsonParse})
+	registerGlobal(&Func{Name: "json_stringify", Call: jsonStringify, Result: "string"})
+}
+
+func jsonParse(_ Env, args []interface{}) (interface{}, *yaperror.YapError) {
+	if err := arity("json_parse", args, 1, 1); err != nil {
+		return nil, err
+	}
+	strs, err := stringArgs("json_parse", args)
+	if err != nil {
+		return nil, err
+	}
+	src := strs[0]
+
+	// Unmarshal reports syntax errors at the offending byte, the decoder
+	// below keeps the keys of objects in order
+	var check interface{}
+	if err := json.Unmarshal([]byte(src), &check); err != nil {
+		return nil, jsonError(src, err)
+	}
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.UseNumber()
+	val, decodeErr := decodeJSON(dec)
+	if decodeErr != nil {
+		return nil, jsonError(src, decodeErr)
+	}
+	return val, nil
+}
+
+// decodeJSON decodes the next value of dec, keeping the keys of objects in
+// order
+func decodeJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			m := NewMap()
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				val, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				m.Set(key.(string), val)
+			}
+			_, err = dec.Token()
+			return m, err
+		}
+		list := []interface{}{}
+		for dec.More() {
+			val, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		_, err = dec.Token()
+		return list, err
+	case json.Number:
+		return jsonNumber(t), nil
+	case nil:
+		return Null, nil
+	default:
+		// A string or a bool
+		return t, nil
+	}
+}
+
+func jsonNumber(n json.Number) interface{} {
+	if !strings.ContainsAny(n.String(), ".eE") {
+		if i, err := strconv.Atoi(n.String()); err == nil {
+			return i
+		}
+	}
+	f, _ := strconv.ParseFloat(n.String(), 64)
+	return f
+}
+
+// jsonError reports err at its line and column in src
+func jsonError(src string, err error) *yaperror.YapError {
+	offset := len(src)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// The offset follows the offending byte
+		offset = int(syntaxErr.Offset) - 1
+	}
+	before := src[:max(0, min(offset, len(src)))]
+	line := strings.Count(before, "\n") + 1
+	col := len(before) - strings.LastIndex(before, "\n")
+	return yaperror.NewDocumentError("JSON", line, col, err.Error())
+}
+
+// jsonStringify writes a value as JSON text. With an indent greater than 0
+// every item is on a line of its own, indented by that many spaces per level
+func jsonStringify(_ Env, args []interface{}) (interface{}, *yaperror.YapError) {
+	if err := arity("json_stringify", args, 1, 2); err != nil {
+		return nil, err
+	}
+	indent := 0
+	if len(args) == 2 {
+		var err *yaperror.YapError
+		if indent, err = intArg("json_stringify", args, 1); err != nil {
+			return nil, err
+		}
+		if indent < 0 {
+			return nil, yaperror.NewRuntimeError("indent of json_stringify must not be negative")
+		}
+	}
+
+	var b bytes.Buffer
+	if err := encodeJSON(&b, args[0]); err != nil {
+		return nil, err
+	}
+	if indent == 0 {
+		return b.String(), nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, b.Bytes(), "", strings.Repeat(" ", indent)); err != nil {
+		return nil, yaperror.NewRuntimeError(err.Error())
+	}
+	return out.String(), nil
+}
+
+// MarshalJSON writes m as a JSON object with its keys in order, so maps can
+// be part of values encoded with encoding/json
+func (m *Map) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	if err := encodeJSON(&b, m); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (null) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+func encodeJSON(b *bytes.Buffer, val interface{}) *yaperror.YapError {
+	switch v := val.(type) {
+	case null:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return yaperror.NewRuntimeError("json_stringify cannot write " + Format(v))
+		}
+		b.WriteString(Format(v))
+	case string:
+		// Unlike json.Marshal, keep <, > and & as they are
+		enc := json.NewEncoder(b)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(v)
+		b.Truncate(b.Len() - 1)
+	case []interface{}:
+		b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := encodeJSON(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case *Map:
+		b.WriteByte('{')
+		for i, key := range v.Keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			_ = encodeJSON(b, key)
+			b.WriteByte(':')
+			if err := encodeJSON(b, v.Values[key]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		return yaperror.NewRuntimeError("json_stringify cannot write a value of type " + TypeName(val))
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package stdlib_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONParse(t *testing.T) {
+	val, err := callGlobal(t, "json_parse", `{"name": "yap", "version": 1.5, "tags": ["a", 2, true, null], "big": 1e3, "huge": 99999999999999999999, "a": {}}`)
+	require.Nil(t, err)
+
+	m, ok := val.(*stdlib.Map)
+	require.True(t, ok)
+	// Keys keep the order of the document
+	assert.Equal(t, []string{"name", "version", "tags", "big", "huge", "a"}, m.Keys)
+	assert.Equal(t, "yap", m.Values["name"])
+	assert.Equal(t, 1.5, m.Values["version"])
+	assert.Equal(t, []interface{}{"a", 2, true, stdlib.Null}, m.Values["tags"])
+	assert.Equal(t, 1000.0, m.Values["big"])
+	assert.Equal(t, 1e20, m.Values["huge"])
+	assert.Equal(t, `{"name": "yap", "version": 1.5, "tags": ["a", 2, true, null], "big": 1000.0, "huge": 1e+20, "a": {}}`, stdlib.Format(m))
+
+	val, err = callGlobal(t, "json_parse", " null ")
+	require.Nil(t, err)
+	assert.Equal(t, stdlib.Null, val)
+}
+
+func TestJSONParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		msg string
+	}{
+		{"", "invalid JSON at line 1, column 1: unexpected end of JSON input"},
+		{`{"a": 1,}`, "invalid JSON at line 1, column 9: invalid character '}' looking for beginning of object key string"},
+		{"[1]\n x", "invalid JSON at line 2, column 2: invalid character 'x' after top-level value"},
+		{"{\"a\":\n  [1,\n   2,,]}", "invalid JSON at line 3, column 6: invalid character ',' looking for beginning of value"},
+	}
+	for _, tt := range tests {
+		_, err := callGlobal(t, "json_parse", tt.src)
+		require.NotNil(t, err, tt.src)
+		assert.Equal(t, yaperror.ErrInvalidDocument, err.Code)
+		assert.Equal(t, tt.msg, err.Message)
+	}
+}
+
+func TestJSONStringify(t *testing.T) {
+	m := stdlib.NewMap()
+	m.Set("b", []interface{}{1, 2.0, "<a & b>\n", stdlib.Null})
+	m.Set("a", stdlib.NewMap())
+	m.Set("b", []interface{}{})
+
+	val, err := callGlobal(t, "json_stringify", m)
+	require.Nil(t, err)
+	assert.Equal(t, `{"b":[],"a":{}}`, val)
+
+	list := []interface{}{1, 2.0, "<a & b>\n", stdlib.Null, true}
+	val, err = callGlobal(t, "json_stringify", list)
+	require.Nil(t, err)
+	assert.Equal(t, `[1,2.0,"<a & b>\n",null,true]`, val)
+
+	m.Set("a", list[:2])
+	val, err = callGlobal(t, "json_stringify", m, 2)
+	require.Nil(t, err)
+	assert.Equal(t, "{\n  \"b\": [],\n  \"a\": [\n    1,\n    2.0\n  ]\n}", val)
+
+	_, err = callGlobal(t, "json_stringify", math.NaN())
+	require.NotNil(t, err)
+	assert.Equal(t, "json_stringify cannot write NaN", err.Message)
+	fn, _ := stdlib.Global("str")
+	_, err = callGlobal(t, "json_stringify", []interface{}{fn})
+	require.NotNil(t, err)
+	assert.Equal(t, "json_stringify cannot write a value of type function", err.Message)
+	_, err = callGlobal(t, "json_stringify", 1, -1)
+	require.NotNil(t, err)
+}
+
+func TestMapMarshalJSON(t *testing.T) {
+	m := stdlib.NewMap()
+	m.Set("b", stdlib.Null)
+	m.Set("a", []interface{}{1, 2.5})
+
+	data, err := json.Marshal(map[string]interface{}{"m": m, "n": stdlib.Null})
+	require.NoError(t, err)
+	assert.Equal(t, `{"m":{"b":null,"a":[1,2.5]},"n":null}`, string(data))
+
+	m.Set("c", math.Inf(1))
+	_, err = json.Marshal(m)
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package stdlib
+
+import (
+	"math"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+func init() {
+	m := &Module{Name: "math", Members: map[string]interface{}{
+		"pi": math.Pi,
+		"e":  math.E,
+	}}
+	fns := map[string]struct {
+		min, max int
+		call     func(name string, args []interface{}) (interface{}, *yaperror.YapError)
+	}{
+		"abs":   {1, 1, mathAbs},
+		"min":   {1, -1, mathMin},
+		"max":   {1, -1, mathMax},
+		"pow":   {2, 2, mathPow},
+		"sqrt":  {1, 1, mathSqrt},
+		"floor": {1, 1, roundWith(math.Floor)},
+		"ceil":  {1, 1, roundWith(math.Ceil)},
+		"round": {1, 1, roundWith(math.Round)},
+		"mod":   {2, 2, mathMod},
+		"int":   {1, 1, roundWith(math.Trunc)},
+		"float": {1, 1, mathFloat},
+	}
+	for short, fn := range fns {
+		name := m.Name + "." + short
+		m.Members[short] = &Func{Name: name, Call: func(_ Env, args []interface{}) (interface{}, *yaperror.YapError) {
+			if err := arity(name, args, fn.min, fn.max); err != nil {
+				return nil, err
+			}
+			for i, arg := range args {
+				if _, _, ok := number(arg); !ok {
+					return nil, yaperror.NewArgTypeError(name, i+1, "number", TypeName(arg))
+				}
+			}
+			return fn.call(name, args)
+		}}
+	}
+	register(m)
+}
+
+// number returns val as an int or as a float, reporting which one it is
+func number(val interface{}) (i int, f float64, ok bool) {
+	switch v := val.(type) {
+	case int:
+		return v, float64(v), true
+	case float64:
+		return 0, v, true
+	default:
+		return 0, 0, false
+	}
+}
+
+func isFloat(val interface{}) bool {
+	_, ok := val.(float64)
+	return ok
+}
+
+func mathAbs(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	if f, ok := args[0].(float64); ok {
+		return math.Abs(f), nil
+	}
+	n := args[0].(int)
+	if n == math.MinInt {
+		return nil, yaperror.NewOverflowError(name)
+	}
+	if n < 0 {
+		return -n, nil
+	}
+	return n, nil
+}
+
+func mathMin(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	return pick(args, func(a, b float64) bool { return a < b }), nil
+}
+
+func mathMax(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	return pick(args, func(a, b float64) bool { return a > b }), nil
+}
+
+// pick returns the argument that is better than all others. The result is a
+// float if any argument is
+func pick(args []interface{}, better func(a, b float64) bool) interface{} {
+	best := args[0]
+	anyFloat := isFloat(best)
+	for _, arg := range args[1:] {
+		_, a, _ := number(arg)
+		_, b, _ := number(best)
+		if better(a, b) {
+			best = arg
+		}
+		anyFloat = anyFloat || isFloat(arg)
+	}
+	if _, f, _ := number(best); anyFloat {
+		return f
+	}
+	return best
+}
+
+func mathPow(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	base, fbase, _ := number(args[0])
+	exp, fexp, _ := number(args[1])
+	if isFloat(args[0]) || isFloat(args[1]) || exp < 0 {
+		return math.Pow(fbase, fexp), nil
+	}
+
+	// Integer powers by squaring, failing instead of wrapping around
+	result := 1
+	for exp > 0 {
+		var ok bool
+		if exp&1 == 1 {
+			if result, ok = mulInt(result, base); !ok {
+				return nil, yaperror.NewOverflowError(name)
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = mulInt(base, base); !ok {
+				return nil, yaperror.NewOverflowError(name)
+			}
+		}
+	}
+	return result, nil
+}
+
+// mulInt multiplies a and b, reporting false if the product overflows
+func mulInt(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+		return 0, false
+	}
+	return c, true
+}
+
+func mathSqrt(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	_, f, _ := number(args[0])
+	if f < 0 {
+		return nil, yaperror.NewRuntimeError(name + ": square root of a negative number")
+	}
+	return math.Sqrt(f), nil
+}
+
+// roundWith returns a function rounding its argument to an int with round
+func roundWith(round func(float64) float64) func(string, []interface{}) (interface{}, *yaperror.YapError) {
+	return func(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+		f, ok := args[0].(float64)
+		if !ok {
+			return args[0], nil
+		}
+		r := round(f)
+		// float64(math.MaxInt) rounds up to 2^63, which does not fit
+		if math.IsNaN(r) || r < math.MinInt || r >= math.MaxInt {
+			return nil, yaperror.NewOverflowError(name)
+		}
+		return int(r), nil
+	}
+}
+
+// mathMod returns the remainder of dividing the arguments, with the sign of
+// the first one like Go and C
+func mathMod(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	x, fx, _ := number(args[0])
+	y, fy, _ := number(args[1])
+	if fy == 0 {
+		return nil, yaperror.NewRuntimeError("division by zero")
+	}
+	if isFloat(args[0]) || isFloat(args[1]) {
+		return math.Mod(fx, fy), nil
+	}
+	if y == -1 {
+		// math.MinInt % -1 panics on some platforms
+		return 0, nil
+	}
+	return x % y, nil
+}
+
+func mathFloat(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	_, f, _ := number(args[0])
+	return f, nil
+}
@@ -0,0 +1,95 @@
+package stdlib_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func call(t *testing.T, name string, args ...interface{}) (interface{}, *yaperror.YapError) {
+	t.Helper()
+	m, ok := stdlib.Lookup("math")
+	require.True(t, ok)
+	fn, ok := m.Members[name].(*stdlib.Func)
+	require.True(t, ok, name)
+	return fn.Call(nil, args)
+}
+
+func TestMathResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []interface{}
+		expected interface{}
+	}{
+		{"abs", []interface{}{-3}, 3},
+		{"abs", []interface{}{-2.5}, 2.5},
+		{"min", []interface{}{3, 1, 2}, 1},
+		{"min", []interface{}{3, 1.5}, 1.5},
+		{"min", []interface{}{1, 2.5}, 1.0},
+		{"max", []interface{}{7}, 7},
+		{"max", []interface{}{1, 3, 2}, 3},
+		{"pow", []interface{}{2, 10}, 1024},
+		{"pow", []interface{}{-3, 3}, -27},
+		{"pow", []interface{}{5, 0}, 1},
+		{"pow", []interface{}{2, -1}, 0.5},
+		{"pow", []interface{}{4, 0.5}, 2.0},
+		{"sqrt", []interface{}{16}, 4.0},
+		{"sqrt", []interface{}{0}, 0.0},
+		{"floor", []interface{}{2.7}, 2},
+		{"floor", []interface{}{-2.5}, -3},
+		{"ceil", []interface{}{2.1}, 3},
+		{"round", []interface{}{2.5}, 3},
+		{"round", []interface{}{-2.5}, -3},
+		{"round", []interface{}{4}, 4},
+		{"mod", []interface{}{7, 3}, 1},
+		{"mod", []interface{}{-7, 3}, -1},
+		{"mod", []interface{}{math.MinInt, -1}, 0},
+		{"mod", []interface{}{7.5, 2}, 1.5},
+		{"int", []interface{}{-2.7}, -2},
+		{"float", []interface{}{3}, 3.0},
+	}
+	for _, tt := range tests {
+		got, err := call(t, tt.name, tt.args...)
+		require.Nil(t, err, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.expected, got, "%s%v", tt.name, tt.args)
+	}
+}
+
+func TestMathErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []interface{}
+		code yaperror.ErrorCode
+		msg  string
+	}{
+		{"sqrt", []interface{}{-1}, yaperror.ErrInvalidType, "math.sqrt: square root of a negative number"},
+		{"pow", []interface{}{2, 63}, yaperror.ErrInvalidType, "math.pow: result does not fit in an integer"},
+		{"pow", []interface{}{-2, 64}, yaperror.ErrInvalidType, "math.pow: result does not fit in an integer"},
+		{"abs", []interface{}{math.MinInt}, yaperror.ErrInvalidType, "math.abs: result does not fit in an integer"},
+		{"floor", []interface{}{1e300}, yaperror.ErrInvalidType, "math.floor: result does not fit in an integer"},
+		{"int", []interface{}{math.NaN()}, yaperror.ErrInvalidType, "math.int: result does not fit in an integer"},
+		{"mod", []interface{}{1, 0}, yaperror.ErrInvalidType, "division by zero"},
+		{"sqrt", []interface{}{"16"}, yaperror.ErrInvalidType, "argument 1 of math.sqrt must be a number, got string"},
+		{"max", []interface{}{1, true}, yaperror.ErrInvalidType, "argument 2 of math.max must be a number, got bool"},
+		{"sqrt", []interface{}{}, yaperror.ErrInvalidArgCount, "math.sqrt expects 1 argument, got 0"},
+		{"pow", []interface{}{2}, yaperror.ErrInvalidArgCount, "math.pow expects 2 arguments, got 1"},
+		{"min", []interface{}{}, yaperror.ErrInvalidArgCount, "math.min expects at least 1 argument, got 0"},
+	}
+	for _, tt := range tests {
+		_, err := call(t, tt.name, tt.args...)
+		require.NotNil(t, err, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.code, err.Code, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.msg, err.Message)
+	}
+}
+
+func TestMathConstants(t *testing.T) {
+	m, _ := stdlib.Lookup("math")
+	assert.Equal(t, math.Pi, m.Members["pi"])
+	assert.Equal(t, math.E, m.Members["e"])
+	assert.Equal(t, []string{"fs", "math", "strings"}, stdlib.Names())
+}
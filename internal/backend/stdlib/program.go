@@ -0,0 +1,90 @@
+package stdlib
+
+import (
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// Var is a builtin variable whose value depends on the running program, e.g.
+// args. Like the functions available without an import, variables of the
+// program shadow it
+type Var struct {
+	Name string
+	Get  func(env Env) interface{}
+}
+
+func (v *Var) String() string {
+	return "<variable " + v.Name + ">"
+}
+
+// EnvLookup looks up an environment variable, like os.LookupEnv
+type EnvLookup func(name string) (string, bool)
+
+func registerVar(v *Var) {
+	globals[v.Name] = v
+}
+
+// args, env and env_or give scripts what they were run with. The environment
+// is only readable if the embedder allows it, see Env
+func init() {
+	registerVar(&Var{Name: "args", Get: programArgs})
+	registerGlobal(&Func{Name: "env", Call: env, Result: "string"})
+	registerGlobal(&Func{Name: "env_or", Call: envOr})
+}
+
+// programArgs returns the arguments after the program's path as a list of
+// strings, empty if there are none
+func programArgs(env Env) interface{} {
+	args := make([]interface{}, len(env.Args()))
+	for i, arg := range env.Args() {
+		args[i] = arg
+	}
+	return args
+}
+
+// env returns the value of an environment variable, failing if it is not set
+func env(e Env, args []interface{}) (interface{}, *yaperror.YapError) {
+	if err := arity("env", args, 1, 1); err != nil {
+		return nil, err
+	}
+	val, ok, err := lookupEnv(e, "env", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, yaperror.NewEnvNotSetError(args[0].(string))
+	}
+	return val, nil
+}
+
+// envOr returns the value of an environment variable, or the default of any
+// type if it is not set
+func envOr(e Env, args []interface{}) (interface{}, *yaperror.YapError) {
+	if err := arity("env_or", args, 2, 2); err != nil {
+		return nil, err
+	}
+	val, ok, err := lookupEnv(e, "env_or", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return args[1], nil
+	}
+	return val, nil
+}
+
+// lookupEnv looks up the environment variable named by the first argument of
+// a call of fn
+func lookupEnv(e Env, fn string, arg interface{}) (string, bool, *yaperror.YapError) {
+	name, ok := arg.(string)
+	if !ok {
+		return "", false, yaperror.NewArgTypeError(fn, 1, "string", TypeName(arg))
+	}
+	lookup := e.EnvLookup()
+	if lookup == nil {
+		err := yaperror.NewEnvNotAllowedError(name)
+		err.AddHint("run the program with --allow-env")
+		return "", false, err
+	}
+	val, ok := lookup(name)
+	return val, ok, nil
+}
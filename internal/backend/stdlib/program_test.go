@@ -0,0 +1,64 @@
+package stdlib_test
+
+import (
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgs(t *testing.T) {
+	val, ok := stdlib.Global("args")
+	require.True(t, ok)
+	args := val.(*stdlib.Var)
+
+	assert.Equal(t, []interface{}{"a", "-v"}, args.Get(env{args: []string{"a", "-v"}}))
+	assert.Equal(t, []interface{}{}, args.Get(env{}))
+}
+
+func TestEnv(t *testing.T) {
+	vars := map[string]string{"HOME": "/home/yap", "EMPTY": ""}
+	allowed := env{lookup: func(name string) (string, bool) {
+		val, ok := vars[name]
+		return val, ok
+	}}
+	call := func(e env, name string, args ...interface{}) (interface{}, *yaperror.YapError) {
+		val, ok := stdlib.Global(name)
+		require.True(t, ok)
+		return val.(*stdlib.Func).Call(e, args)
+	}
+
+	tests := []struct {
+		name     string
+		args     []interface{}
+		expected interface{}
+	}{
+		{"env", []interface{}{"HOME"}, "/home/yap"},
+		{"env", []interface{}{"EMPTY"}, ""},
+		{"env_or", []interface{}{"HOME", "/"}, "/home/yap"},
+		{"env_or", []interface{}{"EMPTY", "x"}, ""},
+		{"env_or", []interface{}{"PORT", 8080}, 8080},
+	}
+	for _, tt := range tests {
+		got, err := call(allowed, tt.name, tt.args...)
+		require.Nil(t, err, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.expected, got, "%s%v", tt.name, tt.args)
+	}
+
+	_, err := call(allowed, "env", "PORT")
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrEnvNotSet, err.Code)
+	assert.Equal(t, `environment variable "PORT" is not set`, err.Message)
+
+	// Without a lookup even env_or fails, rather than hiding the default
+	_, err = call(env{}, "env_or", "HOME", "/")
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrEnvNotAllowed, err.Code)
+	assert.Equal(t, []string{"run the program with --allow-env"}, err.Hints)
+
+	_, err = call(allowed, "env", 1)
+	require.NotNil(t, err)
+	assert.Equal(t, "argument 1 of env must be a string, got int", err.Message)
+}
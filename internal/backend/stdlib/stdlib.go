@@ -0,0 +1,208 @@
+// Package stdlib implements the builtin modules programs import by name, e.g.
+// - import: "math"
+package stdlib
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// Func is a function implemented in Go. Call receives the program running it
+// and the evaluated arguments, and returns errors without a position; the VM
+// points them at the call
+type Func struct {
+	Name string // Qualified name, e.g. math.sqrt
+	Call func(env Env, args []interface{}) (interface{}, *yaperror.YapError)
+
+	// Type of every result as named by TypeName, e.g. "string". Empty if it
+	// depends on the arguments or the input, like the result of json_parse
+	Result string
+}
+
+func (f *Func) String() string {
+	return "<function " + f.Name + ">"
+}
+
+// returns sets the result type of f, see Func.Result
+func (f *Func) returns(result string) *Func {
+	f.Result = result
+	return f
+}
+
+// Env is the part of the running program that functions may use, provided by
+// the VM
+type Env interface {
+	FS() FS               // The filesystem of the fs module
+	Stdin() io.Reader     // The input of the program, shared with input statements
+	Args() []string       // The arguments the program was run with
+	EnvLookup() EnvLookup // Reads environment variables, nil if the program may not
+	MaxMemory() int       // The memory limit in bytes, 0 if there is none
+}
+
+// Module is a builtin module: the functions and constants of its namespace
+type Module struct {
+	Name    string
+	Members map[string]interface{}
+}
+
+var (
+	modules = map[string]*Module{}
+	globals = map[string]interface{}{} // Available without an import
+)
+
+func register(m *Module) {
+	modules[m.Name] = m
+}
+
+func registerGlobal(fn *Func) {
+	globals[fn.Name] = fn
+}
+
+// Global returns the builtin called name that programs use without an
+// import, e.g. str. Variables of the program shadow them
+func Global(name string) (interface{}, bool) {
+	val, ok := globals[name]
+	return val, ok
+}
+
+// GlobalNames returns the names of all builtins available without an import,
+// sorted
+func GlobalNames() []string {
+	names := make([]string, 0, len(globals))
+	for name := range globals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the builtin module called name
+func Lookup(name string) (*Module, bool) {
+	m, ok := modules[name]
+	return m, ok
+}
+
+// Names returns the names of all builtin modules, sorted
+func Names() []string {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TypeName returns the name of the type of a value in diagnostics
+func TypeName(val interface{}) string {
+	switch val.(type) {
+	case int:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case *Map:
+		return "map"
+	case null:
+		return "null"
+	case *Func:
+		return "function"
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
+
+// arity checks that a call of name got between min and max arguments. A
+// negative max means any number of arguments of at least min
+func arity(name string, args []interface{}, min, max int) *yaperror.YapError {
+	if len(args) >= min && (max < 0 || len(args) <= max) {
+		return nil
+	}
+	var want string
+	switch {
+	case min == max:
+		want = fmt.Sprintf("%d", min)
+	case max < 0:
+		want = fmt.Sprintf("at least %d", min)
+	default:
+		want = fmt.Sprintf("%d to %d", min, max)
+	}
+	return yaperror.NewArgCountError(name, want, len(args))
+}
+
+// Format returns val as print writes it. Floats always show a fraction or
+// exponent, so 2.0 is not mistaken for the int 2, and the strings in a list
+// or map are quoted
+func Format(val interface{}) string {
+	switch v := val.(type) {
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eIN") {
+			s += ".0"
+		}
+		return s
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatItem(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *Map:
+		items := make([]string, len(v.Keys))
+		for i, key := range v.Keys {
+			items[i] = strconv.Quote(key) + ": " + formatItem(v.Values[key])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// formatItem formats an item of a list or map, quoting strings
+func formatItem(val interface{}) string {
+	if s, ok := val.(string); ok {
+		return strconv.Quote(s)
+	}
+	return Format(val)
+}
+
+// Map is a map from strings to values, e.g. an object of a JSON document.
+// Its keys keep the order they were set in, so documents keep their order
+type Map struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+func NewMap() *Map {
+	return &Map{Values: map[string]interface{}{}}
+}
+
+// Set sets the value of key, which keeps its place if it was already set
+func (m *Map) Set(key string, val interface{}) {
+	if _, ok := m.Values[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Values[key] = val
+}
+
+func (m *Map) Get(key string) (interface{}, bool) {
+	val, ok := m.Values[key]
+	return val, ok
+}
+
+// Null is the null of JSON and YAML documents. Functions returning no value
+// return nil, so null is a value of its own
+var Null interface{} = null{}
+
+type null struct{}
+
+func (null) String() string { return "null" }
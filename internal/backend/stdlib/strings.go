@@ -0,0 +1,231 @@
+package stdlib
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// The functions of the strings module count and index characters (runes),
+// not bytes, so "é" has length 1
+func init() {
+	m := &Module{Name: "strings", Members: map[string]interface{}{}}
+	add := func(short string, min, max int, call func(name string, args []interface{}) (interface{}, *yaperror.YapError)) {
+		m.Members[short] = newFunc(m.Name+"."+short, min, max, call)
+	}
+
+	add("len", 1, 1, stringsLen)
+	add("upper", 1, 1, mapString(strings.ToUpper))
+	add("lower", 1, 1, mapString(strings.ToLower))
+	add("trim", 1, 1, mapString(strings.TrimSpace))
+	add("split", 2, 2, stringsSplit)
+	add("join", 2, 2, stringsJoin)
+	m.Members["replace"] = &Func{Name: m.Name + ".replace", Call: stringsReplace}
+	add("contains", 2, 2, testStrings(strings.Contains))
+	add("starts_with", 2, 2, testStrings(strings.HasPrefix))
+	add("ends_with", 2, 2, testStrings(strings.HasSuffix))
+	add("index_of", 2, 2, stringsIndexOf)
+	add("substring", 2, 3, stringsSubstring)
+	m.Members["repeat"] = &Func{Name: m.Name + ".repeat", Call: stringsRepeat}
+	register(m)
+}
+
+// newFunc returns a function called name that takes between min and max
+// arguments, see arity
+func newFunc(name string, min, max int, call func(name string, args []interface{}) (interface{}, *yaperror.YapError)) *Func {
+	return &Func{Name: name, Call: func(_ Env, args []interface{}) (interface{}, *yaperror.YapError) {
+		if err := arity(name, args, min, max); err != nil {
+			return nil, err
+		}
+		return call(name, args)
+	}}
+}
+
+// checkMemory fails if a result of size bytes exceeds the memory limit of
+// env. Functions building large strings check before building them
+func checkMemory(env Env, size int) *yaperror.YapError {
+	if limit := env.MaxMemory(); limit > 0 && size > limit {
+		return yaperror.NewMemoryLimitError(limit)
+	}
+	return nil
+}
+
+// stringArgs returns the arguments of a call of name, which must all be strings
+func stringArgs(name string, args []interface{}) ([]string, *yaperror.YapError) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, yaperror.NewArgTypeError(name, i+1, "string", TypeName(arg))
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+// intArg returns argument i of a call of name, which must be an int
+func intArg(name string, args []interface{}, i int) (int, *yaperror.YapError) {
+	n, ok := args[i].(int)
+	if !ok {
+		return 0, yaperror.NewArgTypeError(name, i+1, "int", TypeName(args[i]))
+	}
+	return n, nil
+}
+
+func stringsLen(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	switch v := args[0].(type) {
+	case string:
+		return utf8.RuneCountInString(v), nil
+	case []interface{}:
+		return len(v), nil
+	default:
+		return nil, yaperror.NewArgTypeError(name, 1, "string or list", TypeName(v))
+	}
+}
+
+func mapString(f func(string) string) func(string, []interface{}) (interface{}, *yaperror.YapError) {
+	return func(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+		strs, err := stringArgs(name, args)
+		if err != nil {
+			return nil, err
+		}
+		return f(strs[0]), nil
+	}
+}
+
+func testStrings(f func(s, sub string) bool) func(string, []interface{}) (interface{}, *yaperror.YapError) {
+	return func(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+		strs, err := stringArgs(name, args)
+		if err != nil {
+			return nil, err
+		}
+		return f(strs[0], strs[1]), nil
+	}
+}
+
+// stringsSplit splits a string around a separator into a list. An empty
+// separator splits it into its characters
+func stringsSplit(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	strs, err := stringArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(strs[0], strs[1])
+	list := make([]interface{}, len(parts))
+	for i, part := range parts {
+		list[i] = part
+	}
+	return list, nil
+}
+
+func stringsJoin(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	list, ok := args[0].([]interface{})
+	if !ok {
+		return nil, yaperror.NewArgTypeError(name, 1, "list", TypeName(args[0]))
+	}
+	sep, ok := args[1].(string)
+	if !ok {
+		return nil, yaperror.NewArgTypeError(name, 2, "string", TypeName(args[1]))
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, yaperror.NewRuntimeError(name + ": list items must be strings, got " + TypeName(item))
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func stringsReplace(env Env, args []interface{}) (interface{}, *yaperror.YapError) {
+	const name = "strings.replace"
+	if err := arity(name, args, 3, 3); err != nil {
+		return nil, err
+	}
+	strs, err := stringArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	s, old, repl := strs[0], strs[1], strs[2]
+
+	// An empty old matches before every character and at the end
+	size := len(s) + strings.Count(s, old)*(len(repl)-len(old))
+	if err := checkMemory(env, size); err != nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(s, old, repl), nil
+}
+
+// stringsIndexOf returns the index of the first character of the first
+// occurrence of a substring, or -1 if there is none
+func stringsIndexOf(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	strs, err := stringArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.Index(strs[0], strs[1])
+	if i < 0 {
+		return -1, nil
+	}
+	return utf8.RuneCountInString(strs[0][:i]), nil
+}
+
+// stringsSubstring returns the characters from start up to, but excluding,
+// end. Without end it returns the rest of the string
+func stringsSubstring(name string, args []interface{}) (interface{}, *yaperror.YapError) {
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, yaperror.NewArgTypeError(name, 1, "string", TypeName(args[0]))
+	}
+	runes := []rune(s)
+	start, err := intArg(name, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	end := len(runes)
+	if len(args) > 2 {
+		if end, err = intArg(name, args, 2); err != nil {
+			return nil, err
+		}
+	}
+
+	if start < 0 || start > len(runes) {
+		return nil, yaperror.NewOutOfBoundsError(start, len(runes))
+	}
+	if end < start || end > len(runes) {
+		return nil, yaperror.NewOutOfBoundsError(end, len(runes))
+	}
+	return string(runes[start:end]), nil
+}
+
+// stringsRepeat fails before building a result larger than the memory limit
+func stringsRepeat(env Env, args []interface{}) (interface{}, *yaperror.YapError) {
+	const name = "strings.repeat"
+	if err := arity(name, args, 2, 2); err != nil {
+		return nil, err
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, yaperror.NewArgTypeError(name, 1, "string", TypeName(args[0]))
+	}
+	n, err := intArg(name, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, yaperror.NewRuntimeError(name + ": negative count")
+	}
+	if len(s) == 0 {
+		return "", nil
+	}
+	if limit := env.MaxMemory(); limit > 0 && n > limit/len(s) {
+		return nil, yaperror.NewMemoryLimitError(limit)
+	}
+	if n > math.MaxInt32/len(s) {
+		return nil, yaperror.NewRuntimeError(name + ": result too large")
+	}
+	return strings.Repeat(s, n), nil
+}
@@ -0,0 +1,162 @@
+package stdlib_test
+
+import (
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func callMember(t *testing.T, module, name string, args ...interface{}) (interface{}, *yaperror.YapError) {
+	t.Helper()
+	m, ok := stdlib.Lookup(module)
+	require.True(t, ok)
+	fn, ok := m.Members[name].(*stdlib.Func)
+	require.True(t, ok, name)
+	return fn.Call(env{}, args)
+}
+
+func callGlobal(t *testing.T, name string, args ...interface{}) (interface{}, *yaperror.YapError) {
+	t.Helper()
+	val, ok := stdlib.Global(name)
+	require.True(t, ok, name)
+	return val.(*stdlib.Func).Call(nil, args)
+}
+
+func TestStringsResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []interface{}
+		expected interface{}
+	}{
+		{"len", []interface{}{"héllo"}, 5},
+		{"len", []interface{}{"日本語"}, 3},
+		{"len", []interface{}{[]interface{}{"a", "b"}}, 2},
+		{"upper", []interface{}{"héllo"}, "HÉLLO"},
+		{"lower", []interface{}{"ÀÉ"}, "àé"},
+		{"trim", []interface{}{"\t x y \n"}, "x y"},
+		{"split", []interface{}{"a,b,,c", ","}, []interface{}{"a", "b", "", "c"}},
+		{"split", []interface{}{"añb", ""}, []interface{}{"a", "ñ", "b"}},
+		{"join", []interface{}{[]interface{}{"a", "b"}, "-"}, "a-b"},
+		{"join", []interface{}{[]interface{}{}, "-"}, ""},
+		{"replace", []interface{}{"aXbX", "X", "ü"}, "aübü"},
+		{"contains", []interface{}{"héllo", "él"}, true},
+		{"starts_with", []interface{}{"héllo", "hé"}, true},
+		{"ends_with", []interface{}{"héllo", "x"}, false},
+		{"index_of", []interface{}{"日本語", "語"}, 2},
+		{"index_of", []interface{}{"abc", "z"}, -1},
+		{"substring", []interface{}{"日本語", 1, 2}, "本"},
+		{"substring", []interface{}{"日本語", 1}, "本語"},
+		{"substring", []interface{}{"abc", 3, 3}, ""},
+		{"repeat", []interface{}{"é", 3}, "ééé"},
+		{"repeat", []interface{}{"x", 0}, ""},
+	}
+	for _, tt := range tests {
+		got, err := callMember(t, "strings", tt.name, tt.args...)
+		require.Nil(t, err, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.expected, got, "%s%v", tt.name, tt.args)
+	}
+}
+
+func TestStringsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []interface{}
+		code yaperror.ErrorCode
+		msg  string
+	}{
+		{"upper", []interface{}{1}, yaperror.ErrInvalidType, "argument 1 of strings.upper must be a string, got int"},
+		{"len", []interface{}{true}, yaperror.ErrInvalidType, "argument 1 of strings.len must be a string or list, got bool"},
+		{"join", []interface{}{"ab", ""}, yaperror.ErrInvalidType, "argument 1 of strings.join must be a list, got string"},
+		{"join", []interface{}{[]interface{}{"a", 1}, ""}, yaperror.ErrInvalidType, "strings.join: list items must be strings, got int"},
+		{"substring", []interface{}{"abc", -1}, yaperror.ErrOutOfBounds, "index out of bounds: -1 (length: 3)"},
+		{"substring", []interface{}{"日本語", 1, 4}, yaperror.ErrOutOfBounds, "index out of bounds: 4 (length: 3)"},
+		{"substring", []interface{}{"abc", 2, 1}, yaperror.ErrOutOfBounds, "index out of bounds: 1 (length: 3)"},
+		{"substring", []interface{}{"abc", "1"}, yaperror.ErrInvalidType, "argument 2 of strings.substring must be an int, got string"},
+		{"repeat", []interface{}{"ab", -1}, yaperror.ErrInvalidType, "strings.repeat: negative count"},
+		{"repeat", []interface{}{"ab", 1 << 40}, yaperror.ErrInvalidType, "strings.repeat: result too large"},
+		{"replace", []interface{}{"a", "b"}, yaperror.ErrInvalidArgCount, "strings.replace expects 3 arguments, got 2"},
+		{"substring", []interface{}{"a"}, yaperror.ErrInvalidArgCount, "strings.substring expects 2 to 3 arguments, got 1"},
+	}
+	for _, tt := range tests {
+		_, err := callMember(t, "strings", tt.name, tt.args...)
+		require.NotNil(t, err, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.code, err.Code, "%s%v", tt.name, tt.args)
+		assert.Equal(t, tt.msg, err.Message)
+	}
+}
+
+// Functions building long strings fail before building them
+func TestStringsMemoryLimit(t *testing.T) {
+	m, ok := stdlib.Lookup("strings")
+	require.True(t, ok)
+	repeat := m.Members["repeat"].(*stdlib.Func)
+	replace := m.Members["replace"].(*stdlib.Func)
+
+	val, err := repeat.Call(env{memory: 6}, []interface{}{"ab", 3})
+	require.Nil(t, err)
+	assert.Equal(t, "ababab", val)
+
+	_, err = repeat.Call(env{memory: 6}, []interface{}{"ab", 4})
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+
+	// An empty old matches 3 times in "ab"
+	val, err = replace.Call(env{memory: 8}, []interface{}{"ab", "", "xx"})
+	require.Nil(t, err)
+	assert.Equal(t, "xxaxxbxx", val)
+
+	_, err = replace.Call(env{memory: 8}, []interface{}{"ab", "", "xxx"})
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+
+	val, err = replace.Call(env{memory: 8}, []interface{}{"aaaaaaaa", "aa", "b"})
+	require.Nil(t, err)
+	assert.Equal(t, "bbbb", val)
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		name     string
+		arg      interface{}
+		expected interface{}
+	}{
+		{"str", 42, "42"},
+		{"str", 2.0, "2.0"},
+		{"str", true, "true"},
+		{"str", []interface{}{"a", 1}, `["a", 1]`},
+		{"int", " -42 ", -42},
+		{"int", 2.9, 2},
+		{"int", 7, 7},
+		{"bool", "True", true},
+		{"bool", "false", false},
+		{"bool", false, false},
+	}
+	for _, tt := range tests {
+		got, err := callGlobal(t, tt.name, tt.arg)
+		require.Nil(t, err, "%s(%v)", tt.name, tt.arg)
+		assert.Equal(t, tt.expected, got, "%s(%v)", tt.name, tt.arg)
+	}
+
+	for _, tt := range []struct {
+		name string
+		arg  interface{}
+		msg  string
+	}{
+		{"int", "4x2", `cannot convert "4x2" to int`},
+		{"int", "99999999999999999999", `cannot convert "99999999999999999999" to int`},
+		{"int", 1e300, "int: result does not fit in an integer"},
+		{"int", true, "argument 1 of int must be a string or number, got bool"},
+		{"bool", "yes", `cannot convert "yes" to bool`},
+		{"bool", 1, "argument 1 of bool must be a string or bool, got int"},
+	} {
+		_, err := callGlobal(t, tt.name, tt.arg)
+		require.NotNil(t, err, "%s(%v)", tt.name, tt.arg)
+		assert.Equal(t, yaperror.ErrInvalidType, err.Code)
+		assert.Equal(t, tt.msg, err.Message)
+	}
+
+	assert.Equal(t, []string{"args", "bool", "env", "env_or", "int", "json_parse", "json_stringify", "read_all_stdin", "str", "yaml_parse", "yaml_stringify"}, stdlib.GlobalNames())
+}
@@ -0,0 +1,286 @@
+package stdlib
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"gopkg.in/yaml.v3"
+)
+
+// yaml_parse and yaml_stringify convert between YAML text and values like
+// json_parse and json_stringify. Only the first document of a stream is read
+func init() {
+	registerGlobal(&Func{Name: "yaml_parse", Call: yamlParse})
+	registerGlobal(&Func{Name: "yaml_stringify", Call: yamlStringify, Result: "string"})
+}
+
+// yamlErrorLine matches the line yaml.v3 puts in its syntax errors
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+func yamlParse(_ Env, args []interface{}) (interface{}, *yaperror.YapError) {
+	if err := arity("yaml_parse", args, 1, 1); err != nil {
+		return nil, err
+	}
+	strs, err := stringArgs("yaml_parse", args)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(strs[0]), &doc); err != nil {
+		msg := err.Error()
+		line := 1
+		if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			msg = msg[len(m[0]):]
+		}
+		return nil, yaperror.NewDocumentError("YAML", line, 0, strings.TrimPrefix(msg, "yaml: "))
+	}
+	if doc.Kind == 0 {
+		// An empty document
+		return Null, nil
+	}
+	d := &yamlDecoder{
+		anchors: map[*yaml.Node]yamlValue{},
+		budget:  len(strs[0]) + maxYAMLAliasValues,
+	}
+	val, _, yerr := d.decode(&doc)
+	return val, yerr
+}
+
+// maxYAMLAliasValues bounds the values aliases may add to a document. Without
+// aliases a document has at most one value per byte, so only documents
+// expanding aliases over and over, e.g. "billion laughs", exceed it
+const maxYAMLAliasValues = 1 << 20
+
+// yamlDecoder converts the nodes of a document to values. The value of an
+// anchored node is converted once and shared by its aliases, and every use
+// is charged to the budget of values
+type yamlDecoder struct {
+	anchors map[*yaml.Node]yamlValue
+	budget  int
+}
+
+// yamlValue is a converted node with the number of values it holds
+type yamlValue struct {
+	val   interface{}
+	count int
+}
+
+func (d *yamlDecoder) decode(n *yaml.Node) (interface{}, int, *yaperror.YapError) {
+	if n.Kind == yaml.DocumentNode {
+		return d.decode(n.Content[0])
+	}
+	if n.Kind == yaml.AliasNode {
+		v, ok := d.anchors[n.Alias]
+		if !ok {
+			var err *yaperror.YapError
+			if v.val, v.count, err = d.decode(n.Alias); err != nil {
+				return nil, 0, err
+			}
+		}
+		if err := d.charge(n, v.count); err != nil {
+			return nil, 0, err
+		}
+		return v.val, v.count, nil
+	}
+
+	val, count, err := d.convert(n)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n.Anchor != "" {
+		d.anchors[n] = yamlValue{val: val, count: count}
+	}
+	return val, count, nil
+}
+
+// charge takes count values from the budget, failing once it is spent
+func (d *yamlDecoder) charge(n *yaml.Node, count int) *yaperror.YapError {
+	d.budget -= count
+	if d.budget < 0 {
+		return yaperror.NewDocumentError("YAML", n.Line, n.Column, "too many values from aliases")
+	}
+	return nil
+}
+
+// convert converts a node that is not an alias, charging it to the budget
+func (d *yamlDecoder) convert(n *yaml.Node) (interface{}, int, *yaperror.YapError) {
+	if err := d.charge(n, 1); err != nil {
+		return nil, 0, err
+	}
+	switch n.Kind {
+	case yaml.SequenceNode:
+		list := make([]interface{}, len(n.Content))
+		count := 1
+		for i, item := range n.Content {
+			val, c, err := d.decode(item)
+			if err != nil {
+				return nil, 0, err
+			}
+			list[i] = val
+			count += c
+		}
+		return list, count, nil
+
+	case yaml.MappingNode:
+		m := NewMap()
+		count := 1
+		for i := 0; i < len(n.Content); i += 2 {
+			key, item := n.Content[i], n.Content[i+1]
+			val, c, err := d.decode(item)
+			if err != nil {
+				return nil, 0, err
+			}
+			count += c
+			if key.ShortTag() == "!!merge" {
+				mergeYAML(m, val)
+				continue
+			}
+			if key.Kind != yaml.ScalarNode {
+				return nil, 0, yaperror.NewDocumentError("YAML", key.Line, key.Column, "keys must be scalars")
+			}
+			m.Set(key.Value, val)
+		}
+		return m, count, nil
+
+	default:
+		val, err := yamlScalar(n)
+		return val, 1, err
+	}
+}
+
+// mergeYAML merges the map, or list of maps, of a "<<" key into m. Keys set
+// in m itself win
+func mergeYAML(m *Map, val interface{}) {
+	maps, ok := val.([]interface{})
+	if !ok {
+		maps = []interface{}{val}
+	}
+	for _, v := range maps {
+		merged, ok := v.(*Map)
+		if !ok {
+			continue
+		}
+		for _, key := range merged.Keys {
+			if _, exists := m.Get(key); !exists {
+				m.Set(key, merged.Values[key])
+			}
+		}
+	}
+}
+
+// yamlScalar decodes a scalar by its tag. Timestamps and other tags without a
+// value type of their own are strings
+func yamlScalar(n *yaml.Node) (interface{}, *yaperror.YapError) {
+	var err error
+	switch n.ShortTag() {
+	case "!!null":
+		return Null, nil
+	case "!!bool":
+		var b bool
+		if err = n.Decode(&b); err == nil {
+			return b, nil
+		}
+	case "!!int":
+		var i int
+		if n.Decode(&i) == nil {
+			return i, nil
+		}
+		// Too large for an int
+		var f float64
+		if err = n.Decode(&f); err == nil {
+			return f, nil
+		}
+	case "!!float":
+		var f float64
+		if err = n.Decode(&f); err == nil {
+			return f, nil
+		}
+	default:
+		return n.Value, nil
+	}
+	return nil, yaperror.NewDocumentError("YAML", n.Line, n.Column, fmt.Sprintf("cannot read %q as %s", n.Value, n.ShortTag()))
+}
+
+// yamlStringify writes a value as a YAML document, indented by 2 spaces and
+// without a final line break. Maps keep the order of their keys
+func yamlStringify(_ Env, args []interface{}) (interface{}, *yaperror.YapError) {
+	if err := arity("yaml_stringify", args, 1, 1); err != nil {
+		return nil, err
+	}
+	node, err := toYAML(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, yaperror.NewRuntimeError(err.Error())
+	}
+	if err := enc.Close(); err != nil {
+		return nil, yaperror.NewRuntimeError(err.Error())
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func toYAML(val interface{}) (*yaml.Node, *yaperror.YapError) {
+	scalar := func(tag, value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	}
+	switch v := val.(type) {
+	case null:
+		return scalar("!!null", "null"), nil
+	case bool:
+		return scalar("!!bool", strconv.FormatBool(v)), nil
+	case int:
+		return scalar("!!int", strconv.Itoa(v)), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return scalar("!!float", ".nan"), nil
+		case math.IsInf(v, 1):
+			return scalar("!!float", ".inf"), nil
+		case math.IsInf(v, -1):
+			return scalar("!!float", "-.inf"), nil
+		}
+		return scalar("!!float", Format(v)), nil
+	case string:
+		return scalar("!!str", v), nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if len(v) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		for _, item := range v {
+			child, err := toYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case *Map:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if len(v.Keys) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		for _, key := range v.Keys {
+			child, err := toYAML(v.Values[key])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, scalar("!!str", key), child)
+		}
+		return node, nil
+	default:
+		return nil, yaperror.NewRuntimeError("yaml_stringify cannot write a value of type " + TypeName(val))
+	}
+}
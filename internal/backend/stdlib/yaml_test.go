@@ -0,0 +1,109 @@
+package stdlib_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const configYAML = `name: yap
+version: 1.5
+server:
+  port: 8080
+  debug: true
+tags: [a, "true", ~, 99999999999999999999]
+base: &base
+  retries: 3
+  timeout: 10
+prod:
+  <<: *base
+  retries: 5
+created: 2024-01-02
+`
+
+func TestYAMLParse(t *testing.T) {
+	val, err := callGlobal(t, "yaml_parse", configYAML)
+	require.Nil(t, err)
+
+	m, ok := val.(*stdlib.Map)
+	require.True(t, ok)
+	assert.Equal(t, []string{"name", "version", "server", "tags", "base", "prod", "created"}, m.Keys)
+	assert.Equal(t, `{"port": 8080, "debug": true}`, stdlib.Format(m.Values["server"]))
+	assert.Equal(t, []interface{}{"a", "true", stdlib.Null, 1e20}, m.Values["tags"])
+	// Keys of the map itself win over merged ones
+	assert.Equal(t, `{"retries": 5, "timeout": 10}`, stdlib.Format(m.Values["prod"]))
+	assert.Equal(t, "2024-01-02", m.Values["created"])
+
+	val, err = callGlobal(t, "yaml_parse", "")
+	require.Nil(t, err)
+	assert.Equal(t, stdlib.Null, val)
+}
+
+func TestYAMLParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		msg string
+	}{
+		{"a: 1\n b: 2\n", "invalid YAML at line 2: mapping values are not allowed in this context"},
+		{"a: 1\nb: !!int abc\n", `invalid YAML at line 2, column 4: cannot read "abc" as !!int`},
+		{"? [a]\n: 1\n", "invalid YAML at line 1, column 3: keys must be scalars"},
+	}
+	for _, tt := range tests {
+		_, err := callGlobal(t, "yaml_parse", tt.src)
+		require.NotNil(t, err, tt.src)
+		assert.Equal(t, yaperror.ErrInvalidDocument, err.Code)
+		assert.Equal(t, tt.msg, err.Message)
+	}
+}
+
+func TestYAMLStringify(t *testing.T) {
+	inner := stdlib.NewMap()
+	inner.Set("port", 8080)
+	inner.Set("ratio", 0.5)
+	m := stdlib.NewMap()
+	m.Set("name", "yap")
+	m.Set("server", inner)
+	m.Set("tags", []interface{}{"true", "two\nlines", stdlib.Null})
+	m.Set("none", []interface{}{})
+
+	val, err := callGlobal(t, "yaml_stringify", m)
+	require.Nil(t, err)
+	assert.Equal(t, "name: yap\nserver:\n  port: 8080\n  ratio: 0.5\ntags:\n  - \"true\"\n  - |-\n    two\n    lines\n  - null\nnone: []", val)
+
+	// Parsing the document gives the value back
+	back, err := callGlobal(t, "yaml_parse", val)
+	require.Nil(t, err)
+	assert.Equal(t, stdlib.Format(m), stdlib.Format(back))
+
+	_, err = callGlobal(t, "yaml_stringify", stdlib.NewMap(), 2)
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrInvalidArgCount, err.Code)
+}
+
+// Aliases are converted once, and expanding them over and over is an error
+// rather than an exponential blow-up
+func TestYAMLParseAliasBomb(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("a0: &a0 [x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i <= 8; i++ {
+		fmt.Fprintf(&b, "a%d: &a%d [*a%d, *a%d, *a%d, *a%d, *a%d, *a%d, *a%d, *a%d, *a%d]\n", i, i, i-1, i-1, i-1, i-1, i-1, i-1, i-1, i-1, i-1)
+	}
+
+	start := time.Now()
+	_, err := callGlobal(t, "yaml_parse", b.String())
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrInvalidDocument, err.Code)
+	assert.Contains(t, err.Message, "too many values from aliases")
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// A few levels stay within the budget and share their values
+	val, err := callGlobal(t, "yaml_parse", "a: &a [1, 2]\nb: [*a, *a]\n")
+	require.Nil(t, err)
+	assert.Equal(t, `{"a": [1, 2], "b": [[1, 2], [1, 2]]}`, stdlib.Format(val))
+}
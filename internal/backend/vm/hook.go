@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"sort"
+
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+)
+
+// Hook observes a run of the VM. Tools such as the debugger are built on it
+type Hook interface {
+	// BeforeInstruction is called before the instruction at pc executes.
+	// instr.Span locates its statement in the source. Returning an error
+	// stops the run with that error
+	BeforeInstruction(vm *VM, pc int, instr ir.Instruction) *yaperror.YapError
+}
+
+// SetHook is implemented by hooks that also observe variable writes
+type SetHook interface {
+	// AfterSet is called after an OpSet or the OpForNext of a loop stored val
+	// in name. old is the previous value and existed reports whether there
+	// was one
+	AfterSet(vm *VM, pc int, name string, old interface{}, existed bool, val interface{})
+}
+
+// BranchHook is implemented by hooks that also observe conditional jumps
+type BranchHook interface {
+	// AfterBranch is called after an OpJumpIfFalse evaluated its condition
+	// and before every run of the body of a loop, with cond false once the
+	// loop ends. For loops pc is the index of the loop's OpForStart
+	AfterBranch(vm *VM, pc int, cond bool)
+}
+
+// CallHook is implemented by hooks that also observe calls of builtin
+// functions
+type CallHook interface {
+	// BeforeCall is called once the arguments of a call of fn are evaluated
+	// and AfterCall once fn returned, also if it failed
+	BeforeCall(vm *VM, fn *stdlib.Func)
+	AfterCall(vm *VM, fn *stdlib.Func)
+}
+
+// WithHook registers a hook. Hooks are called in the order they were added
+func WithHook(h Hook) Option {
+	return func(vm *VM) {
+		vm.hooks = append(vm.hooks, h)
+		if sh, ok := h.(SetHook); ok {
+			vm.setHooks = append(vm.setHooks, sh)
+		}
+		if bh, ok := h.(BranchHook); ok {
+			vm.branchHooks = append(vm.branchHooks, bh)
+		}
+		if ch, ok := h.(CallHook); ok {
+			vm.callHooks = append(vm.callHooks, ch)
+		}
+	}
+}
+
+// PC returns the index of the next instruction to execute
+func (vm *VM) PC() int {
+	return vm.pc
+}
+
+// Instructions returns the program the VM runs
+func (vm *VM) Instructions() []ir.Instruction {
+	return vm.instructions
+}
+
+// Lookup returns the value of a variable
+func (vm *VM) Lookup(name string) (interface{}, bool) {
+	val, ok := vm.env[name]
+	return val, ok
+}
+
+// Assign sets a variable, subject to the memory limit like a set statement
+func (vm *VM) Assign(name string, val interface{}) *yaperror.YapError {
+	return vm.store(name, val)
+}
+
+// Variables returns the names of all variables in sorted order
+func (vm *VM) Variables() []string {
+	names := make([]string, 0, len(vm.env))
+	for name := range vm.env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Evaluate evaluates expr against the current variables
+func (vm *VM) Evaluate(expr parser.Value) (interface{}, *yaperror.YapError) {
+	return vm.evaluate(expr)
+}
+
+// Stop ends the run before the next instruction without an error
+func (vm *VM) Stop() {
+	vm.stopped = true
+}
@@ -0,0 +1,221 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/suggest"
+)
+
+// Namespace is the value of an imported module: the variables its top-level
+// statements set, read with a selector like util.name
+type Namespace struct {
+	Name string // Alias the module was imported as
+	Path string // Absolute path of the module file
+	Vars map[string]interface{}
+}
+
+func (n *Namespace) String() string {
+	return "<module " + n.Name + ">"
+}
+
+// Names returns the names of the variables of the namespace, sorted
+func (n *Namespace) Names() []string {
+	names := make([]string, 0, len(n.Vars))
+	for name := range n.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// importModule runs mod on its first import and binds its namespace to name.
+// The module runs in its own VM sharing the input, output, filesystem,
+// arguments, environment, limits, step count and memory of this one. Hooks
+// only see the statements of the main program
+func (vm *VM) importModule(name string, mod *ir.Module) *yaperror.YapError {
+	if vm.modules == nil {
+		vm.modules = map[*ir.Module]*Namespace{}
+	}
+	ns, ok := vm.modules[mod]
+	if !ok && mod.Builtin {
+		builtin, found := stdlib.Lookup(mod.Path)
+		if !found {
+			return yaperror.NewRuntimeError("unknown builtin module " + mod.Path)
+		}
+		ns = &Namespace{Path: mod.Path, Vars: builtin.Members}
+		vm.modules[mod] = ns
+	} else if !ok {
+		child := New(mod.Instructions, WithStdout(vm.stdout), WithStderr(vm.stderr), WithFS(vm.fs), WithLimits(vm.limits))
+		child.stdin = vm.stdin
+		child.args = vm.args
+		child.envLookup = vm.envLookup
+		child.modules = vm.modules
+		child.steps = vm.steps
+		child.memory = vm.memory
+
+		ctx := vm.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		err := child.RunContext(ctx)
+		vm.steps = child.steps
+		// The namespace keeps the variables of the module, and their memory
+		vm.memory = child.memory
+		if err != nil {
+			return err
+		}
+		// An exit in a module ends the whole program
+		vm.exited, vm.exitCode = child.exited, child.exitCode
+
+		ns = &Namespace{Path: mod.Path, Vars: child.env}
+		vm.modules[mod] = ns
+	}
+
+	// Every import gets its own alias, the variables are shared
+	return vm.store(name, &Namespace{Name: name, Path: ns.Path, Vars: ns.Vars})
+}
+
+// selectName evaluates a selector like util.name
+func (vm *VM) selectName(v *parser.SelectorExpr) (interface{}, *yaperror.YapError) {
+	x, err := vm.evaluate(v.X)
+	if err != nil {
+		return nil, err
+	}
+	return vm.member(x, v)
+}
+
+// member returns the variable selected by v from x, the value of v.X, or
+// the value of a key if x is a map, e.g. config.port
+func (vm *VM) member(x interface{}, v *parser.SelectorExpr) (interface{}, *yaperror.YapError) {
+	if m, ok := x.(*stdlib.Map); ok {
+		val, ok := m.Get(v.Name)
+		if !ok {
+			err := yaperror.NewRuntimeError(fmt.Sprintf("map %s has no key %q", v.X, v.Name))
+			if hint := suggest.Hint(v.Name, m.Keys); hint != "" {
+				err.AddHint(hint)
+			}
+			return nil, err
+		}
+		return val, nil
+	}
+	ns, ok := x.(*Namespace)
+	if !ok {
+		return nil, yaperror.NewRuntimeError("cannot select " + v.Name + " from a value that is not a module or map")
+	}
+	val, ok := ns.Vars[v.Name]
+	if !ok {
+		err := yaperror.NewUndefinedVariable(ns.Name + "." + v.Name)
+		if hint := suggest.Hint(v.Name, ns.Names()); hint != "" {
+			err.AddHint(hint)
+		}
+		return nil, err
+	}
+	return val, nil
+}
+
+// FS returns the filesystem of the fs module. With the methods below it makes
+// the VM the stdlib.Env of the functions it calls
+func (vm *VM) FS() stdlib.FS {
+	return vm.fs
+}
+
+// Stdin returns the input of the program
+func (vm *VM) Stdin() io.Reader {
+	return vm.stdin
+}
+
+// Args returns the arguments of the program
+func (vm *VM) Args() []string {
+	return vm.args
+}
+
+// EnvLookup returns how environment variables are read, nil if the program
+// may not read them
+func (vm *VM) EnvLookup() stdlib.EnvLookup {
+	return vm.envLookup
+}
+
+// MaxMemory returns the memory limit of the program, 0 if there is none
+func (vm *VM) MaxMemory() int {
+	return vm.limits.MaxMemory
+}
+
+// call evaluates a call of a builtin function like math.sqrt(x) or str(x).
+// Errors of the function point at the call
+func (vm *VM) call(v *parser.CallExpr) (interface{}, *yaperror.YapError) {
+	var fun interface{}
+	var err *yaperror.YapError
+	switch f := v.Fun.(type) {
+	case *parser.SelectorExpr:
+		x, err := vm.evaluate(f.X)
+		if err != nil {
+			return nil, err
+		}
+		fun, err = vm.member(x, f)
+		if err != nil && err.Code == yaperror.ErrUndefinedVariable {
+			// Report the missing member as the function it was called as
+			undefined := yaperror.NewUndefinedFunction(f.String())
+			undefined.Hints = err.Hints
+			return nil, vm.at(undefined, v.Loc)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+	case *parser.Identifier:
+		_, isVar := vm.env[f.Name]
+		if _, isGlobal := stdlib.Global(f.Name); !isVar && !isGlobal {
+			undefined := yaperror.NewUndefinedFunction(f.Name)
+			candidates := stdlib.GlobalNames()
+			for name := range vm.env {
+				candidates = append(candidates, name)
+			}
+			if hint := suggest.Hint(f.Name, candidates); hint != "" {
+				undefined.AddHint(hint)
+			}
+			return nil, vm.at(undefined, v.Loc)
+		}
+		if fun, err = vm.evaluate(f); err != nil {
+			return nil, err
+		}
+
+	default:
+		if fun, err = vm.evaluate(v.Fun); err != nil {
+			return nil, err
+		}
+	}
+
+	fn, ok := fun.(*stdlib.Func)
+	if !ok {
+		return nil, vm.at(yaperror.NewRuntimeError(fmt.Sprintf("cannot call %s of type %s", v.Fun, stdlib.TypeName(fun))), v.Loc)
+	}
+
+	args := make([]interface{}, len(v.Args))
+	for i, arg := range v.Args {
+		if args[i], err = vm.evaluate(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, h := range vm.callHooks {
+		h.BeforeCall(vm, fn)
+	}
+	val, err := fn.Call(vm, args)
+	for _, h := range vm.callHooks {
+		h.AfterCall(vm, fn)
+	}
+	if err != nil {
+		return nil, vm.at(err, v.Loc)
+	}
+	if err := vm.checkMemory(sizeOf(val)); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
@@ -0,0 +1,96 @@
+package vm
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+)
+
+// Option configures a VM created with New
+type Option func(*VM)
+
+// WithStdin sets the reader input statements and read_all_stdin read from.
+// The default is an empty input, so a VM never reads the process's stdin
+// unless it is passed here
+func WithStdin(r io.Reader) Option {
+	return func(vm *VM) {
+		vm.stdin = bufio.NewReader(r)
+	}
+}
+
+// WithStdout sets the writer print statements write to
+func WithStdout(w io.Writer) Option {
+	return func(vm *VM) {
+		vm.stdout = w
+	}
+}
+
+// WithStderr sets the writer for error output, e.g. print with stderr: True
+func WithStderr(w io.Writer) Option {
+	return func(vm *VM) {
+		vm.stderr = w
+	}
+}
+
+// WithFS sets the filesystem of the fs module, e.g. a stdlib.RootFS to keep
+// programs in a directory or a stdlib.MemFS. The default is stdlib.OSFS
+func WithFS(fsys stdlib.FS) Option {
+	return func(vm *VM) {
+		vm.fs = fsys
+	}
+}
+
+// WithArgs sets the arguments of the program, the value of args
+func WithArgs(args []string) Option {
+	return func(vm *VM) {
+		vm.args = args
+	}
+}
+
+// WithEnvLookup lets env and env_or read environment variables, e.g. with
+// os.LookupEnv. Without it they fail, so programs cannot read secrets unless
+// the embedder allows it
+func WithEnvLookup(lookup stdlib.EnvLookup) Option {
+	return func(vm *VM) {
+		vm.envLookup = lookup
+	}
+}
+
+// Limits bounds the resources a single run may consume.
+// A zero value for any field means that resource is unlimited.
+type Limits struct {
+	MaxSteps     int // Maximum number of executed instructions
+	MaxCallDepth int // Maximum nesting depth of expression evaluation
+	MaxMemory    int // Maximum total size in bytes of values held in variables
+}
+
+// WithLimits replaces all execution limits of the VM
+func WithLimits(limits Limits) Option {
+	return func(vm *VM) {
+		vm.limits = limits
+	}
+}
+
+// WithMaxSteps limits the number of instructions a run may execute
+func WithMaxSteps(n int) Option {
+	return func(vm *VM) {
+		vm.limits.MaxSteps = n
+	}
+}
+
+// WithMaxCallDepth limits how deeply expression evaluation may nest
+func WithMaxCallDepth(n int) Option {
+	return func(vm *VM) {
+		vm.limits.MaxCallDepth = n
+	}
+}
+
+// WithMaxMemory limits the total size in bytes of string values and of the
+// items of lists and maps held in variables, as well as the size of any single
+// intermediate value
+func WithMaxMemory(n int) Option {
+	return func(vm *VM) {
+		vm.limits.MaxMemory = n
+	}
+}
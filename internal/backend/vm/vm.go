@@ -1,76 +1,536 @@
 package vm
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
 	yaperror "github.com/rlamalama/YAP/internal/error"
 	"github.com/rlamalama/YAP/internal/frontend/lexer"
 	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/source"
+	"github.com/rlamalama/YAP/internal/frontend/suggest"
 )
 
 type VM struct {
 	instructions []ir.Instruction
 	env          map[string]interface{}
 	pc           int // program counter
+
+	stdin  *bufio.Reader // input of input statements and read_all_stdin
+	stdout io.Writer
+	stderr io.Writer
+	fs     stdlib.FS // filesystem of the fs module
+
+	args      []string         // arguments of the program, the value of args
+	envLookup stdlib.EnvLookup // reads environment variables, nil to deny env and env_or
+
+	limits Limits
+	steps  int // number of executed instructions
+	depth  int // current evaluation depth
+	memory int // bytes held by values in env
+
+	hooks       []Hook
+	setHooks    []SetHook
+	branchHooks []BranchHook
+	callHooks   []CallHook
+	stopped     bool // set by Stop
+	exited      bool // set by an exit statement
+	exitCode    int
+
+	handlers []int         // recovery offsets of the enclosing expect_error blocks
+	loops    map[int]*loop // state of the running loops, keyed by OpForNext index
+
+	ctx     context.Context           // context of the current run, for imported modules
+	modules map[*ir.Module]*Namespace // imported modules that already ran, shared with their VMs
 }
 
-func New(instructions []ir.Instruction) *VM {
+// New creates a VM for a single run of instructions. All mutable state lives
+// in the VM, so each goroutine should use its own VM while sharing instructions
+func New(instructions []ir.Instruction, opts ...Option) *VM {
 	env := make(map[string]interface{})
-	return &VM{instructions: instructions, env: env, pc: 0}
+	vm := &VM{
+		instructions: instructions,
+		env:          env,
+		pc:           0,
+		stdin:        bufio.NewReader(strings.NewReader("")),
+		stdout:       os.Stdout,
+		stderr:       os.Stderr,
+		fs:           stdlib.OSFS{},
+	}
+	for _, opt := range opts {
+		opt(vm)
+	}
+	return vm
 }
 
 func (vm *VM) Run() *yaperror.YapError {
+	return vm.RunContext(context.Background())
+}
+
+// RunContext runs the program until it finishes, fails, exceeds one of the
+// configured limits or ctx is done
+func (vm *VM) RunContext(ctx context.Context) *yaperror.YapError {
+	vm.ctx = ctx
+	done := ctx.Done()
 	for vm.pc < len(vm.instructions) {
-		instr := vm.instructions[vm.pc]
-		switch instr.Op {
-		case ir.OpSet:
-			name := instr.Arg.Value
-			val, err := vm.evaluate(instr.Expr)
-			if err != nil {
-				return err
+		if done != nil {
+			select {
+			case <-done:
+				return vm.locate(yaperror.NewTimeoutError(ctx.Err()))
+			default:
 			}
-			vm.env[name] = val
-			vm.pc++
+		}
 
-		case ir.OpPrint:
-			val, err := vm.evaluate(instr.Expr)
-			if err != nil {
-				return err
-			}
-			fmt.Println(val)
-			vm.pc++
+		vm.steps++
+		if vm.limits.MaxSteps > 0 && vm.steps > vm.limits.MaxSteps {
+			return vm.locate(yaperror.NewStepLimitError(vm.limits.MaxSteps))
+		}
 
-		case ir.OpJumpIfFalse:
-			val, err := vm.evaluate(instr.Expr)
-			if err != nil {
+		instr := vm.instructions[vm.pc]
+		for _, h := range vm.hooks {
+			if err := h.BeforeInstruction(vm, vm.pc, instr); err != nil {
 				return err
 			}
-			boolVal, ok := val.(bool)
-			if !ok {
-				return yaperror.NewRuntimeError(fmt.Sprintf("condition must be a boolean, got %T", val))
-			}
-			if !boolVal {
-				vm.pc = instr.Arg.Offset
-			} else {
-				vm.pc++
+		}
+		if vm.stopped {
+			return nil
+		}
+
+		if err := vm.exec(instr); err != nil {
+			if !vm.recover(err) {
+				return vm.locate(err)
 			}
+		}
+		if vm.exited {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ExitCode returns the code of the exit statement that ended the last run,
+// or 0 if the program ran to its end
+func (vm *VM) ExitCode() int {
+	return vm.exitCode
+}
+
+// locate points err at the statement of the instruction at pc, unless it
+// already has a position
+func (vm *VM) locate(err *yaperror.YapError) *yaperror.YapError {
+	if err.Position.Line > 0 || vm.pc >= len(vm.instructions) {
+		return err
+	}
+	instr := vm.instructions[vm.pc]
+	switch instr.Op {
+	case ir.OpEndExpectError:
+		// The end of a block has no span of its own, use its start
+		instr = vm.instructions[instr.Arg.Offset]
+	case ir.OpForNext:
+		instr = vm.instructions[vm.pc-1]
+	}
+	return vm.at(err, instr.Span)
+}
+
+// at points err at span, unless err already has a position or span is unknown
+func (vm *VM) at(err *yaperror.YapError, span source.Span) *yaperror.YapError {
+	if err.Position.Line > 0 || span.Start.Line == 0 {
+		return err
+	}
 
-		case ir.OpJump:
+	var path string
+	if span.File != nil {
+		path = span.File.Path
+		if line, ok := span.File.Line(span.Start.Line); ok {
+			err.Context = line
+		}
+	}
+	start := yaperror.Position{File: path, Line: span.Start.Line, Column: span.Start.Column}
+	end := yaperror.Position{File: path, Line: span.End.Line, Column: span.End.Column}
+	err.Position = start
+	return err.WithSpan(start, end)
+}
+
+// exec runs a single instruction and advances the program counter
+func (vm *VM) exec(instr ir.Instruction) *yaperror.YapError {
+	switch instr.Op {
+	case ir.OpSet:
+		name := instr.Arg.Value
+		val, err := vm.evaluate(instr.Expr)
+		if err != nil {
+			return err
+		}
+		old, existed := vm.env[name]
+		if err := vm.store(name, val); err != nil {
+			return err
+		}
+		for _, h := range vm.setHooks {
+			h.AfterSet(vm, vm.pc, name, old, existed, val)
+		}
+		vm.pc++
+
+	case ir.OpPrint:
+		if err := vm.print(instr); err != nil {
+			return err
+		}
+		vm.pc++
+
+	case ir.OpJumpIfFalse:
+		val, err := vm.evaluate(instr.Expr)
+		if err != nil {
+			return err
+		}
+		boolVal, ok := val.(bool)
+		if !ok {
+			return yaperror.NewRuntimeError(fmt.Sprintf("condition must be a boolean, got %T", val))
+		}
+		for _, h := range vm.branchHooks {
+			h.AfterBranch(vm, vm.pc, boolVal)
+		}
+		if !boolVal {
 			vm.pc = instr.Arg.Offset
+		} else {
+			vm.pc++
+		}
+
+	case ir.OpJump:
+		vm.pc = instr.Arg.Offset
+
+	case ir.OpAssert:
+		if err := vm.assert(instr); err != nil {
+			return err
+		}
+		vm.pc++
+
+	case ir.OpImport:
+		if err := vm.importModule(instr.Arg.Value, instr.Module); err != nil {
+			return err
+		}
+		vm.pc++
+
+	case ir.OpForStart:
+		if err := vm.startLoop(instr); err != nil {
+			return err
+		}
+		vm.pc++
+
+	case ir.OpForNext:
+		if err := vm.nextItem(instr); err != nil {
+			return err
+		}
+
+	case ir.OpCall:
+		if _, err := vm.call(instr.Expr.(*parser.CallExpr)); err != nil {
+			return err
+		}
+		vm.pc++
+
+	case ir.OpInput:
+		if err := vm.input(instr); err != nil {
+			return err
+		}
+		vm.pc++
 
-		default:
-			return yaperror.NewUnknownOpcodeError(int(instr.Op))
+	case ir.OpExit:
+		if err := vm.exit(instr); err != nil {
+			return err
 		}
+		vm.pc++
+
+	case ir.OpExpectError:
+		vm.handlers = append(vm.handlers, instr.Arg.Offset)
+		vm.pc++
+
+	case ir.OpEndExpectError:
+		// The block completed, so the error it expected was not raised
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		return yaperror.NewErrorNotRaisedError()
+
+	default:
+		return yaperror.NewUnknownOpcodeError(int(instr.Op))
+	}
+	return nil
+}
+
+// loop is the state of a running for statement
+type loop struct {
+	items []interface{}
+	next  int // index of the item for the next run of the body
+}
+
+// startLoop evaluates the list of an OpForStart. Starting a loop again, e.g.
+// after an expect_error block recovered from an error in its body, starts
+// over with the first item
+func (vm *VM) startLoop(instr ir.Instruction) *yaperror.YapError {
+	val, err := vm.evaluate(instr.Expr)
+	if err != nil {
+		return err
+	}
+	items, ok := val.([]interface{})
+	if !ok {
+		return yaperror.NewRuntimeError(fmt.Sprintf("for needs a list, got %s", stdlib.TypeName(val)))
+	}
+	if vm.loops == nil {
+		vm.loops = map[int]*loop{}
+	}
+	vm.loops[vm.pc+1] = &loop{items: items}
+	return nil
+}
+
+// nextItem assigns the next item of the running loop to its variable, or
+// leaves the loop after the last one
+func (vm *VM) nextItem(instr ir.Instruction) *yaperror.YapError {
+	l := vm.loops[vm.pc]
+	more := l.next < len(l.items)
+	for _, h := range vm.branchHooks {
+		h.AfterBranch(vm, vm.pc-1, more)
+	}
+	if !more {
+		delete(vm.loops, vm.pc)
+		vm.pc = instr.Arg.Offset
+		return nil
 	}
+
+	name, val := instr.Arg.Value, l.items[l.next]
+	l.next++
+	old, existed := vm.env[name]
+	if err := vm.store(name, val); err != nil {
+		return err
+	}
+	for _, h := range vm.setHooks {
+		h.AfterSet(vm, vm.pc, name, old, existed, val)
+	}
+	vm.pc++
 	return nil
 }
 
+// recover resumes after the innermost expect_error block when err was raised
+// inside one. Limit errors always stop the program
+func (vm *VM) recover(err *yaperror.YapError) bool {
+	if len(vm.handlers) == 0 || yaperror.IsLimitError(err) {
+		return false
+	}
+	last := len(vm.handlers) - 1
+	vm.pc = vm.handlers[last]
+	vm.handlers = vm.handlers[:last]
+	return true
+}
+
+// assert fails unless the condition of an OpAssert evaluates to true
+func (vm *VM) assert(instr ir.Instruction) *yaperror.YapError {
+	val, err := vm.evaluate(instr.Expr)
+	if err != nil {
+		return err
+	}
+	ok, isBool := val.(bool)
+	if !isBool {
+		return yaperror.NewRuntimeError(fmt.Sprintf("assertion must be a boolean, got %T", val))
+	}
+	if ok {
+		return nil
+	}
+
+	msg := fmt.Sprint(instr.Expr)
+	if instr.Msg != nil {
+		val, err := vm.evaluate(instr.Msg)
+		if err != nil {
+			return err
+		}
+		s, isStr := val.(string)
+		if !isStr {
+			return yaperror.NewRuntimeError(fmt.Sprintf("assertion message must be a string, got %T", val))
+		}
+		msg = s
+	}
+	return yaperror.NewAssertionError(msg)
+}
+
+// print evaluates the values of an OpPrint and writes them to the configured writer
+func (vm *VM) print(instr ir.Instruction) *yaperror.YapError {
+	exprs := []interface{}{instr.Expr}
+	if list, ok := instr.Expr.(*parser.ExprList); ok {
+		exprs = exprs[:0]
+		for _, v := range list.Values {
+			exprs = append(exprs, v)
+		}
+	}
+
+	parts := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		val, err := vm.evaluate(expr)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, FormatValue(val))
+	}
+
+	opts := instr.Print
+	if opts == nil {
+		opts = &ir.PrintOptions{}
+	}
+
+	sep := " "
+	if opts.Sep != nil {
+		val, err := vm.evaluate(opts.Sep)
+		if err != nil {
+			return err
+		}
+		s, ok := val.(string)
+		if !ok {
+			return yaperror.NewRuntimeError(fmt.Sprintf("print separator must be a string, got %T", val))
+		}
+		sep = s
+	}
+
+	out := strings.Join(parts, sep)
+	if !opts.NoNewline {
+		out += "\n"
+	}
+
+	w := vm.stdout
+	if opts.Stderr {
+		w = vm.stderr
+	}
+	if _, err := io.WriteString(w, out); err != nil {
+		return yaperror.NewIOError(err)
+	}
+	return nil
+}
+
+// input writes the prompt of an OpInput and reads a line into its variable.
+// A last line without a line ending is read as well, only a read at the end
+// of the input fails
+func (vm *VM) input(instr ir.Instruction) *yaperror.YapError {
+	name := instr.Arg.Value
+	if instr.Expr != nil {
+		val, err := vm.evaluate(instr.Expr)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(vm.stdout, FormatValue(val)); err != nil {
+			return yaperror.NewIOError(err)
+		}
+	}
+
+	line, err := vm.stdin.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return yaperror.NewEndOfInputError(name)
+	}
+	if err != nil && err != io.EOF {
+		return yaperror.NewIOError(err)
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+	old, existed := vm.env[name]
+	if err := vm.store(name, line); err != nil {
+		return err
+	}
+	for _, h := range vm.setHooks {
+		h.AfterSet(vm, vm.pc, name, old, existed, line)
+	}
+	return nil
+}
+
+// exit ends the program with the code of an OpExit. An exit is not an error,
+// so expect_error blocks do not catch it
+func (vm *VM) exit(instr ir.Instruction) *yaperror.YapError {
+	val, err := vm.evaluate(instr.Expr)
+	if err != nil {
+		return err
+	}
+	code, ok := val.(int)
+	if !ok {
+		return yaperror.NewRuntimeError(fmt.Sprintf("exit needs an int, got %s", stdlib.TypeName(val)))
+	}
+	if code < 0 || code > 255 {
+		return yaperror.NewRuntimeError(fmt.Sprintf("exit code must be from 0 to 255, got %d", code))
+	}
+	vm.exited = true
+	vm.exitCode = code
+	return nil
+}
+
+// store assigns val to name, accounting for the memory held by the variable
+func (vm *VM) store(name string, val interface{}) *yaperror.YapError {
+	if old, ok := vm.env[name]; ok {
+		vm.memory -= sizeOf(old)
+	}
+	vm.memory += sizeOf(val)
+	vm.env[name] = val
+	return vm.checkMemory(0)
+}
+
+// checkMemory fails if size more bytes on top of the memory held by variables
+// exceed the memory limit, e.g. for a new intermediate value
+func (vm *VM) checkMemory(size int) *yaperror.YapError {
+	if vm.limits.MaxMemory > 0 && vm.memory+size > vm.limits.MaxMemory {
+		return yaperror.NewMemoryLimitError(vm.limits.MaxMemory)
+	}
+	return nil
+}
+
+// toFloat converts a number to a float, reporting false for other values
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// FormatValue returns val as print writes it
+func FormatValue(val interface{}) string {
+	return stdlib.Format(val)
+}
+
+// itemSize is the number of bytes counted for each item of a list or map on
+// top of its value, so lists of numbers count against the memory limit too
+const itemSize = 8
+
+// sizeOf returns the number of bytes counted against the memory limit for val
+func sizeOf(val interface{}) int {
+	switch v := val.(type) {
+	case string:
+		return len(v)
+	case []interface{}:
+		size := 0
+		for _, item := range v {
+			size += itemSize + sizeOf(item)
+		}
+		return size
+	case *stdlib.Map:
+		size := 0
+		for key, item := range v.Values {
+			size += itemSize + len(key) + sizeOf(item)
+		}
+		return size
+	default:
+		return 0
+	}
+}
+
 func (vm *VM) evaluate(expr interface{}) (interface{}, *yaperror.YapError) {
+	vm.depth++
+	defer func() { vm.depth-- }()
+	if vm.limits.MaxCallDepth > 0 && vm.depth > vm.limits.MaxCallDepth {
+		return nil, yaperror.NewCallDepthError(vm.limits.MaxCallDepth)
+	}
+
 	switch v := expr.(type) {
 	case *parser.NumericLiteral:
 		return v.Value, nil
 
+	case *parser.FloatLiteral:
+		return v.Value, nil
+
 	case *parser.StringLiteral:
 		return v.Value, nil
 
@@ -78,11 +538,35 @@ func (vm *VM) evaluate(expr interface{}) (interface{}, *yaperror.YapError) {
 		return v.Value, nil
 
 	case *parser.Identifier:
-		val, ok := vm.env[v.Name]
-		if !ok {
-			return nil, yaperror.NewUndefinedVariable(v.Name)
+		if val, ok := vm.env[v.Name]; ok {
+			return val, nil
+		}
+		if val, ok := stdlib.Global(v.Name); ok {
+			if builtin, ok := val.(*stdlib.Var); ok {
+				return builtin.Get(vm), nil
+			}
+			return val, nil
 		}
-		return val, nil
+		err := yaperror.NewUndefinedVariable(v.Name)
+		candidates := []string{lexer.KeywordTrue, lexer.KeywordFalse}
+		for name := range vm.env {
+			candidates = append(candidates, name)
+		}
+		if hint := suggest.Hint(v.Name, candidates); hint != "" {
+			err.AddHint(hint)
+		}
+		return nil, err
+
+	case *parser.SelectorExpr:
+		return vm.selectName(v)
+
+	case *parser.CallExpr:
+		val, err := vm.call(v)
+		if err == nil && val == nil {
+			// e.g. fs.write_file, which is called with a call statement
+			return nil, vm.at(yaperror.NewRuntimeError(fmt.Sprintf("%s does not return a value", v.Fun)), v.Loc)
+		}
+		return val, err
 
 	case *parser.BinaryExpr:
 		left, err := vm.evaluate(v.Left)
@@ -128,6 +612,38 @@ func (vm *VM) evaluate(expr interface{}) (interface{}, *yaperror.YapError) {
 			}
 		}
 
+		// Handle float operations, an int operand is converted to a float
+		leftFloat, leftIsNum := toFloat(left)
+		rightFloat, rightIsNum := toFloat(right)
+
+		if leftIsNum && rightIsNum {
+			switch v.Operator {
+			case lexer.ArithmeticAdditionOperator.String():
+				return leftFloat + rightFloat, nil
+			case lexer.ArithmeticSubtractionOperator.String():
+				return leftFloat - rightFloat, nil
+			case lexer.ArithmeticMultiplicationOperator.String():
+				return leftFloat * rightFloat, nil
+			case lexer.ArithmeticDivisionOperator.String():
+				if rightFloat == 0 {
+					return nil, yaperror.NewRuntimeError("division by zero")
+				}
+				return leftFloat / rightFloat, nil
+			case lexer.ComparisonGtOperator.String():
+				return leftFloat > rightFloat, nil
+			case lexer.ComparisonLtOperator.String():
+				return leftFloat < rightFloat, nil
+			case lexer.ComparisonGteOperator.String():
+				return leftFloat >= rightFloat, nil
+			case lexer.ComparisonLteOperator.String():
+				return leftFloat <= rightFloat, nil
+			case lexer.ComparisonEqOperator.String():
+				return leftFloat == rightFloat, nil
+			case lexer.ComparisonNeOperator.String():
+				return leftFloat != rightFloat, nil
+			}
+		}
+
 		// Handle string operations
 		leftStr, leftIsStr := left.(string)
 		rightStr, rightIsStr := right.(string)
@@ -135,6 +651,9 @@ func (vm *VM) evaluate(expr interface{}) (interface{}, *yaperror.YapError) {
 		if leftIsStr && rightIsStr {
 			switch v.Operator {
 			case lexer.ArithmeticAdditionOperator.String():
+				if err := vm.checkMemory(len(leftStr) + len(rightStr)); err != nil {
+					return nil, err
+				}
 				return leftStr + rightStr, nil
 			// Comparison operators for strings
 			case lexer.ComparisonEqOperator.String():
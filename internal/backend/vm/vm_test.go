@@ -1,31 +1,39 @@
 package vm_test
 
 import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
 	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
 	"github.com/rlamalama/YAP/internal/frontend/parser"
-	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/rlamalama/YAP/internal/frontend/source"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestVMPrint(t *testing.T) {
 	arg := "hi"
+	var out bytes.Buffer
 	vm := vm.New([]ir.Instruction{
 		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: arg}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, vm.Run())
-	})
+	require.Nil(t, vm.Run())
+	output := out.String()
 
 	assert.Equal(t, arg+"\n", output)
 }
 
 func TestVMSetAndPrint(t *testing.T) {
 	key, arg := "x", "hi"
+	var out bytes.Buffer
 	vm := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -33,11 +41,10 @@ func TestVMSetAndPrint(t *testing.T) {
 			Expr: &parser.StringLiteral{Value: arg},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: key}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, vm.Run())
-	})
+	require.Nil(t, vm.Run())
+	output := out.String()
 
 	assert.Equal(t, arg+"\n", output)
 }
@@ -49,6 +56,7 @@ func TestVMBinaryExprAddition(t *testing.T) {
 		Operator: "+",
 		Right:    &parser.NumericLiteral{Value: 5},
 	}
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -56,11 +64,10 @@ func TestVMBinaryExprAddition(t *testing.T) {
 			Expr: binExpr,
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "x"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "15\n", output)
 }
@@ -78,6 +85,7 @@ func TestVMBinaryExprChained(t *testing.T) {
 		Right:    &parser.NumericLiteral{Value: 15},
 	}
 
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -85,17 +93,17 @@ func TestVMBinaryExprChained(t *testing.T) {
 			Expr: outerExpr,
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "x"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "5\n", output)
 }
 
 func TestVMBinaryExprWithVariables(t *testing.T) {
 	// Test: x = 5, y = x * 4 (should output 20)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -112,17 +120,17 @@ func TestVMBinaryExprWithVariables(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "y"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "20\n", output)
 }
 
 func TestVMBinaryExprDivision(t *testing.T) {
 	// Test: y = 20, z = y / 5 (should output 4)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -139,11 +147,10 @@ func TestVMBinaryExprDivision(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "z"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "4\n", output)
 }
@@ -161,19 +168,20 @@ func TestVMBinaryExprStringConcat(t *testing.T) {
 		Right:    &parser.StringLiteral{Value: "world!"},
 	}
 
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{Op: ir.OpPrint, Expr: outerExpr},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "hello world!\n", output)
 }
 
 func TestVMPrintBinaryExprWithVariables(t *testing.T) {
 	// Test: x = 5, z = 4, print x * z (should output 20)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -193,17 +201,17 @@ func TestVMPrintBinaryExprWithVariables(t *testing.T) {
 				Right:    &parser.Identifier{Name: "z"},
 			},
 		},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "20\n", output)
 }
 
 func TestVMBooleanLiteralTrue(t *testing.T) {
 	// Test: flag = True, print flag (should output true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -211,17 +219,17 @@ func TestVMBooleanLiteralTrue(t *testing.T) {
 			Expr: &parser.BooleanLiteral{Value: true},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "flag"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "true\n", output)
 }
 
 func TestVMBooleanLiteralFalse(t *testing.T) {
 	// Test: flag = False, print flag (should output false)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -229,17 +237,17 @@ func TestVMBooleanLiteralFalse(t *testing.T) {
 			Expr: &parser.BooleanLiteral{Value: false},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "flag"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "false\n", output)
 }
 
 func TestVMComparisonGreaterThan(t *testing.T) {
 	// Test: a = 10, b = 5, isGreater = a > b (should output true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -261,17 +269,17 @@ func TestVMComparisonGreaterThan(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "isGreater"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "true\n", output)
 }
 
 func TestVMComparisonLessThan(t *testing.T) {
 	// Test: a = 5, b = 10, isLess = a < b (should output true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -293,17 +301,17 @@ func TestVMComparisonLessThan(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "isLess"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "true\n", output)
 }
 
 func TestVMComparisonEqual(t *testing.T) {
 	// Test: a = 5, b = 5, isEqual = a == b (should output true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -325,17 +333,17 @@ func TestVMComparisonEqual(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "isEqual"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "true\n", output)
 }
 
 func TestVMComparisonNotEqual(t *testing.T) {
 	// Test: a = 10, b = 5, notEqual = a != b (should output true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -357,17 +365,17 @@ func TestVMComparisonNotEqual(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "notEqual"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "true\n", output)
 }
 
 func TestVMComparisonGreaterOrEqual(t *testing.T) {
 	// Test: a = 10, print a >= 10 (should output true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -382,17 +390,17 @@ func TestVMComparisonGreaterOrEqual(t *testing.T) {
 				Right:    &parser.NumericLiteral{Value: 10},
 			},
 		},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "true\n", output)
 }
 
 func TestVMComparisonLessOrEqual(t *testing.T) {
 	// Test: b = 5, a = 10, isLessOrEqual = b <= a (should output true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -414,17 +422,17 @@ func TestVMComparisonLessOrEqual(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "isLessOrEqual"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "true\n", output)
 }
 
 func TestVMComparisonFalseResult(t *testing.T) {
 	// Test: a = 10, b = 5, isEqual = a == b (should output false)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -446,17 +454,17 @@ func TestVMComparisonFalseResult(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "isEqual"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "false\n", output)
 }
 
 func TestVMBooleanComparison(t *testing.T) {
 	// Test: flag1 = True, flag2 = True, areEqual = flag1 == flag2 (should output true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -478,17 +486,17 @@ func TestVMBooleanComparison(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "areEqual"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "true\n", output)
 }
 
 func TestVMStringComparison(t *testing.T) {
 	// Test: s1 = "hello", s2 = "hello", areEqual = s1 == s2 (should output true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -510,11 +518,10 @@ func TestVMStringComparison(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "areEqual"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "true\n", output)
 }
@@ -547,6 +554,7 @@ func TestVMUndefinedVariableError(t *testing.T) {
 func TestVMIfThenElseTrueBranch(t *testing.T) {
 	// Test: x = 10, if x > 5 then print "big" else print "small"
 	// Expected output: "big" (because x > 5 is true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -565,11 +573,10 @@ func TestVMIfThenElseTrueBranch(t *testing.T) {
 		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "big"}},
 		{Op: ir.OpJump, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 5}},
 		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "small"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "big\n", output)
 }
@@ -577,6 +584,7 @@ func TestVMIfThenElseTrueBranch(t *testing.T) {
 func TestVMIfThenElseFalseBranch(t *testing.T) {
 	// Test: x = 3, if x > 5 then print "big" else print "small"
 	// Expected output: "small" (because x > 5 is false)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -595,11 +603,10 @@ func TestVMIfThenElseFalseBranch(t *testing.T) {
 		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "big"}},
 		{Op: ir.OpJump, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 5}},
 		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "small"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "small\n", output)
 }
@@ -607,6 +614,7 @@ func TestVMIfThenElseFalseBranch(t *testing.T) {
 func TestVMIfThenNoElse(t *testing.T) {
 	// Test: x = 10, if x > 5 then print "big" (no else)
 	// Expected output: "big" (because x > 5 is true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -623,11 +631,10 @@ func TestVMIfThenNoElse(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "big"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "big\n", output)
 }
@@ -635,6 +642,7 @@ func TestVMIfThenNoElse(t *testing.T) {
 func TestVMIfThenNoElseSkipped(t *testing.T) {
 	// Test: x = 3, if x > 5 then print "big" (no else)
 	// Expected output: "" (nothing printed because x > 5 is false)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		{
 			Op:   ir.OpSet,
@@ -651,11 +659,10 @@ func TestVMIfThenNoElseSkipped(t *testing.T) {
 			},
 		},
 		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "big"}},
-	})
+	}, vm.WithStdout(&out))
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
-	})
+	require.Nil(t, v.Run())
+	output := out.String()
 
 	assert.Equal(t, "", output)
 }
@@ -663,6 +670,7 @@ func TestVMIfThenNoElseSkipped(t *testing.T) {
 func TestVMNestedIfThenElse(t *testing.T) {
 	// Test nested if: x = 10, if x > 5 then (if x < 20 then print "medium" else print "large") else print "small"
 	// Expected output: "medium" (because x > 5 is true and x < 20 is true)
+	var out bytes.Buffer
 	v := vm.New([]ir.Instruction{
 		// 0: x = 10
 		{
@@ -700,11 +708,863 @@ func TestVMNestedIfThenElse(t *testing.T) {
 		{Op: ir.OpJump, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 8}},
 		// 7: Print "small"
 		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "small"}},
+	}, vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+	output := out.String()
+
+	assert.Equal(t, "medium\n", output)
+}
+
+// infiniteLoop returns a program that jumps back to its first instruction forever
+func infiniteLoop() []ir.Instruction {
+	return []ir.Instruction{
+		{
+			Op:   ir.OpSet,
+			Arg:  ir.Operand{Kind: ir.OperandIdentifier, Value: "x"},
+			Expr: &parser.NumericLiteral{Value: 1},
+		},
+		{Op: ir.OpJump, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 0}},
+	}
+}
+
+func TestVMAssert(t *testing.T) {
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpAssert, Expr: &parser.BooleanLiteral{Value: true}},
+		{Op: ir.OpAssert, Expr: &parser.BinaryExpr{
+			Left:     &parser.NumericLiteral{Value: 1},
+			Operator: "==",
+			Right:    &parser.NumericLiteral{Value: 2},
+		}},
+	})
+
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrAssertionFailed, err.Code)
+	assert.Equal(t, "assertion failed: (1 == 2)", err.Message)
+	assert.Equal(t, 1, v.PC())
+}
+
+func TestVMAssertMessage(t *testing.T) {
+	v := vm.New([]ir.Instruction{
+		{
+			Op:   ir.OpAssert,
+			Expr: &parser.BooleanLiteral{Value: false},
+			Msg:  &parser.StringLiteral{Value: "x should be positive"},
+		},
 	})
 
-	output := test_util.CaptureStdout(t, func() {
-		require.Nil(t, v.Run())
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, "assertion failed: x should be positive", err.Message)
+}
+
+func TestVMAssertMustBeBoolean(t *testing.T) {
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpAssert, Expr: &parser.NumericLiteral{Value: 1}},
 	})
 
-	assert.Equal(t, "medium\n", output)
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, "assertion must be a boolean, got int", err.Message)
+}
+
+func TestVMRuntimeErrorPosition(t *testing.T) {
+	file := source.NewFile("pos.yap", []byte("- print: 1\n- print: missing\n"))
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.NumericLiteral{Value: 1}},
+		{
+			Op:   ir.OpPrint,
+			Expr: &parser.Identifier{Name: "missing"},
+			Span: source.Span{File: file, Start: source.Position{Line: 2, Column: 1}, End: source.Position{Line: 2, Column: 17}},
+		},
+	}, vm.WithStdout(&bytes.Buffer{}))
+
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.Position{File: "pos.yap", Line: 2, Column: 1}, err.Position)
+	assert.Equal(t, "- print: missing", err.Context)
+	assert.Equal(t, "pos.yap:2:1: error: undefined variable: missing", err.Error())
+}
+
+// expectErrorBlock wraps body in an expect_error block followed by a print
+func expectErrorBlock(body ...ir.Instruction) []ir.Instruction {
+	end := len(body) + 2
+	program := []ir.Instruction{{Op: ir.OpExpectError, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: end}}}
+	program = append(program, body...)
+	return append(program,
+		ir.Instruction{Op: ir.OpEndExpectError, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 0}},
+		ir.Instruction{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "after"}},
+	)
+}
+
+func TestVMExpectErrorRecovers(t *testing.T) {
+	var out bytes.Buffer
+	v := vm.New(expectErrorBlock(
+		ir.Instruction{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "before"}},
+		ir.Instruction{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "missing"}},
+		ir.Instruction{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "skipped"}},
+	), vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "before\nafter\n", out.String())
+}
+
+func TestVMExpectErrorNotRaised(t *testing.T) {
+	var out bytes.Buffer
+	v := vm.New(expectErrorBlock(
+		ir.Instruction{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "fine"}},
+	), vm.WithStdout(&out))
+
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrErrorNotRaised, err.Code)
+	assert.Equal(t, ir.OpEndExpectError, v.Instructions()[v.PC()].Op)
+	assert.Equal(t, "fine\n", out.String())
+}
+
+func TestVMExpectErrorDoesNotCatchLimits(t *testing.T) {
+	v := vm.New(expectErrorBlock(
+		ir.Instruction{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "a"}},
+		ir.Instruction{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "b"}},
+	), vm.WithMaxSteps(2), vm.WithStdout(&bytes.Buffer{}))
+
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrStepLimitExceeded, err.Code)
+}
+
+func TestVMMaxStepsExceeded(t *testing.T) {
+	v := vm.New(infiniteLoop(), vm.WithMaxSteps(100))
+
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrStepLimitExceeded, err.Code)
+	assert.Equal(t, yaperror.PhaseRuntime, err.Phase)
+}
+
+func TestVMMaxStepsNotReached(t *testing.T) {
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "a"}},
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "b"}},
+	}, vm.WithMaxSteps(2), vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+	output := out.String()
+
+	assert.Equal(t, "a\nb\n", output)
+}
+
+func TestVMTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	v := vm.New(infiniteLoop())
+	err := v.RunContext(ctx)
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrTimeout, err.Code)
+	assert.Contains(t, err.Error(), "deadline exceeded")
+}
+
+func TestVMCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	v := vm.New(infiniteLoop())
+	err := v.RunContext(ctx)
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrTimeout, err.Code)
+}
+
+func TestVMMaxCallDepthExceeded(t *testing.T) {
+	// ((((1 + 1) + 1) + 1) + 1) nests five evaluations deep
+	var expr parser.Value = &parser.NumericLiteral{Value: 1}
+	for i := 0; i < 4; i++ {
+		expr = &parser.BinaryExpr{Left: expr, Operator: "+", Right: &parser.NumericLiteral{Value: 1}}
+	}
+
+	v := vm.New([]ir.Instruction{{Op: ir.OpPrint, Expr: expr}}, vm.WithMaxCallDepth(3))
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrCallDepthExceeded, err.Code)
+
+	var out bytes.Buffer
+	v = vm.New([]ir.Instruction{{Op: ir.OpPrint, Expr: expr}}, vm.WithMaxCallDepth(5), vm.WithStdout(&out))
+	require.Nil(t, v.Run())
+	assert.Equal(t, "5\n", out.String())
+}
+
+func TestVMMaxMemoryExceededByConcat(t *testing.T) {
+	v := vm.New([]ir.Instruction{
+		{
+			Op: ir.OpPrint,
+			Expr: &parser.BinaryExpr{
+				Left:     &parser.StringLiteral{Value: "hello"},
+				Operator: "+",
+				Right:    &parser.StringLiteral{Value: "world"},
+			},
+		},
+	}, vm.WithMaxMemory(8))
+
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+}
+
+// Concatenation counts the memory already held by variables
+func TestVMMaxMemoryExceededByConcatOfHeldStrings(t *testing.T) {
+	concat := &parser.BinaryExpr{
+		Left:     &parser.StringLiteral{Value: "abc"},
+		Operator: "+",
+		Right:    &parser.StringLiteral{Value: "def"},
+	}
+	set := func(name string) ir.Instruction {
+		return ir.Instruction{Op: ir.OpSet, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: name}, Expr: concat}
+	}
+
+	printConcat := ir.Instruction{Op: ir.OpPrint, Expr: concat}
+	require.Nil(t, vm.New([]ir.Instruction{set("a")}, vm.WithMaxMemory(10)).Run())
+	require.Nil(t, vm.New([]ir.Instruction{printConcat}, vm.WithStdout(&bytes.Buffer{}), vm.WithMaxMemory(10)).Run())
+
+	err := vm.New([]ir.Instruction{set("a"), printConcat}, vm.WithStdout(&bytes.Buffer{}), vm.WithMaxMemory(10)).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+}
+
+func TestVMMaxMemoryExceededByVariables(t *testing.T) {
+	set := func(name, val string) ir.Instruction {
+		return ir.Instruction{
+			Op:   ir.OpSet,
+			Arg:  ir.Operand{Kind: ir.OperandIdentifier, Value: name},
+			Expr: &parser.StringLiteral{Value: val},
+		}
+	}
+
+	// Reassigning a variable releases the memory of its previous value
+	v := vm.New([]ir.Instruction{set("a", "12345"), set("a", "12345")}, vm.WithMaxMemory(8))
+	require.Nil(t, v.Run())
+
+	v = vm.New([]ir.Instruction{set("a", "12345"), set("b", "12345")}, vm.WithMaxMemory(8))
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+}
+
+func TestVMPrintMultipleValues(t *testing.T) {
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{
+			Op: ir.OpPrint,
+			Expr: &parser.ExprList{Values: []parser.Value{
+				&parser.StringLiteral{Value: "x ="},
+				&parser.NumericLiteral{Value: 10},
+				&parser.BooleanLiteral{Value: true},
+			}},
+		},
+	}, vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "x = 10 true\n", out.String())
+}
+
+func TestVMPrintSepAndNoNewline(t *testing.T) {
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{
+			Op: ir.OpPrint,
+			Expr: &parser.ExprList{Values: []parser.Value{
+				&parser.NumericLiteral{Value: 1},
+				&parser.NumericLiteral{Value: 2},
+			}},
+			Print: &ir.PrintOptions{Sep: &parser.StringLiteral{Value: ", "}, NoNewline: true},
+		},
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "!"}},
+	}, vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "1, 2!\n", out.String())
+}
+
+func TestVMPrintSepMustBeString(t *testing.T) {
+	v := vm.New([]ir.Instruction{
+		{
+			Op:    ir.OpPrint,
+			Expr:  &parser.StringLiteral{Value: "a"},
+			Print: &ir.PrintOptions{Sep: &parser.NumericLiteral{Value: 1}},
+		},
+	}, vm.WithStdout(&bytes.Buffer{}))
+
+	err := v.Run()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "separator")
+}
+
+func TestVMPrintStderr(t *testing.T) {
+	var out, errOut bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "to stdout"}},
+		{
+			Op:    ir.OpPrint,
+			Expr:  &parser.StringLiteral{Value: "to stderr"},
+			Print: &ir.PrintOptions{Stderr: true},
+		},
+	}, vm.WithStdout(&out), vm.WithStderr(&errOut))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "to stdout\n", out.String())
+	assert.Equal(t, "to stderr\n", errOut.String())
+}
+
+// recordingHook records the pc of every instruction and can stop or fail the run
+type recordingHook struct {
+	pcs    []int
+	stopAt int
+	err    *yaperror.YapError
+}
+
+func (h *recordingHook) BeforeInstruction(v *vm.VM, pc int, instr ir.Instruction) *yaperror.YapError {
+	h.pcs = append(h.pcs, pc)
+	if pc == h.stopAt {
+		v.Stop()
+	}
+	return h.err
+}
+
+func TestVMHookCalledBeforeEachInstruction(t *testing.T) {
+	var out bytes.Buffer
+	hook := &recordingHook{stopAt: -1}
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpJumpIfFalse, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 2}, Expr: &parser.BooleanLiteral{Value: false}},
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "skipped"}},
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "a"}},
+	}, vm.WithHook(hook), vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+
+	assert.Equal(t, []int{0, 2}, hook.pcs)
+	assert.Equal(t, "a\n", out.String())
+}
+
+func TestVMHookStop(t *testing.T) {
+	var out bytes.Buffer
+	hook := &recordingHook{stopAt: 1}
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "a"}},
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "b"}},
+	}, vm.WithHook(hook), vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+
+	assert.Equal(t, "a\n", out.String())
+	assert.Equal(t, 1, v.PC())
+}
+
+func TestVMHookError(t *testing.T) {
+	hook := &recordingHook{stopAt: -1, err: yaperror.NewRuntimeError("stopped by hook")}
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "a"}},
+	}, vm.WithHook(hook))
+
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, "stopped by hook", err.Message)
+}
+
+func TestVMInspectAndAssign(t *testing.T) {
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "x"}},
+	}, vm.WithStdout(&out))
+
+	require.Nil(t, v.Assign("x", 41))
+	val, err := v.Evaluate(&parser.BinaryExpr{
+		Left:     &parser.Identifier{Name: "x"},
+		Operator: "+",
+		Right:    &parser.NumericLiteral{Value: 1},
+	})
+	require.Nil(t, err)
+	require.Nil(t, v.Assign("x", val))
+
+	got, ok := v.Lookup("x")
+	assert.True(t, ok)
+	assert.Equal(t, 42, got)
+	assert.Equal(t, []string{"x"}, v.Variables())
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "42\n", out.String())
+}
+
+func TestVMUndefinedVariableHint(t *testing.T) {
+	v := vm.New([]ir.Instruction{
+		{
+			Op:   ir.OpSet,
+			Arg:  ir.Operand{Kind: ir.OperandIdentifier, Value: "count"},
+			Expr: &parser.NumericLiteral{Value: 1},
+		},
+		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "cuont"}},
+	}, vm.WithStdout(&bytes.Buffer{}))
+
+	err := v.Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrUndefinedVariable, err.Code)
+	assert.Equal(t, []string{`did you mean "count"?`}, err.Hints)
+}
+
+func TestVMImport(t *testing.T) {
+	mod := &ir.Module{Path: "/lib/util.yap", Instructions: []ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "loading"}},
+		{
+			Op:   ir.OpSet,
+			Arg:  ir.Operand{Kind: ir.OperandIdentifier, Value: "answer"},
+			Expr: &parser.NumericLiteral{Value: 42},
+		},
+	}}
+	answer := func(module string) *parser.SelectorExpr {
+		return &parser.SelectorExpr{X: &parser.Identifier{Name: module}, Name: "answer"}
+	}
+
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "util"}, Module: mod},
+		{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "u"}, Module: mod},
+		{Op: ir.OpPrint, Expr: answer("util")},
+		{Op: ir.OpPrint, Expr: answer("u")},
+		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "u"}},
+	}, vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "loading\n42\n42\n<module u>\n", out.String())
+}
+
+func TestVMImportErrors(t *testing.T) {
+	mod := &ir.Module{Path: "/lib/util.yap", Instructions: []ir.Instruction{{
+		Op:   ir.OpSet,
+		Arg:  ir.Operand{Kind: ir.OperandIdentifier, Value: "answer"},
+		Expr: &parser.NumericLiteral{Value: 42},
+	}}}
+	run := func(expr parser.Value) *yaperror.YapError {
+		return vm.New([]ir.Instruction{
+			{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "util"}, Module: mod},
+			{Op: ir.OpPrint, Expr: expr},
+		}, vm.WithStdout(&bytes.Buffer{})).Run()
+	}
+
+	err := run(&parser.SelectorExpr{X: &parser.Identifier{Name: "util"}, Name: "anser"})
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrUndefinedVariable, err.Code)
+	assert.Equal(t, []string{`did you mean "answer"?`}, err.Hints)
+
+	err = run(&parser.SelectorExpr{X: &parser.NumericLiteral{Value: 1}, Name: "answer"})
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrInvalidType, err.Code)
+}
+
+func TestVMImportSharesStepLimit(t *testing.T) {
+	mod := &ir.Module{Path: "/lib/util.yap", Instructions: []ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.NumericLiteral{Value: 1}},
+		{Op: ir.OpPrint, Expr: &parser.NumericLiteral{Value: 2}},
+	}}
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "util"}, Module: mod},
+		{Op: ir.OpPrint, Expr: &parser.NumericLiteral{Value: 3}},
+	}, vm.WithStdout(&bytes.Buffer{}), vm.WithMaxSteps(3))
+
+	err := v.Run()
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrStepLimitExceeded, err.Code)
+}
+
+func TestVMImportSharesMemoryLimit(t *testing.T) {
+	set := func(name, val string) ir.Instruction {
+		return ir.Instruction{
+			Op:   ir.OpSet,
+			Arg:  ir.Operand{Kind: ir.OperandIdentifier, Value: name},
+			Expr: &parser.StringLiteral{Value: val},
+		}
+	}
+	mod := &ir.Module{Path: "/lib/util.yap", Instructions: []ir.Instruction{set("a", "12345")}}
+	imp := ir.Instruction{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "util"}, Module: mod}
+
+	// The variables of the module count after the import
+	err := vm.New([]ir.Instruction{imp, set("b", "12345")}, vm.WithMaxMemory(8)).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+
+	// and the module sees the memory of the program importing it
+	err = vm.New([]ir.Instruction{set("b", "12345"), imp}, vm.WithMaxMemory(8)).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+}
+
+func TestVMFloatArithmetic(t *testing.T) {
+	var out bytes.Buffer
+	binary := func(left parser.Value, op string, right parser.Value) *parser.BinaryExpr {
+		return &parser.BinaryExpr{Left: left, Operator: op, Right: right}
+	}
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.ExprList{Values: []parser.Value{
+			binary(&parser.NumericLiteral{Value: 1}, "+", &parser.FloatLiteral{Value: 0.5}),
+			binary(&parser.FloatLiteral{Value: 3}, "/", &parser.NumericLiteral{Value: 2}),
+			binary(&parser.FloatLiteral{Value: 1.5}, "*", &parser.NumericLiteral{Value: 2}),
+			binary(&parser.NumericLiteral{Value: 1}, "==", &parser.FloatLiteral{Value: 1}),
+			binary(&parser.NumericLiteral{Value: 3}, "/", &parser.NumericLiteral{Value: 2}),
+		}}},
+	}, vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "1.5 1.5 3.0 true 1\n", out.String())
+
+	err := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: binary(&parser.FloatLiteral{Value: 1}, "/", &parser.NumericLiteral{Value: 0})},
+	}, vm.WithStdout(&out)).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, "division by zero", err.Message)
+}
+
+func TestVMFormatValue(t *testing.T) {
+	assert.Equal(t, "2.0", vm.FormatValue(2.0))
+	assert.Equal(t, "0.1", vm.FormatValue(0.1))
+	assert.Equal(t, "1e+21", vm.FormatValue(1e21))
+	assert.Equal(t, "-Inf", vm.FormatValue(math.Inf(-1)))
+	assert.Equal(t, "NaN", vm.FormatValue(math.NaN()))
+	assert.Equal(t, "42", vm.FormatValue(42))
+}
+
+func TestVMCallBuiltin(t *testing.T) {
+	file := source.NewFile("call.yap", []byte("- print: math.sqrt(x)\n"))
+	span := func(from, to int) source.Span {
+		return source.Span{File: file, Start: source.Position{Line: 1, Column: from}, End: source.Position{Line: 1, Column: to}}
+	}
+	sqrt := func(arg parser.Value) *parser.CallExpr {
+		return &parser.CallExpr{
+			Fun:  &parser.SelectorExpr{X: &parser.Identifier{Name: "math"}, Name: "sqrt", Loc: span(10, 19)},
+			Args: []parser.Value{arg},
+			Loc:  span(10, 22),
+		}
+	}
+	run := func(expr parser.Value) (string, *yaperror.YapError) {
+		var out bytes.Buffer
+		err := vm.New([]ir.Instruction{
+			{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "math"}, Module: &ir.Module{Path: "math", Builtin: true}},
+			{Op: ir.OpPrint, Expr: expr, Span: span(1, 22)},
+		}, vm.WithStdout(&out)).Run()
+		return out.String(), err
+	}
+
+	out, err := run(sqrt(&parser.NumericLiteral{Value: 16}))
+	require.Nil(t, err)
+	assert.Equal(t, "4.0\n", out)
+
+	// Errors of the function point at the call rather than the statement
+	_, err = run(sqrt(&parser.StringLiteral{Value: "x"}))
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrInvalidType, err.Code)
+	assert.Equal(t, 10, err.Position.Column)
+	assert.Equal(t, "- print: math.sqrt(x)", err.Context)
+
+	call := sqrt(&parser.NumericLiteral{Value: 16})
+	call.Fun.(*parser.SelectorExpr).Name = "sqr"
+	_, err = run(call)
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrUndefinedFunction, err.Code)
+	assert.Equal(t, []string{`did you mean "sqrt"?`}, err.Hints)
+
+	_, err = run(&parser.CallExpr{Fun: &parser.NumericLiteral{Value: 1}, Loc: span(10, 13)})
+	require.NotNil(t, err)
+	assert.Equal(t, "cannot call 1 of type int", err.Message)
+}
+
+func TestVMCallGlobal(t *testing.T) {
+	str := func(arg parser.Value) *parser.CallExpr {
+		return &parser.CallExpr{Fun: &parser.Identifier{Name: "str"}, Args: []parser.Value{arg}}
+	}
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.BinaryExpr{Left: str(&parser.NumericLiteral{Value: 4}), Operator: "+", Right: &parser.StringLiteral{Value: "!"}}},
+		{
+			Op:   ir.OpSet,
+			Arg:  ir.Operand{Kind: ir.OperandIdentifier, Value: "str"},
+			Expr: &parser.StringLiteral{Value: "shadowed"},
+		},
+		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "str"}},
+	}, vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "4!\nshadowed\n", out.String())
+
+	err := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.CallExpr{Fun: &parser.Identifier{Name: "strr"}}},
+	}, vm.WithStdout(&out)).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrUndefinedFunction, err.Code)
+	assert.Equal(t, []string{`did you mean "str"?`}, err.Hints)
+}
+
+func TestVMCallMemoryLimit(t *testing.T) {
+	repeat := &parser.CallExpr{
+		Fun: &parser.SelectorExpr{X: &parser.Identifier{Name: "strings"}, Name: "repeat"},
+		Args: []parser.Value{
+			&parser.StringLiteral{Value: "ab"},
+			&parser.NumericLiteral{Value: 100},
+		},
+	}
+	err := vm.New([]ir.Instruction{
+		{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "strings"}, Module: &ir.Module{Path: "strings", Builtin: true}},
+		{Op: ir.OpPrint, Expr: repeat},
+	}, vm.WithStdout(&bytes.Buffer{}), vm.WithMaxMemory(64)).Run()
+
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+}
+
+func TestVMMaxMemoryExceededByNumbers(t *testing.T) {
+	parse := func(src string) []ir.Instruction {
+		return []ir.Instruction{{
+			Op:  ir.OpSet,
+			Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "numbers"},
+			Expr: &parser.CallExpr{
+				Fun:  &parser.Identifier{Name: "json_parse"},
+				Args: []parser.Value{&parser.StringLiteral{Value: src}},
+			},
+		}}
+	}
+
+	require.Nil(t, vm.New(parse("[1, 2, 3]"), vm.WithMaxMemory(64)).Run())
+
+	err := vm.New(parse("[1, 2, 3, 4, 5, 6, 7, 8, 9]"), vm.WithMaxMemory(64)).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+
+	err = vm.New(parse(`{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8}`), vm.WithMaxMemory(64)).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrMemoryLimitExceeded, err.Code)
+}
+
+func TestVMForLoopWithFS(t *testing.T) {
+	fsys := stdlib.NewMemFS(map[string]string{"in.txt": "a\nb\n"})
+	fsCall := func(name string, args ...parser.Value) *parser.CallExpr {
+		return &parser.CallExpr{Fun: &parser.SelectorExpr{X: &parser.Identifier{Name: "fs"}, Name: name}, Args: args}
+	}
+	line := &parser.Identifier{Name: "line"}
+
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "fs"}, Module: &ir.Module{Path: "fs", Builtin: true}},
+		{Op: ir.OpForStart, Expr: fsCall("read_lines", &parser.StringLiteral{Value: "in.txt"})},
+		{Op: ir.OpForNext, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "line", Offset: 5}},
+		{Op: ir.OpCall, Expr: fsCall("append_line", &parser.StringLiteral{Value: "out.txt"}, line)},
+		{Op: ir.OpJump, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 2}},
+		{Op: ir.OpPrint, Expr: line},
+	}, vm.WithStdout(&out), vm.WithFS(fsys))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "b\n", out.String())
+	data, err := fsys.ReadFile("out.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\n", string(data))
+}
+
+// A loop left by an error starts over with its first item when it runs again
+func TestVMForLoopRestarts(t *testing.T) {
+	list := &parser.CallExpr{
+		Fun:  &parser.SelectorExpr{X: &parser.Identifier{Name: "strings"}, Name: "split"},
+		Args: []parser.Value{&parser.StringLiteral{Value: "1,0"}, &parser.StringLiteral{Value: ","}},
+	}
+	quotient := &parser.BinaryExpr{
+		Left:     &parser.NumericLiteral{Value: 1},
+		Operator: "/",
+		Right:    &parser.CallExpr{Fun: &parser.Identifier{Name: "int"}, Args: []parser.Value{&parser.Identifier{Name: "x"}}},
+	}
+
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "strings"}, Module: &ir.Module{Path: "strings", Builtin: true}},
+		{Op: ir.OpSet, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "runs"}, Expr: &parser.NumericLiteral{Value: 0}},
+		{Op: ir.OpSet, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "runs"}, Expr: &parser.BinaryExpr{
+			Left: &parser.Identifier{Name: "runs"}, Operator: "+", Right: &parser.NumericLiteral{Value: 1},
+		}},
+		{Op: ir.OpExpectError, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 9}},
+		{Op: ir.OpForStart, Expr: list},
+		{Op: ir.OpForNext, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "x", Offset: 8}},
+		{Op: ir.OpPrint, Expr: quotient},
+		{Op: ir.OpJump, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 5}},
+		{Op: ir.OpEndExpectError, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 3}},
+		{Op: ir.OpJumpIfFalse, Expr: &parser.BinaryExpr{
+			Left: &parser.Identifier{Name: "runs"}, Operator: "<", Right: &parser.NumericLiteral{Value: 2},
+		}, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 11}},
+		{Op: ir.OpJump, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 2}},
+	}, vm.WithStdout(&out))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "1\n1\n", out.String())
+}
+
+func TestVMForLoopErrors(t *testing.T) {
+	err := vm.New([]ir.Instruction{
+		{Op: ir.OpForStart, Expr: &parser.StringLiteral{Value: "abc"}},
+		{Op: ir.OpForNext, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "x", Offset: 3}},
+		{Op: ir.OpJump, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 1}},
+	}).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, "for needs a list, got string", err.Message)
+
+	// Functions without a result can only be called by a call statement
+	write := &parser.CallExpr{
+		Fun:  &parser.SelectorExpr{X: &parser.Identifier{Name: "fs"}, Name: "write_file"},
+		Args: []parser.Value{&parser.StringLiteral{Value: "out.txt"}, &parser.StringLiteral{Value: "x"}},
+	}
+	err = vm.New([]ir.Instruction{
+		{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "fs"}, Module: &ir.Module{Path: "fs", Builtin: true}},
+		{Op: ir.OpPrint, Expr: write},
+	}, vm.WithFS(stdlib.NewMemFS(nil))).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, "fs.write_file does not return a value", err.Message)
+}
+
+func TestVMInput(t *testing.T) {
+	name := &parser.Identifier{Name: "name"}
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpInput, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "name"}, Expr: &parser.StringLiteral{Value: "Name? "}},
+		{Op: ir.OpPrint, Expr: name},
+		{Op: ir.OpInput, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "name"}},
+		{Op: ir.OpPrint, Expr: name},
+		{Op: ir.OpPrint, Expr: &parser.CallExpr{Fun: &parser.Identifier{Name: "read_all_stdin"}}},
+		{Op: ir.OpInput, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "name"}},
+	}, vm.WithStdout(&out), vm.WithStdin(strings.NewReader("Ada\r\nGrace\nrest\nof it")))
+
+	err := v.Run()
+	assert.Equal(t, "Name? Ada\nGrace\nrest\nof it\n", out.String())
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrEndOfInput, err.Code)
+	assert.Equal(t, `end of input: no line left to read into "name"`, err.Message)
+}
+
+// The last line is read even without a line ending
+func TestVMInputLastLine(t *testing.T) {
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpInput, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "x"}},
+		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "x"}},
+		{Op: ir.OpExpectError, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 5}},
+		{Op: ir.OpInput, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "x"}},
+		{Op: ir.OpEndExpectError, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 2}},
+		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "x"}},
+	}, vm.WithStdout(&out), vm.WithStdin(strings.NewReader("last")))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "last\nlast\n", out.String())
+}
+
+func TestVMArgs(t *testing.T) {
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "args"}},
+		{Op: ir.OpSet, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "args"}, Expr: &parser.NumericLiteral{Value: 1}},
+		{Op: ir.OpPrint, Expr: &parser.Identifier{Name: "args"}},
+	}, vm.WithStdout(&out), vm.WithArgs([]string{"in.csv", "--dry-run"}))
+
+	require.Nil(t, v.Run())
+	assert.Equal(t, "[\"in.csv\", \"--dry-run\"]\n1\n", out.String())
+}
+
+// An exit ends the program, even inside an expect_error block or a module
+func TestVMExit(t *testing.T) {
+	module := &ir.Module{Path: "lib.yap", Instructions: []ir.Instruction{
+		{Op: ir.OpExit, Expr: &parser.NumericLiteral{Value: 4}},
+	}}
+	tests := []struct {
+		name   string
+		instrs []ir.Instruction
+		code   int
+	}{
+		{"exit", []ir.Instruction{
+			{Op: ir.OpExit, Expr: &parser.NumericLiteral{Value: 3}},
+		}, 3},
+		{"expect_error", []ir.Instruction{
+			{Op: ir.OpExpectError, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 3}},
+			{Op: ir.OpExit, Expr: &parser.NumericLiteral{Value: 0}},
+			{Op: ir.OpEndExpectError, Arg: ir.Operand{Kind: ir.OperandOffset, Offset: 0}},
+		}, 0},
+		{"module", []ir.Instruction{
+			{Op: ir.OpImport, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "lib"}, Module: module},
+		}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			instrs := append(tt.instrs, ir.Instruction{Op: ir.OpPrint, Expr: &parser.StringLiteral{Value: "not reached"}})
+			v := vm.New(instrs, vm.WithStdout(&out))
+
+			require.Nil(t, v.Run())
+			assert.Equal(t, tt.code, v.ExitCode())
+			assert.Empty(t, out.String())
+		})
+	}
+}
+
+func TestVMExitErrors(t *testing.T) {
+	tests := []struct {
+		code parser.Value
+		msg  string
+	}{
+		{&parser.StringLiteral{Value: "1"}, "exit needs an int, got string"},
+		{&parser.NumericLiteral{Value: 256}, "exit code must be from 0 to 255, got 256"},
+	}
+	for _, tt := range tests {
+		v := vm.New([]ir.Instruction{{Op: ir.OpExit, Expr: tt.code}})
+		err := v.Run()
+		require.NotNil(t, err)
+		assert.Equal(t, tt.msg, err.Message)
+		assert.Equal(t, 0, v.ExitCode())
+	}
+}
+
+func TestVMEnvLookup(t *testing.T) {
+	home := &parser.CallExpr{Fun: &parser.Identifier{Name: "env"}, Args: []parser.Value{&parser.StringLiteral{Value: "HOME"}}}
+	instrs := []ir.Instruction{{Op: ir.OpPrint, Expr: home}}
+
+	err := vm.New(instrs).Run()
+	require.NotNil(t, err)
+	assert.Equal(t, yaperror.ErrEnvNotAllowed, err.Code)
+
+	var out bytes.Buffer
+	lookup := func(name string) (string, bool) { return "/home/" + strings.ToLower(name), true }
+	require.Nil(t, vm.New(instrs, vm.WithStdout(&out), vm.WithEnvLookup(lookup)).Run())
+	assert.Equal(t, "/home/home\n", out.String())
+}
+
+// The keys of a map are selected like the variables of a module
+func TestVMSelectMapKey(t *testing.T) {
+	parse := &parser.CallExpr{Fun: &parser.Identifier{Name: "yaml_parse"}, Args: []parser.Value{&parser.StringLiteral{Value: "server: {port: 8080}"}}}
+	cfg := &parser.Identifier{Name: "cfg"}
+	server := &parser.SelectorExpr{X: cfg, Name: "server"}
+
+	var out bytes.Buffer
+	v := vm.New([]ir.Instruction{
+		{Op: ir.OpSet, Arg: ir.Operand{Kind: ir.OperandIdentifier, Value: "cfg"}, Expr: parse},
+		{Op: ir.OpPrint, Expr: &parser.BinaryExpr{Left: &parser.SelectorExpr{X: server, Name: "port"}, Operator: "+", Right: &parser.NumericLiteral{Value: 1}}},
+		{Op: ir.OpPrint, Expr: &parser.SelectorExpr{X: cfg, Name: "sever"}},
+	}, vm.WithStdout(&out))
+
+	err := v.Run()
+	assert.Equal(t, "8081\n", out.String())
+	require.NotNil(t, err)
+	assert.Equal(t, `map cfg has no key "sever"`, err.Message)
+	assert.Equal(t, []string{`did you mean "server"?`}, err.Hints)
 }
@@ -0,0 +1,188 @@
+// Package coverage records which statements and branches of a program ran.
+// Profiles are saved as JSON so the counts of many runs can be merged
+package coverage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// Profile holds the coverage of every file a program was built from,
+// keyed by absolute path
+type Profile struct {
+	Files map[string]*File `json:"files"`
+}
+
+// File holds the coverage of one source file
+type File struct {
+	// Statements maps the line a statement starts on to its hit count
+	Statements map[int]int `json:"statements"`
+
+	// Branches maps the line of a conditional to the hit counts of its arms
+	Branches map[int]*Branch `json:"branches"`
+}
+
+// Branch counts how often each arm of an if statement was taken. Else is
+// counted even when the statement has no else block
+type Branch struct {
+	Then int `json:"then"`
+	Else int `json:"else"`
+}
+
+func NewProfile() *Profile {
+	return &Profile{Files: map[string]*File{}}
+}
+
+func (p *Profile) file(path string) *File {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	f, ok := p.Files[path]
+	if !ok {
+		f = &File{Statements: map[int]int{}, Branches: map[int]*Branch{}}
+		p.Files[path] = f
+	}
+	return f
+}
+
+// Paths returns the paths of the covered files in order
+func (p *Profile) Paths() []string {
+	paths := make([]string, 0, len(p.Files))
+	for path := range p.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Merge adds the counts of other to p
+func (p *Profile) Merge(other *Profile) {
+	for path, of := range other.Files {
+		f := p.file(path)
+		for line, hits := range of.Statements {
+			f.Statements[line] += hits
+		}
+		for line, ob := range of.Branches {
+			b, ok := f.Branches[line]
+			if !ok {
+				b = &Branch{}
+				f.Branches[line] = b
+			}
+			b.Then += ob.Then
+			b.Else += ob.Else
+		}
+	}
+}
+
+// Load reads a profile saved by Save
+func Load(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := NewProfile()
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("invalid coverage profile %s: %w", path, err)
+	}
+	if p.Files == nil {
+		p.Files = map[string]*File{}
+	}
+	return p, nil
+}
+
+// Save writes the profile to path
+func (p *Profile) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// MergeInto merges p into the profile saved at path, creating it if needed
+func (p *Profile) MergeInto(path string) error {
+	merged, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		merged = NewProfile()
+	} else if err != nil {
+		return err
+	}
+	merged.Merge(p)
+	return merged.Save(path)
+}
+
+// Collector is a VM hook that counts executed statements and taken branches
+type Collector struct {
+	profile *Profile
+	started bool
+}
+
+func NewCollector() *Collector {
+	return &Collector{profile: NewProfile()}
+}
+
+// Profile returns the counts collected so far
+func (c *Collector) Profile() *Profile {
+	return c.profile
+}
+
+// BeforeInstruction implements vm.Hook
+func (c *Collector) BeforeInstruction(m *vm.VM, pc int, instr ir.Instruction) *yaperror.YapError {
+	if !c.started {
+		// Statements that never run must show up with a count of 0
+		c.started = true
+		for _, in := range m.Instructions() {
+			c.register(in)
+		}
+	}
+	if f, line, ok := c.locate(instr); ok {
+		f.Statements[line]++
+	}
+	return nil
+}
+
+// AfterBranch implements vm.BranchHook
+func (c *Collector) AfterBranch(m *vm.VM, pc int, cond bool) {
+	f, line, ok := c.locate(m.Instructions()[pc])
+	if !ok {
+		return
+	}
+	if cond {
+		f.Branches[line].Then++
+	} else {
+		f.Branches[line].Else++
+	}
+}
+
+func (c *Collector) register(instr ir.Instruction) {
+	f, line, ok := c.locate(instr)
+	if !ok {
+		return
+	}
+	f.Statements[line] += 0
+	// The arms of a loop are running its body again and leaving it
+	if instr.Op == ir.OpJumpIfFalse || instr.Op == ir.OpForStart {
+		if _, ok := f.Branches[line]; !ok {
+			f.Branches[line] = &Branch{}
+		}
+	}
+}
+
+// locate returns the file and line of a statement. Instructions added by
+// the builder have no source
+func (c *Collector) locate(instr ir.Instruction) (*File, int, bool) {
+	span := instr.Span
+	if span.File == nil || span.Start.Line == 0 {
+		return nil, 0, false
+	}
+	return c.profile.file(span.File.Path), span.Start.Line, true
+}
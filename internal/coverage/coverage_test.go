@@ -0,0 +1,113 @@
+package coverage_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/coverage"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProgram writes a program whose branch depends on x and returns its path
+func writeProgram(t *testing.T, x string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cover.yap")
+	src := "- set:\n  - x: " + x + "\n- if: x > 5\n  then:\n    - print: \"big\"\n  else:\n    - print: \"small\"\n"
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	return path
+}
+
+func collect(t *testing.T, path string) *coverage.Profile {
+	t.Helper()
+	prog, err := parser.NewParser(path).Parse()
+	require.NoError(t, err)
+	program, err := build.New().Build(prog.Statements)
+	require.NoError(t, err)
+
+	collector := coverage.NewCollector()
+	require.Nil(t, vm.New(program, vm.WithHook(collector), vm.WithStdout(io.Discard)).Run())
+	return collector.Profile()
+}
+
+func TestCollector(t *testing.T) {
+	path := writeProgram(t, "10")
+	profile := collect(t, path)
+
+	require.Equal(t, []string{path}, profile.Paths())
+	f := profile.Files[path]
+	assert.Equal(t, map[int]int{2: 1, 3: 1, 5: 1, 7: 0}, f.Statements)
+	assert.Equal(t, &coverage.Branch{Then: 1, Else: 0}, f.Branches[3])
+	assert.Equal(t, coverage.Summary{Statements: 4, StatementsHit: 3, Branches: 2, BranchesHit: 1}, f.Summary())
+}
+
+func TestMergeInto(t *testing.T) {
+	path := writeProgram(t, "10")
+	profilePath := filepath.Join(t.TempDir(), "out.cov")
+
+	require.NoError(t, collect(t, path).MergeInto(profilePath))
+	require.NoError(t, collect(t, path).MergeInto(profilePath))
+
+	profile, err := coverage.Load(profilePath)
+	require.NoError(t, err)
+	f := profile.Files[path]
+	assert.Equal(t, 2, f.Statements[2])
+	assert.Equal(t, 0, f.Statements[7])
+	assert.Equal(t, &coverage.Branch{Then: 2, Else: 0}, f.Branches[3])
+}
+
+func TestWriteLCOV(t *testing.T) {
+	path := writeProgram(t, "1")
+	var out bytes.Buffer
+	require.NoError(t, coverage.WriteLCOV(&out, collect(t, path)))
+
+	expected := "TN:\nSF:" + path + "\n" +
+		"BRDA:3,0,0,0\nBRDA:3,0,1,1\nBRF:2\nBRH:1\n" +
+		"DA:2,1\nDA:3,1\nDA:5,0\nDA:7,1\nLF:4\nLH:3\n" +
+		"end_of_record\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestWriteText(t *testing.T) {
+	path := writeProgram(t, "1")
+	var out bytes.Buffer
+	require.NoError(t, coverage.WriteText(&out, collect(t, path)))
+
+	expected := path + ": 75.0% of statements (3/4), 50.0% of branches (1/2)\n" +
+		`        -:    1:- set:
+        1:    2:  - x: 1
+        1:    3:- if: x > 5
+                 then taken 0
+                 else taken 1
+        -:    4:  then:
+    #####:    5:    - print: "big"
+        -:    6:  else:
+        1:    7:    - print: "small"
+
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestLoadInvalidProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.cov")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+
+	_, err := coverage.Load(path)
+	assert.ErrorContains(t, err, "invalid coverage profile")
+}
+
+func TestCollectorLoop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.yap")
+	src := "- import: \"strings\"\n- for: x\n  in: strings.split(\"a,b,c\", \",\")\n  do:\n    - print: x\n"
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+
+	f := collect(t, path).Files[path]
+	assert.Equal(t, map[int]int{1: 1, 2: 1, 5: 3}, f.Statements)
+	assert.Equal(t, &coverage.Branch{Then: 3, Else: 1}, f.Branches[2])
+}
@@ -0,0 +1,136 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Summary counts the covered statements and branch arms of a file
+type Summary struct {
+	Statements, StatementsHit int
+	Branches, BranchesHit     int
+}
+
+func (f *File) Summary() Summary {
+	var s Summary
+	for _, hits := range f.Statements {
+		s.Statements++
+		if hits > 0 {
+			s.StatementsHit++
+		}
+	}
+	for _, b := range f.Branches {
+		s.Branches += 2
+		if b.Then > 0 {
+			s.BranchesHit++
+		}
+		if b.Else > 0 {
+			s.BranchesHit++
+		}
+	}
+	return s
+}
+
+func percent(hit, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(hit) / float64(total)
+}
+
+// WriteText writes each covered file annotated with hit counts in the style
+// of gcov: "-" marks lines without a statement and "#####" statements that
+// never ran. The arms of each if statement follow its line
+func WriteText(w io.Writer, p *Profile) error {
+	for _, path := range p.Paths() {
+		f := p.Files[path]
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading source of %s: %w", path, err)
+		}
+
+		s := f.Summary()
+		fmt.Fprintf(w, "%s: %.1f%% of statements (%d/%d), %.1f%% of branches (%d/%d)\n",
+			relative(path),
+			percent(s.StatementsHit, s.Statements), s.StatementsHit, s.Statements,
+			percent(s.BranchesHit, s.Branches), s.BranchesHit, s.Branches,
+		)
+
+		lines := strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+		for i, text := range lines {
+			line := i + 1
+			count := "-"
+			if hits, ok := f.Statements[line]; ok {
+				count = fmt.Sprint(hits)
+				if hits == 0 {
+					count = "#####"
+				}
+			}
+			fmt.Fprintf(w, "%9s:%5d:%s\n", count, line, strings.TrimRight(text, "\r"))
+			if b, ok := f.Branches[line]; ok {
+				fmt.Fprintf(w, "%9s        then taken %d\n", "", b.Then)
+				fmt.Fprintf(w, "%9s        else taken %d\n", "", b.Else)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// WriteLCOV writes the profile in the LCOV tracefile format. Each if
+// statement is a block with branch 0 for then and branch 1 for else
+func WriteLCOV(w io.Writer, p *Profile) error {
+	for _, path := range p.Paths() {
+		f := p.Files[path]
+		fmt.Fprintln(w, "TN:")
+		fmt.Fprintf(w, "SF:%s\n", path)
+
+		branchLines := sortedKeys(f.Branches)
+		for block, line := range branchLines {
+			b := f.Branches[line]
+			if f.Statements[line] == 0 {
+				// The condition itself never ran
+				fmt.Fprintf(w, "BRDA:%d,%d,0,-\nBRDA:%d,%d,1,-\n", line, block, line, block)
+				continue
+			}
+			fmt.Fprintf(w, "BRDA:%d,%d,0,%d\nBRDA:%d,%d,1,%d\n", line, block, b.Then, line, block, b.Else)
+		}
+
+		s := f.Summary()
+		fmt.Fprintf(w, "BRF:%d\nBRH:%d\n", s.Branches, s.BranchesHit)
+		for _, line := range sortedKeys(f.Statements) {
+			fmt.Fprintf(w, "DA:%d,%d\n", line, f.Statements[line])
+		}
+		fmt.Fprintf(w, "LF:%d\nLH:%d\n", s.Statements, s.StatementsHit)
+		if _, err := fmt.Fprintln(w, "end_of_record"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// relative shortens path for display when it is under the working directory
+func relative(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,149 @@
+package dap
+
+import "encoding/json"
+
+// Base protocol messages. Every message carries a sequence number and type
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+const (
+	messageRequest  = "request"
+	messageResponse = "response"
+	messageEvent    = "event"
+)
+
+// Capabilities lists the optional requests the adapter supports
+type Capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+}
+
+type LaunchArguments struct {
+	Program     string `json:"program"`
+	StopOnEntry bool   `json:"stopOnEntry,omitempty"`
+	NoDebug     bool   `json:"noDebug,omitempty"`
+}
+
+type Source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type SourceBreakpoint struct {
+	Line int `json:"line"`
+}
+
+type SetBreakpointsArguments struct {
+	Source      Source             `json:"source"`
+	Breakpoints []SourceBreakpoint `json:"breakpoints"`
+}
+
+type Breakpoint struct {
+	Verified bool   `json:"verified"`
+	Line     int    `json:"line"`
+	Message  string `json:"message,omitempty"`
+}
+
+type SetBreakpointsResponseBody struct {
+	Breakpoints []Breakpoint `json:"breakpoints"`
+}
+
+type Thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type ThreadsResponseBody struct {
+	Threads []Thread `json:"threads"`
+}
+
+type StackFrame struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Source *Source `json:"source,omitempty"`
+	Line   int     `json:"line"`
+	Column int     `json:"column"`
+}
+
+type StackTraceResponseBody struct {
+	StackFrames []StackFrame `json:"stackFrames"`
+	TotalFrames int          `json:"totalFrames"`
+}
+
+type Scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type ScopesResponseBody struct {
+	Scopes []Scope `json:"scopes"`
+}
+
+type VariablesArguments struct {
+	VariablesReference int `json:"variablesReference"`
+}
+
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+type VariablesResponseBody struct {
+	Variables []Variable `json:"variables"`
+}
+
+type StoppedEventBody struct {
+	Reason            string `json:"reason"`
+	ThreadID          int    `json:"threadId"`
+	AllThreadsStopped bool   `json:"allThreadsStopped"`
+}
+
+type OutputEventBody struct {
+	Category string `json:"category"`
+	Output   string `json:"output"`
+}
+
+type ExitedEventBody struct {
+	ExitCode int `json:"exitCode"`
+}
+
+type ContinueResponseBody struct {
+	AllThreadsContinued bool `json:"allThreadsContinued"`
+}
+
+// Stopped event reasons
+const (
+	ReasonEntry      = "entry"
+	ReasonStep       = "step"
+	ReasonBreakpoint = "breakpoint"
+)
+
+// Output event categories
+const (
+	CategoryStdout  = "stdout"
+	CategoryStderr  = "stderr"
+	CategoryConsole = "console"
+)
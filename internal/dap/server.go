@@ -0,0 +1,416 @@
+// Package dap implements a Debug Adapter Protocol server for YAP. The
+// program runs in a VM whose instruction hook pauses it at breakpoints and
+// steps while the server answers the client's requests
+package dap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/debugger"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/source"
+	"github.com/rlamalama/YAP/internal/rpc"
+)
+
+const (
+	// YAP programs run on a single thread with a single frame
+	threadID   = 1
+	threadName = "main"
+	frameID    = 1
+
+	globalsReference = 1
+)
+
+// Server runs one debug session for a client
+type Server struct {
+	conn *rpc.Conn
+
+	sendMu sync.Mutex // orders sequence numbers with writes
+	seq    int
+
+	// mu guards the session state, which is shared with the VM goroutine
+	mu         sync.Mutex
+	file       *source.File
+	program    []ir.Instruction
+	stepper    *debugger.Stepper
+	noDebug    bool
+	launched   bool
+	configured bool
+	quit       bool
+	paused     *pause
+
+	resume chan struct{} // wakes a paused VM
+	done   chan struct{} // closed when the program has finished
+}
+
+// pause is where the VM is waiting for the client
+type pause struct {
+	vm    *vm.VM
+	instr ir.Instruction
+}
+
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		conn:   rpc.NewConn(r, w),
+		resume: make(chan struct{}, 1),
+	}
+}
+
+// Serve handles requests until the client disconnects or closes the connection
+func (s *Server) Serve() error {
+	for {
+		body, err := s.conn.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				s.stop()
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+		if req.Type != messageRequest {
+			continue
+		}
+
+		result, then, err := s.handle(&req)
+		resp := response{
+			Type:       messageResponse,
+			RequestSeq: req.Seq,
+			Success:    err == nil,
+			Command:    req.Command,
+			Body:       result,
+		}
+		if err != nil {
+			resp.Message = err.Error()
+		}
+		if err := s.send(&resp); err != nil {
+			return err
+		}
+
+		// Events that follow a response, e.g. stopped after next
+		if then != nil {
+			then()
+		}
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+// handle answers a request. The returned function, if any, runs once the
+// response has been sent
+func (s *Server) handle(req *request) (interface{}, func(), error) {
+	switch req.Command {
+	case "initialize":
+		return Capabilities{SupportsConfigurationDoneRequest: true}, nil, nil
+
+	case "launch":
+		var args LaunchArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, nil, err
+		}
+		if err := s.launch(args); err != nil {
+			return nil, nil, err
+		}
+		// The client sends breakpoints once it knows the program is loaded
+		return nil, func() { s.sendEvent("initialized", nil) }, nil
+
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, nil, err
+		}
+		return s.setBreakpoints(args)
+
+	case "configurationDone":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.configured = true
+		return nil, s.startLocked(), nil
+
+	case "threads":
+		return ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: threadName}}}, nil, nil
+
+	case "stackTrace":
+		return s.stackTrace()
+
+	case "scopes":
+		return ScopesResponseBody{Scopes: []Scope{{Name: "Globals", VariablesReference: globalsReference}}}, nil, nil
+
+	case "variables":
+		var args VariablesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, nil, err
+		}
+		return s.variables(args)
+
+	case "next":
+		return s.resumeWith(func(st *debugger.Stepper, current ir.Instruction) { st.Next(current) })
+
+	case "stepIn":
+		return s.resumeWith(func(st *debugger.Stepper, _ ir.Instruction) { st.Step() })
+
+	case "continue":
+		_, then, err := s.resumeWith(func(st *debugger.Stepper, _ ir.Instruction) { st.Continue() })
+		if err != nil {
+			return nil, nil, err
+		}
+		return ContinueResponseBody{AllThreadsContinued: true}, then, nil
+
+	case "disconnect", "terminate":
+		return nil, s.stop, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported command %q", req.Command)
+	}
+}
+
+func (s *Server) launch(args LaunchArguments) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.launched {
+		return errors.New("program already launched")
+	}
+
+	prog, err := parser.NewParser(args.Program).Parse()
+	if err != nil {
+		return fmt.Errorf("error parsing program: %w", err)
+	}
+	program, err := build.New().Build(prog.Statements)
+	if err != nil {
+		return fmt.Errorf("error building program: %w", err)
+	}
+
+	s.file = prog.File
+	s.program = program
+	s.stepper = debugger.NewStepper(program, args.StopOnEntry)
+	s.noDebug = args.NoDebug
+	s.launched = true
+	return nil
+}
+
+// startLocked returns a function that starts the program once it has been
+// launched and configured
+func (s *Server) startLocked() func() {
+	if !s.launched || !s.configured || s.done != nil {
+		return nil
+	}
+	s.done = make(chan struct{})
+	return func() { go s.run() }
+}
+
+func (s *Server) run() {
+	defer close(s.done)
+
+	// Stdin carries the protocol, so input statements find an empty input
+	m := vm.New(s.program,
+		vm.WithStdin(strings.NewReader("")),
+		vm.WithStdout(&outputWriter{s: s, category: CategoryStdout}),
+		vm.WithStderr(&outputWriter{s: s, category: CategoryStderr}),
+		vm.WithHook(s),
+	)
+
+	err := m.Run()
+	// An exit statement ends the run without an error
+	exitCode := m.ExitCode()
+	if err != nil {
+		s.sendEvent("output", OutputEventBody{Category: CategoryStderr, Output: err.Error() + "\n"})
+		exitCode = 1
+	}
+	s.sendEvent("exited", ExitedEventBody{ExitCode: exitCode})
+	s.sendEvent("terminated", nil)
+}
+
+// BeforeInstruction implements vm.Hook. It runs on the VM goroutine and
+// blocks while the program is paused
+func (s *Server) BeforeInstruction(m *vm.VM, pc int, instr ir.Instruction) *yaperror.YapError {
+	s.mu.Lock()
+	if s.quit {
+		s.mu.Unlock()
+		m.Stop()
+		return nil
+	}
+	reason, ok := s.stepper.ShouldStop(instr)
+	if !ok || s.noDebug {
+		s.mu.Unlock()
+		return nil
+	}
+	s.paused = &pause{vm: m, instr: instr}
+	s.mu.Unlock()
+
+	s.sendEvent("stopped", StoppedEventBody{
+		Reason:            stopReason(reason),
+		ThreadID:          threadID,
+		AllThreadsStopped: true,
+	})
+	<-s.resume
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.quit {
+		m.Stop()
+	}
+	return nil
+}
+
+func stopReason(reason debugger.StopReason) string {
+	switch reason {
+	case debugger.StopEntry:
+		return ReasonEntry
+	case debugger.StopBreakpoint:
+		return ReasonBreakpoint
+	default:
+		return ReasonStep
+	}
+}
+
+// resumeWith updates the stepping mode of a paused program and resumes it
+// after the response has been sent
+func (s *Server) resumeWith(update func(*debugger.Stepper, ir.Instruction)) (interface{}, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused == nil {
+		return nil, nil, errors.New("program is not stopped")
+	}
+	update(s.stepper, s.paused.instr)
+	s.paused = nil
+	return nil, func() { s.resume <- struct{}{} }, nil
+}
+
+// stop ends the program and waits for it to finish
+func (s *Server) stop() {
+	s.mu.Lock()
+	s.quit = true
+	wasPaused := s.paused != nil
+	s.paused = nil
+	done := s.done
+	s.mu.Unlock()
+
+	if wasPaused {
+		s.resume <- struct{}{}
+	}
+	if done != nil {
+		<-done
+	}
+}
+
+func (s *Server) setBreakpoints(args SetBreakpointsArguments) (interface{}, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.launched {
+		return nil, nil, errors.New("program not launched")
+	}
+
+	body := SetBreakpointsResponseBody{Breakpoints: []Breakpoint{}}
+	sameFile := samePath(args.Source.Path, s.file.Path)
+	s.stepper.ClearBreakpoints()
+	for _, bp := range args.Breakpoints {
+		b := Breakpoint{Line: bp.Line}
+		switch {
+		case !sameFile:
+			b.Message = "source is not part of the program"
+		case !s.stepper.SetBreakpoint(bp.Line):
+			b.Message = fmt.Sprintf("no statement on line %d", bp.Line)
+		default:
+			b.Verified = true
+		}
+		body.Breakpoints = append(body.Breakpoints, b)
+	}
+	return body, nil, nil
+}
+
+func (s *Server) stackTrace() (interface{}, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused == nil {
+		return nil, nil, errors.New("program is not stopped")
+	}
+
+	start := s.paused.instr.Span.Start
+	frame := StackFrame{
+		ID:     frameID,
+		Name:   threadName,
+		Source: &Source{Name: filepath.Base(s.file.Path), Path: s.file.Path},
+		Line:   start.Line,
+		Column: start.Column,
+	}
+	return StackTraceResponseBody{StackFrames: []StackFrame{frame}, TotalFrames: 1}, nil, nil
+}
+
+func (s *Server) variables(args VariablesArguments) (interface{}, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused == nil {
+		return nil, nil, errors.New("program is not stopped")
+	}
+
+	body := VariablesResponseBody{Variables: []Variable{}}
+	if args.VariablesReference != globalsReference {
+		return body, nil, nil
+	}
+	m := s.paused.vm
+	for _, name := range m.Variables() {
+		val, _ := m.Lookup(name)
+		body.Variables = append(body.Variables, Variable{
+			Name:  name,
+			Value: debugger.FormatValue(val),
+			Type:  stdlib.TypeName(val),
+		})
+	}
+	return body, nil, nil
+}
+
+func (s *Server) send(msg interface{}) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.seq++
+	switch m := msg.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+	return s.conn.Write(msg)
+}
+
+// sendEvent sends an event. Errors are dropped: the client has gone away
+// and Serve will notice when it next reads
+func (s *Server) sendEvent(name string, body interface{}) {
+	_ = s.send(&event{Type: messageEvent, Event: name, Body: body})
+}
+
+// outputWriter turns program output into output events
+type outputWriter struct {
+	s        *Server
+	category string
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.s.sendEvent("output", OutputEventBody{Category: w.category, Output: string(p)})
+	return len(p), nil
+}
+
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
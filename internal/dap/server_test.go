@@ -0,0 +1,335 @@
+package dap_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rlamalama/YAP/internal/dap"
+	"github.com/rlamalama/YAP/internal/rpc"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFileDir = "../.."
+
+// The debug test file:
+//
+//	1 - set:
+//	2   - x: 10
+//	3   - name: "YAP"
+//	4 - if: x > 5
+//	5   then:
+//	6     - print: "big"
+//	7     - print: name
+//	8   else:
+//	9     - print: "small"
+//	10 - print: "done"
+var program = test_util.GetTestFilepath(test_util.DebugYAP, testFileDir)
+
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client is a scripted DAP client talking to a server over pipes
+type client struct {
+	t        *testing.T
+	conn     *rpc.Conn
+	seq      int
+	messages chan message
+	pending  []message // events read while waiting for a response
+	done     chan error
+}
+
+func startServer(t *testing.T) *client {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	c := &client{
+		t:        t,
+		conn:     rpc.NewConn(clientR, clientW),
+		messages: make(chan message, 100),
+		done:     make(chan error, 1),
+	}
+	go func() {
+		err := dap.NewServer(serverR, serverW).Serve()
+		serverW.Close()
+		c.done <- err
+	}()
+	go func() {
+		defer close(c.messages)
+		for {
+			body, err := c.conn.Read()
+			if err != nil {
+				return
+			}
+			var msg message
+			if err := json.Unmarshal(body, &msg); err == nil {
+				c.messages <- msg
+			}
+		}
+	}()
+	t.Cleanup(func() { clientW.Close() })
+	return c
+}
+
+func (c *client) next() message {
+	select {
+	case msg, ok := <-c.messages:
+		require.True(c.t, ok, "connection closed")
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for a message")
+		return message{}
+	}
+}
+
+// request sends a request and waits for its response
+func (c *client) request(command string, args interface{}) message {
+	c.t.Helper()
+	c.seq++
+	seq := c.seq
+	require.NoError(c.t, c.conn.Write(map[string]interface{}{
+		"seq": seq, "type": "request", "command": command, "arguments": args,
+	}))
+	for {
+		msg := c.next()
+		if msg.Type == "response" && msg.RequestSeq == seq {
+			require.Equal(c.t, command, msg.Command)
+			return msg
+		}
+		c.pending = append(c.pending, msg)
+	}
+}
+
+// succeed sends a request that must succeed and decodes its body into v
+func (c *client) succeed(command string, args interface{}, v interface{}) {
+	c.t.Helper()
+	msg := c.request(command, args)
+	require.True(c.t, msg.Success, "%s failed: %s", command, msg.Message)
+	if v != nil {
+		require.NoError(c.t, json.Unmarshal(msg.Body, v))
+	}
+}
+
+// event waits for the named event, collecting the output of any output
+// events on the way
+func (c *client) event(name string, output *string) message {
+	c.t.Helper()
+	for {
+		var msg message
+		if len(c.pending) > 0 {
+			msg, c.pending = c.pending[0], c.pending[1:]
+		} else {
+			msg = c.next()
+		}
+		if msg.Type != "event" {
+			continue
+		}
+		if msg.Event == "output" && output != nil {
+			var body dap.OutputEventBody
+			require.NoError(c.t, json.Unmarshal(msg.Body, &body))
+			*output += body.Output
+		}
+		if msg.Event == name {
+			return msg
+		}
+	}
+}
+
+func (c *client) stopped(reason string) {
+	c.t.Helper()
+	var body dap.StoppedEventBody
+	require.NoError(c.t, json.Unmarshal(c.event("stopped", nil).Body, &body))
+	assert.Equal(c.t, reason, body.Reason)
+	assert.Equal(c.t, 1, body.ThreadID)
+}
+
+func (c *client) line() int {
+	c.t.Helper()
+	var body dap.StackTraceResponseBody
+	c.succeed("stackTrace", map[string]interface{}{"threadId": 1}, &body)
+	require.Equal(c.t, 1, len(body.StackFrames))
+	return body.StackFrames[0].Line
+}
+
+func (c *client) variables() map[string]dap.Variable {
+	c.t.Helper()
+	var scopes dap.ScopesResponseBody
+	c.succeed("scopes", map[string]interface{}{"frameId": 1}, &scopes)
+	require.Equal(c.t, 1, len(scopes.Scopes))
+
+	var body dap.VariablesResponseBody
+	c.succeed("variables", dap.VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}, &body)
+	vars := map[string]dap.Variable{}
+	for _, v := range body.Variables {
+		vars[v.Name] = v
+	}
+	return vars
+}
+
+// launch starts a session of the debug test file up to configurationDone
+func (c *client) launch(stopOnEntry bool, breakpoints ...int) dap.SetBreakpointsResponseBody {
+	c.t.Helper()
+	return c.launchProgram(program, stopOnEntry, breakpoints...)
+}
+
+func (c *client) launchProgram(program string, stopOnEntry bool, breakpoints ...int) dap.SetBreakpointsResponseBody {
+	c.t.Helper()
+	var caps dap.Capabilities
+	c.succeed("initialize", map[string]interface{}{"adapterID": "yap"}, &caps)
+	assert.True(c.t, caps.SupportsConfigurationDoneRequest)
+
+	c.succeed("launch", dap.LaunchArguments{Program: program, StopOnEntry: stopOnEntry}, nil)
+	c.event("initialized", nil)
+
+	bps := []dap.SourceBreakpoint{}
+	for _, line := range breakpoints {
+		bps = append(bps, dap.SourceBreakpoint{Line: line})
+	}
+	var body dap.SetBreakpointsResponseBody
+	c.succeed("setBreakpoints", dap.SetBreakpointsArguments{
+		Source:      dap.Source{Path: program},
+		Breakpoints: bps,
+	}, &body)
+
+	c.succeed("configurationDone", nil, nil)
+	return body
+}
+
+func TestDAPStepping(t *testing.T) {
+	c := startServer(t)
+	c.launch(true)
+
+	c.stopped(dap.ReasonEntry)
+	assert.Equal(t, 2, c.line())
+
+	var threads dap.ThreadsResponseBody
+	c.succeed("threads", nil, &threads)
+	assert.Equal(t, []dap.Thread{{ID: 1, Name: "main"}}, threads.Threads)
+
+	c.succeed("next", map[string]interface{}{"threadId": 1}, nil)
+	c.stopped(dap.ReasonStep)
+	assert.Equal(t, 3, c.line())
+
+	c.succeed("next", map[string]interface{}{"threadId": 1}, nil)
+	c.stopped(dap.ReasonStep)
+	assert.Equal(t, 4, c.line())
+
+	vars := c.variables()
+	assert.Equal(t, dap.Variable{Name: "name", Value: `"YAP"`, Type: "string"}, vars["name"])
+	assert.Equal(t, dap.Variable{Name: "x", Value: "10", Type: "int"}, vars["x"])
+
+	// stepIn enters the then block
+	c.succeed("stepIn", map[string]interface{}{"threadId": 1}, nil)
+	c.stopped(dap.ReasonStep)
+	assert.Equal(t, 6, c.line())
+
+	// next steps out of the block once it is done
+	var output string
+	c.succeed("next", map[string]interface{}{"threadId": 1}, nil)
+	c.event("stopped", &output)
+	assert.Equal(t, 7, c.line())
+	c.succeed("next", map[string]interface{}{"threadId": 1}, nil)
+	c.event("stopped", &output)
+	assert.Equal(t, 10, c.line())
+	assert.Equal(t, "big\nYAP\n", output)
+
+	c.succeed("continue", map[string]interface{}{"threadId": 1}, nil)
+	var exited dap.ExitedEventBody
+	require.NoError(t, json.Unmarshal(c.event("exited", &output).Body, &exited))
+	assert.Equal(t, 0, exited.ExitCode)
+	assert.Equal(t, "big\nYAP\ndone\n", output)
+	c.event("terminated", nil)
+
+	c.succeed("disconnect", nil, nil)
+	require.NoError(t, <-c.done)
+}
+
+func TestDAPExitCode(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "exit.yap")
+	require.NoError(t, os.WriteFile(fp, []byte("- print: \"bye\"\n- exit: 3\n- print: \"unreachable\"\n"), 0o644))
+
+	c := startServer(t)
+	c.launchProgram(fp, false)
+
+	var output string
+	var exited dap.ExitedEventBody
+	require.NoError(t, json.Unmarshal(c.event("exited", &output).Body, &exited))
+	assert.Equal(t, 3, exited.ExitCode)
+	assert.Equal(t, "bye\n", output)
+	c.event("terminated", nil)
+
+	c.succeed("disconnect", nil, nil)
+	require.NoError(t, <-c.done)
+}
+
+func TestDAPBreakpoints(t *testing.T) {
+	c := startServer(t)
+	bps := c.launch(false, 5, 7, 10)
+
+	require.Equal(t, 3, len(bps.Breakpoints))
+	assert.False(t, bps.Breakpoints[0].Verified, "line 5 has no statement")
+	assert.Equal(t, "no statement on line 5", bps.Breakpoints[0].Message)
+	assert.True(t, bps.Breakpoints[1].Verified)
+	assert.True(t, bps.Breakpoints[2].Verified)
+
+	var output string
+	c.event("stopped", &output)
+	assert.Equal(t, 7, c.line())
+	assert.Equal(t, "big\n", output)
+
+	c.succeed("continue", map[string]interface{}{"threadId": 1}, nil)
+	c.stopped(dap.ReasonBreakpoint)
+	assert.Equal(t, 10, c.line())
+
+	c.succeed("continue", map[string]interface{}{"threadId": 1}, nil)
+	c.event("terminated", nil)
+
+	c.succeed("disconnect", nil, nil)
+	require.NoError(t, <-c.done)
+}
+
+func TestDAPRequestsWhileRunning(t *testing.T) {
+	c := startServer(t)
+
+	msg := c.request("setBreakpoints", dap.SetBreakpointsArguments{Source: dap.Source{Path: program}})
+	assert.False(t, msg.Success)
+	assert.Equal(t, "program not launched", msg.Message)
+
+	msg = c.request("launch", dap.LaunchArguments{Program: "missing.yap"})
+	assert.False(t, msg.Success)
+	assert.Contains(t, msg.Message, "error parsing program")
+
+	msg = c.request("stackTrace", map[string]interface{}{"threadId": 1})
+	assert.False(t, msg.Success)
+	assert.Equal(t, "program is not stopped", msg.Message)
+
+	msg = c.request("evaluate", nil)
+	assert.False(t, msg.Success)
+	assert.Equal(t, `unsupported command "evaluate"`, msg.Message)
+}
+
+func TestDAPDisconnectWhilePaused(t *testing.T) {
+	c := startServer(t)
+	c.launch(true)
+	c.stopped(dap.ReasonEntry)
+
+	var output string
+	c.succeed("disconnect", nil, nil)
+	require.NoError(t, <-c.done)
+	c.event("terminated", &output)
+	assert.Empty(t, output)
+}
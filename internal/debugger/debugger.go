@@ -0,0 +1,290 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/source"
+)
+
+const (
+	Prompt = "(yap) "
+
+	// contextLines is the number of lines shown around the current line by list
+	contextLines = 3
+)
+
+const helpText = `Commands:
+  break <line>, b      Set a breakpoint on a line
+  delete <line>        Remove a breakpoint
+  breakpoints          List breakpoints
+  step, s              Run to the next statement, entering if blocks
+  next, n              Run to the next statement at the same or an outer level
+  continue, c          Run to the next breakpoint
+  print <expr>, p      Evaluate an expression
+  vars                 Show all variables
+  set <name> <expr>    Assign the value of an expression to a variable
+  list, l              Show the source around the current statement
+  help, h              Show this help
+  quit, q              Stop the program
+`
+
+// Debugger drives a VM interactively. It is attached to the VM as a hook,
+// reads commands from in and writes to out
+type Debugger struct {
+	file *source.File
+	in   *bufio.Scanner
+	out  io.Writer
+
+	stepper *Stepper
+	current ir.Instruction
+}
+
+// New creates a debugger for instructions compiled from file. It stops
+// before the first statement
+func New(file *source.File, instructions []ir.Instruction, in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		file:    file,
+		in:      bufio.NewScanner(in),
+		out:     out,
+		stepper: NewStepper(instructions, true),
+	}
+}
+
+// Break sets a breakpoint on line
+func (d *Debugger) Break(line int) error {
+	if !d.stepper.SetBreakpoint(line) {
+		return fmt.Errorf("no statement on line %d", line)
+	}
+	return nil
+}
+
+// BeforeInstruction implements vm.Hook
+func (d *Debugger) BeforeInstruction(m *vm.VM, pc int, instr ir.Instruction) *yaperror.YapError {
+	reason, ok := d.stepper.ShouldStop(instr)
+	if !ok {
+		return nil
+	}
+
+	d.current = instr
+	line := instr.Span.Start.Line
+	if reason == StopBreakpoint {
+		fmt.Fprintf(d.out, "Breakpoint at %s:%d\n", d.file.Path, line)
+	}
+	d.showLine(line, "=>")
+
+	d.prompt(m)
+	return nil
+}
+
+// prompt reads and runs commands until one resumes the program
+func (d *Debugger) prompt(m *vm.VM) {
+	for {
+		fmt.Fprint(d.out, Prompt)
+		if !d.in.Scan() {
+			// No more input, stop the program like quit
+			fmt.Fprintln(d.out)
+			m.Stop()
+			return
+		}
+
+		text := strings.TrimSpace(d.in.Text())
+		if text == "" {
+			continue
+		}
+		cmd, rest, _ := strings.Cut(text, " ")
+		rest = strings.TrimSpace(rest)
+
+		switch cmd {
+		case "step", "s":
+			d.stepper.Step()
+			return
+		case "next", "n":
+			d.stepper.Next(d.current)
+			return
+		case "continue", "c":
+			d.stepper.Continue()
+			return
+		case "quit", "q":
+			m.Stop()
+			return
+		case "break", "b":
+			d.breakCmd(rest)
+		case "delete":
+			d.deleteCmd(rest)
+		case "breakpoints":
+			d.listBreakpoints()
+		case "print", "p":
+			d.printCmd(m, rest)
+		case "vars":
+			d.varsCmd(m)
+		case "set":
+			d.setCmd(m, rest)
+		case "list", "l":
+			d.list()
+		case "help", "h":
+			fmt.Fprint(d.out, helpText)
+		default:
+			fmt.Fprintf(d.out, "unknown command %q, type help for a list of commands\n", cmd)
+		}
+	}
+}
+
+func (d *Debugger) breakCmd(arg string) {
+	line, ok := d.lineArg(arg)
+	if !ok {
+		return
+	}
+	if err := d.Break(line); err != nil {
+		fmt.Fprintln(d.out, err)
+		return
+	}
+	fmt.Fprintf(d.out, "Breakpoint set at %s:%d\n", d.file.Path, line)
+}
+
+func (d *Debugger) deleteCmd(arg string) {
+	line, ok := d.lineArg(arg)
+	if !ok {
+		return
+	}
+	if !d.stepper.ClearBreakpoint(line) {
+		fmt.Fprintf(d.out, "no breakpoint on line %d\n", line)
+		return
+	}
+	fmt.Fprintf(d.out, "Breakpoint removed from %s:%d\n", d.file.Path, line)
+}
+
+func (d *Debugger) listBreakpoints() {
+	lines := d.stepper.Breakpoints()
+	if len(lines) == 0 {
+		fmt.Fprintln(d.out, "No breakpoints")
+		return
+	}
+	for _, line := range lines {
+		fmt.Fprintf(d.out, "%s:%d\n", d.file.Path, line)
+	}
+}
+
+func (d *Debugger) lineArg(arg string) (int, bool) {
+	if arg == "" {
+		fmt.Fprintln(d.out, "expected a line number")
+		return 0, false
+	}
+	line, err := strconv.Atoi(arg)
+	if err != nil {
+		fmt.Fprintf(d.out, "invalid line number %q\n", arg)
+		return 0, false
+	}
+	return line, true
+}
+
+func (d *Debugger) printCmd(m *vm.VM, src string) {
+	if src == "" {
+		fmt.Fprintln(d.out, "expected an expression")
+		return
+	}
+	val, err := Evaluate(m, src)
+	if err != nil {
+		d.report(err)
+		return
+	}
+	fmt.Fprintln(d.out, FormatValue(val))
+}
+
+func (d *Debugger) varsCmd(m *vm.VM) {
+	names := m.Variables()
+	if len(names) == 0 {
+		fmt.Fprintln(d.out, "No variables")
+		return
+	}
+	for _, name := range names {
+		val, _ := m.Lookup(name)
+		fmt.Fprintf(d.out, "%s = %s\n", name, FormatValue(val))
+	}
+}
+
+func (d *Debugger) setCmd(m *vm.VM, rest string) {
+	name, src, _ := strings.Cut(rest, " ")
+	src = strings.TrimSpace(src)
+	if name == "" || src == "" {
+		fmt.Fprintln(d.out, "usage: set <name> <expr>")
+		return
+	}
+	val, err := Evaluate(m, src)
+	if err != nil {
+		d.report(err)
+		return
+	}
+	if err := m.Assign(name, val); err != nil {
+		d.report(err)
+		return
+	}
+	fmt.Fprintf(d.out, "%s = %s\n", name, FormatValue(val))
+}
+
+// report writes an error from evaluating a command. Runtime errors have no
+// position in the program, so only their message is shown
+func (d *Debugger) report(err error) {
+	if yerr, ok := err.(*yaperror.YapError); ok {
+		fmt.Fprintf(d.out, "error: %s\n", yerr.Message)
+		return
+	}
+	fmt.Fprintln(d.out, err)
+}
+
+// list shows the lines around the current statement
+func (d *Debugger) list() {
+	line := d.current.Span.Start.Line
+	for n := line - contextLines; n <= line+contextLines; n++ {
+		marker := "  "
+		if n == line {
+			marker = "=>"
+		}
+		d.showLine(n, marker)
+	}
+}
+
+// showLine writes a source line, marking breakpoints with a *
+func (d *Debugger) showLine(n int, marker string) {
+	text, ok := d.file.Line(n)
+	if !ok {
+		return
+	}
+	bp := " "
+	if d.stepper.IsBreakpoint(n) {
+		bp = "*"
+	}
+	fmt.Fprintf(d.out, "%s%s %4d | %s\n", bp, marker, n, text)
+}
+
+// Evaluate parses src as a YAP expression and evaluates it in the VM
+func Evaluate(m *vm.VM, src string) (interface{}, error) {
+	prog, err := parser.NewParserFromBytes([]byte("- print: "+src+"\n"), "<debug>").Parse()
+	if err != nil {
+		return nil, err
+	}
+	stmt, ok := prog.Statements[0].(parser.PrintStmt)
+	if !ok || len(prog.Statements) != 1 {
+		return nil, fmt.Errorf("invalid expression %q", src)
+	}
+	val, yerr := m.Evaluate(stmt.Expr)
+	if yerr != nil {
+		return nil, yerr
+	}
+	return val, nil
+}
+
+// FormatValue formats a value for display, quoting strings
+func FormatValue(val interface{}) string {
+	if s, ok := val.(string); ok {
+		return strconv.Quote(s)
+	}
+	return vm.FormatValue(val)
+}
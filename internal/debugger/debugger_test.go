@@ -0,0 +1,125 @@
+package debugger_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/debugger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// debug runs src under the debugger with the commands in input and returns
+// everything written to out
+func debug(t *testing.T, input string, breakpoints ...int) string {
+	t.Helper()
+	file, program := compile(t, src)
+
+	var out bytes.Buffer
+	d := debugger.New(file, program, strings.NewReader(input), &out)
+	for _, line := range breakpoints {
+		require.NoError(t, d.Break(line))
+	}
+	err := vm.New(program, vm.WithHook(d), vm.WithArgs([]string{"a", "b"}), vm.WithStdout(&out)).Run()
+	require.Nil(t, err)
+	return out.String()
+}
+
+func TestDebugger(t *testing.T) {
+	tests := []struct {
+		name        string
+		breakpoints []int
+		input       string
+		expected    string
+	}{
+		{
+			name:  "breakpoint hits",
+			input: "break 11\nb 4\nbreakpoints\ncontinue\np i\nc\nc\n",
+			expected: " =>    2 |   - x: 10\n" +
+				"(yap) Breakpoint set at debug.yap:11\n" +
+				"(yap) no statement on line 4\n" +
+				"(yap) debug.yap:11\n" +
+				"(yap) big\n" +
+				"Breakpoint at debug.yap:11\n" +
+				"*=>   11 |     - print: i\n" +
+				"(yap) \"a\"\n" +
+				"(yap) a\n" +
+				"Breakpoint at debug.yap:11\n" +
+				"*=>   11 |     - print: i\n" +
+				"(yap) b\n" +
+				"11\n",
+		},
+		{
+			name:        "delete a breakpoint that was hit",
+			breakpoints: []int{5},
+			input:       "continue\ndelete 5\ncontinue\n",
+			expected: " =>    2 |   - x: 10\n" +
+				"(yap) Breakpoint at debug.yap:5\n" +
+				"*=>    5 |     - print: \"big\"\n" +
+				"(yap) Breakpoint removed from debug.yap:5\n" +
+				"(yap) big\n" +
+				"a\n" +
+				"b\n" +
+				"11\n",
+		},
+		{
+			name:  "next steps over blocks",
+			input: "next\nnext\nprint x\nnext\nvars\nnext\n",
+			expected: " =>    2 |   - x: 10\n" +
+				"(yap)  =>    3 | - if: x > 5\n" +
+				"(yap) big\n" +
+				" =>    8 | - for: i\n" +
+				"(yap) 11\n" +
+				"(yap) a\n" +
+				"b\n" +
+				" =>   12 | - print: x\n" +
+				"(yap) i = \"b\"\n" +
+				"x = 11\n" +
+				"(yap) 11\n",
+		},
+		{
+			name:  "step into a block and off the end",
+			input: "step\nstep\nstep\nset x 1\nnext\nnext\nstep\n",
+			expected: " =>    2 |   - x: 10\n" +
+				"(yap)  =>    3 | - if: x > 5\n" +
+				"(yap)  =>    5 |     - print: \"big\"\n" +
+				"(yap) big\n" +
+				" =>    7 |       - x: x + 1\n" +
+				"(yap) x = 1\n" +
+				"(yap)  =>    8 | - for: i\n" +
+				"(yap) a\n" +
+				"b\n" +
+				" =>   12 | - print: x\n" +
+				"(yap) 2\n",
+		},
+		{
+			name:  "commands that do not resume",
+			input: "delete 5\nbreakpoints\np y\np\nfoo\nlist\nq\n",
+			expected: " =>    2 |   - x: 10\n" +
+				"(yap) no breakpoint on line 5\n" +
+				"(yap) No breakpoints\n" +
+				"(yap) error: undefined variable: y\n" +
+				"(yap) expected an expression\n" +
+				"(yap) unknown command \"foo\", type help for a list of commands\n" +
+				"(yap)        1 | - set:\n" +
+				" =>    2 |   - x: 10\n" +
+				"       3 | - if: x > 5\n" +
+				"       4 |   then:\n" +
+				"       5 |     - print: \"big\"\n" +
+				"(yap) ",
+		},
+		{
+			name:     "end of input stops the program",
+			input:    "",
+			expected: " =>    2 |   - x: 10\n(yap) \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, debug(t, tt.input, tt.breakpoints...))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package debugger
+
+import (
+	"sort"
+
+	"github.com/rlamalama/YAP/internal/backend/ir"
+)
+
+// StopReason says why a Stepper stopped at a statement
+type StopReason int
+
+const (
+	StopEntry      StopReason = iota // the first stop of a run
+	StopStep                         // after step or next
+	StopBreakpoint                   // at a breakpoint, after continue
+)
+
+type mode int
+
+const (
+	modeStep     mode = iota // stop at the next statement
+	modeNext                 // stop at the next statement no deeper than depth
+	modeContinue             // stop at breakpoints only
+)
+
+// Stepper decides which instructions a debugger stops at. It maps source
+// lines to the instructions of their statements and tracks breakpoints and
+// the current stepping mode
+type Stepper struct {
+	lines       map[int]bool // lines that start a statement
+	breakpoints map[int]bool
+	mode        mode
+	depth       int  // depth of the statement where next was issued
+	started     bool // whether the run has stopped before
+}
+
+// NewStepper creates a stepper for instructions. With stopOnEntry it stops
+// at the first statement, otherwise only at breakpoints
+func NewStepper(instructions []ir.Instruction, stopOnEntry bool) *Stepper {
+	s := &Stepper{
+		lines:       map[int]bool{},
+		breakpoints: map[int]bool{},
+		mode:        modeContinue,
+	}
+	if stopOnEntry {
+		s.mode = modeStep
+	}
+	for _, instr := range instructions {
+		if IsStatement(instr) {
+			s.lines[instr.Span.Start.Line] = true
+		}
+	}
+	return s
+}
+
+// HasStatement reports whether a statement starts on line
+func (s *Stepper) HasStatement(line int) bool {
+	return s.lines[line]
+}
+
+// SetBreakpoint sets a breakpoint on line, reporting false when no statement
+// starts there
+func (s *Stepper) SetBreakpoint(line int) bool {
+	if !s.lines[line] {
+		return false
+	}
+	s.breakpoints[line] = true
+	return true
+}
+
+// ClearBreakpoint removes the breakpoint on line, reporting whether it existed
+func (s *Stepper) ClearBreakpoint(line int) bool {
+	ok := s.breakpoints[line]
+	delete(s.breakpoints, line)
+	return ok
+}
+
+// ClearBreakpoints removes all breakpoints
+func (s *Stepper) ClearBreakpoints() {
+	s.breakpoints = map[int]bool{}
+}
+
+// IsBreakpoint reports whether line has a breakpoint
+func (s *Stepper) IsBreakpoint(line int) bool {
+	return s.breakpoints[line]
+}
+
+// Breakpoints returns the lines with a breakpoint in order
+func (s *Stepper) Breakpoints() []int {
+	lines := make([]int, 0, len(s.breakpoints))
+	for line := range s.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Step resumes until the next statement, entering if blocks
+func (s *Stepper) Step() {
+	s.mode = modeStep
+}
+
+// Next resumes until the next statement that is not nested deeper than
+// current, stepping over if blocks
+func (s *Stepper) Next(current ir.Instruction) {
+	s.mode = modeNext
+	s.depth = current.Depth
+}
+
+// Continue resumes until the next breakpoint
+func (s *Stepper) Continue() {
+	s.mode = modeContinue
+}
+
+// ShouldStop reports whether to stop before instr and why
+func (s *Stepper) ShouldStop(instr ir.Instruction) (StopReason, bool) {
+	if !IsStatement(instr) {
+		return 0, false
+	}
+
+	reason := StopStep
+	stop := false
+	switch s.mode {
+	case modeStep:
+		stop = true
+	case modeNext:
+		stop = instr.Depth <= s.depth
+	}
+	if !stop && s.breakpoints[instr.Span.Start.Line] {
+		reason, stop = StopBreakpoint, true
+	}
+	if !stop {
+		return 0, false
+	}
+
+	if !s.started {
+		s.started = true
+		if reason == StopStep {
+			reason = StopEntry
+		}
+	}
+	return reason, true
+}
+
+// IsStatement reports whether instr starts a statement a debugger can stop
+// at. Jumps added by the builder have no span
+func IsStatement(instr ir.Instruction) bool {
+	return instr.Span.Start.Line > 0
+}
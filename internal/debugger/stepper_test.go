@@ -0,0 +1,181 @@
+package debugger_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/debugger"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const src = `- set:
+  - x: 10
+- if: x > 5
+  then:
+    - print: "big"
+    - set:
+      - x: x + 1
+- for: i
+  in: args
+  do:
+    - print: i
+- print: x
+`
+
+func compile(t *testing.T, src string) (*source.File, []ir.Instruction) {
+	t.Helper()
+	prog, err := parser.NewParserFromBytes([]byte(src), "debug.yap").Parse()
+	require.NoError(t, err)
+	program, err := build.New().Build(prog.Statements)
+	require.NoError(t, err)
+	return prog.File, program
+}
+
+// scriptedHook stops where its stepper says, records the stop and resumes
+// with the next action of its script. Once the script runs out it continues
+type scriptedHook struct {
+	stepper *debugger.Stepper
+	actions []string
+	stops   []string
+}
+
+func (h *scriptedHook) BeforeInstruction(m *vm.VM, pc int, instr ir.Instruction) *yaperror.YapError {
+	reason, ok := h.stepper.ShouldStop(instr)
+	if !ok {
+		return nil
+	}
+	h.stops = append(h.stops, fmt.Sprintf("%s %d", reasonName(reason), instr.Span.Start.Line))
+
+	action := "continue"
+	if len(h.actions) > 0 {
+		action, h.actions = h.actions[0], h.actions[1:]
+	}
+	switch action {
+	case "step":
+		h.stepper.Step()
+	case "next":
+		h.stepper.Next(instr)
+	default:
+		h.stepper.Continue()
+	}
+	return nil
+}
+
+func reasonName(reason debugger.StopReason) string {
+	switch reason {
+	case debugger.StopEntry:
+		return "entry"
+	case debugger.StopBreakpoint:
+		return "breakpoint"
+	default:
+		return "step"
+	}
+}
+
+func TestStepper(t *testing.T) {
+	tests := []struct {
+		name        string
+		stopOnEntry bool
+		breakpoints []int
+		actions     []string
+		stops       []string
+	}{
+		{
+			name:        "no breakpoints runs to the end",
+			stopOnEntry: false,
+			stops:       nil,
+		},
+		{
+			name:        "continue from entry",
+			stopOnEntry: true,
+			actions:     []string{"continue"},
+			stops:       []string{"entry 2"},
+		},
+		{
+			name:        "step enters blocks",
+			stopOnEntry: true,
+			actions:     []string{"step", "step", "step", "step", "step", "step", "step"},
+			stops: []string{
+				"entry 2", "step 3", "step 5", "step 7",
+				"step 8", "step 11", "step 11", "step 12",
+			},
+		},
+		{
+			name:        "next steps over blocks",
+			stopOnEntry: true,
+			actions:     []string{"next", "next", "next", "next"},
+			stops:       []string{"entry 2", "step 3", "step 8", "step 12"},
+		},
+		{
+			name:        "next inside a block stays at its level",
+			stopOnEntry: true,
+			actions:     []string{"step", "step", "next", "next"},
+			stops:       []string{"entry 2", "step 3", "step 5", "step 7", "step 8"},
+		},
+		{
+			name:        "next stops at a breakpoint inside a skipped block",
+			stopOnEntry: true,
+			breakpoints: []int{7},
+			actions:     []string{"next", "next", "next"},
+			stops:       []string{"entry 2", "step 3", "breakpoint 7", "step 8"},
+		},
+		{
+			name:        "continue hits every breakpoint",
+			stopOnEntry: false,
+			breakpoints: []int{5, 11},
+			stops:       []string{"breakpoint 5", "breakpoint 11", "breakpoint 11"},
+		},
+		{
+			name:        "step off the end of the program",
+			stopOnEntry: false,
+			breakpoints: []int{12},
+			actions:     []string{"step", "step"},
+			stops:       []string{"breakpoint 12"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, program := compile(t, src)
+			stepper := debugger.NewStepper(program, tt.stopOnEntry)
+			for _, line := range tt.breakpoints {
+				require.True(t, stepper.SetBreakpoint(line))
+			}
+
+			hook := &scriptedHook{stepper: stepper, actions: tt.actions}
+			err := vm.New(program, vm.WithHook(hook), vm.WithArgs([]string{"a", "b"}), vm.WithStdout(io.Discard)).Run()
+			require.Nil(t, err)
+			assert.Equal(t, tt.stops, hook.stops)
+		})
+	}
+}
+
+func TestStepperBreakpoints(t *testing.T) {
+	_, program := compile(t, src)
+	stepper := debugger.NewStepper(program, false)
+
+	assert.True(t, stepper.HasStatement(5))
+	assert.False(t, stepper.HasStatement(4), "then has no statement of its own")
+	assert.False(t, stepper.SetBreakpoint(4))
+	assert.False(t, stepper.SetBreakpoint(100))
+
+	require.True(t, stepper.SetBreakpoint(11))
+	require.True(t, stepper.SetBreakpoint(2))
+	assert.Equal(t, []int{2, 11}, stepper.Breakpoints())
+	assert.True(t, stepper.IsBreakpoint(11))
+
+	assert.True(t, stepper.ClearBreakpoint(11))
+	assert.False(t, stepper.ClearBreakpoint(11))
+	assert.Equal(t, []int{2}, stepper.Breakpoints())
+
+	stepper.ClearBreakpoints()
+	assert.Empty(t, stepper.Breakpoints())
+}
@@ -0,0 +1,52 @@
+package diagnostics
+
+import (
+	"fmt"
+	"os"
+)
+
+// ColorMode selects when diagnostics are coloured
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto" // Colour if the output is a terminal
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+// ParseColorMode validates a colour mode given on the command line
+func ParseColorMode(s string) (ColorMode, error) {
+	switch m := ColorMode(s); m {
+	case ColorAuto, ColorAlways, ColorNever:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown color mode %q, expected auto, always or never", s)
+	}
+}
+
+// Enabled reports whether output written to f should be coloured. In auto
+// mode that is the case for terminals, unless NO_COLOR is set or TERM is dumb
+func (m ColorMode) Enabled(f *os.File) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ANSI escape sequences of the renderer
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiGreen  = "\x1b[1;32m"
+	ansiBlue   = "\x1b[1;34m"
+	ansiCyan   = "\x1b[1;36m"
+)
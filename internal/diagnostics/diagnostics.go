@@ -0,0 +1,72 @@
+// Package diagnostics writes YAP errors for people and tools: as text, as
+// JSON with a stable schema, or as a SARIF log for code scanning in CI
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// Format is the output format of diagnostics
+type Format string
+
+const (
+	FormatText  Format = "text"
+	FormatJSON  Format = "json"
+	FormatSARIF Format = "sarif"
+)
+
+// ParseFormat validates a format name given on the command line
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON, FormatSARIF:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown diagnostics format %q, expected text, json or sarif", s)
+	}
+}
+
+// FromError returns the YAP errors in err, which may be a single error or an
+// error list, possibly wrapped. Other errors become a diagnostic without a
+// code or position
+func FromError(err error) []*yaperror.YapError {
+	var list *yaperror.ErrorList
+	if errors.As(err, &list) {
+		return list.Errors()
+	}
+	var yerr *yaperror.YapError
+	if errors.As(err, &yerr) {
+		return []*yaperror.YapError{yerr}
+	}
+	return []*yaperror.YapError{{Severity: yaperror.SeverityError, Message: err.Error()}}
+}
+
+// Options selects how diagnostics are written
+type Options struct {
+	Format Format
+	Color  bool // Colour text diagnostics
+}
+
+// Write writes diags to w in the format of opts
+func Write(w io.Writer, opts Options, diags []*yaperror.YapError) error {
+	switch opts.Format {
+	case FormatJSON:
+		return WriteJSON(w, diags)
+	case FormatSARIF:
+		return WriteSARIF(w, diags)
+	default:
+		return (&Renderer{Color: opts.Color}).Render(w, diags)
+	}
+}
+
+// summarize counts diags like ErrorList.Summary
+func summarize(diags []*yaperror.YapError) *yaperror.ErrorList {
+	list := yaperror.NewErrorList()
+	for _, d := range diags {
+		list.Add(d)
+	}
+	return list
+}
@@ -0,0 +1,83 @@
+package diagnostics_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/diagnostics"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"text", "json", "sarif"} {
+		format, err := diagnostics.ParseFormat(name)
+		require.NoError(t, err)
+		assert.Equal(t, diagnostics.Format(name), format)
+	}
+	_, err := diagnostics.ParseFormat("xml")
+	assert.EqualError(t, err, `unknown diagnostics format "xml", expected text, json or sarif`)
+}
+
+func TestFromError(t *testing.T) {
+	yerr := &yaperror.YapError{Code: yaperror.ErrDivisionByZero, Severity: yaperror.SeverityError, Phase: yaperror.PhaseRuntime, Message: "division by zero"}
+	assert.Equal(t, []*yaperror.YapError{yerr}, diagnostics.FromError(fmt.Errorf("error running program: %w", yerr)))
+
+	list := yaperror.NewErrorList()
+	list.Add(yerr)
+	list.Add(yerr)
+	assert.Len(t, diagnostics.FromError(list), 2)
+
+	other := diagnostics.FromError(errors.New("error finding file"))
+	require.Len(t, other, 1)
+	assert.Equal(t, yaperror.SeverityError, other[0].Severity)
+	assert.Equal(t, "error finding file", other[0].Message)
+}
+
+func TestWriteJSON(t *testing.T) {
+	list := yaperror.NewErrorList()
+	list.AddError(yaperror.ErrDivisionByZero, yaperror.PhaseRuntime, yaperror.Position{File: "<inline>", Line: 2, Column: 1}, "division by zero")
+	list.AddWarning(yaperror.ErrUndefinedVariable, yaperror.PhaseParser, yaperror.Position{}, "unused")
+	list.Errors()[0].AddNote("the divisor is 0")
+
+	var out bytes.Buffer
+	require.NoError(t, diagnostics.WriteJSON(&out, list.Errors()))
+
+	code := yaperror.ErrDivisionByZero.String()
+	assert.JSONEq(t, `{
+		"version": 1,
+		"diagnostics": [
+			{
+				"code": "`+code+`",
+				"severity": "error",
+				"phase": "runtime",
+				"message": "division by zero",
+				"position": {"file": "<inline>", "line": 2, "column": 1},
+				"notes": ["the divisor is 0"],
+				"hints": []
+			},
+			{
+				"code": "`+yaperror.ErrUndefinedVariable.String()+`",
+				"severity": "warning",
+				"phase": "parser",
+				"message": "unused",
+				"notes": [],
+				"hints": []
+			}
+		],
+		"summary": {"errors": 1, "warnings": 1, "text": "1 error(s), 1 warning(s)"}
+	}`, out.String())
+}
+
+func TestWriteJSONEmpty(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, diagnostics.WriteJSON(&out, nil))
+	assert.JSONEq(t, `{"version": 1, "diagnostics": [], "summary": {"errors": 0, "warnings": 0, "text": "no errors"}}`, out.String())
+}
+
+func TestErrorCodeString(t *testing.T) {
+	assert.Equal(t, "E0042", yaperror.ErrorCode(42).String())
+}
@@ -0,0 +1,103 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"io"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// SchemaVersion is the version of the JSON report. It changes only when
+// fields are removed or change meaning
+const SchemaVersion = 1
+
+// Report is the JSON document written by WriteJSON
+type Report struct {
+	Version     int          `json:"version"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Summary     Summary      `json:"summary"`
+}
+
+// Diagnostic is the JSON form of a YapError. Code and phase are omitted for
+// errors that do not come from YAP itself, e.g. a missing file
+type Diagnostic struct {
+	Code     string    `json:"code,omitempty"`
+	Severity string    `json:"severity"`
+	Phase    string    `json:"phase,omitempty"`
+	Message  string    `json:"message"`
+	Position *Position `json:"position,omitempty"`
+	Span     *Span     `json:"span,omitempty"`
+	Notes    []string  `json:"notes"`
+	Hints    []string  `json:"hints"`
+}
+
+// Position is a 1-based line and column in a file
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Span is a range in a file, End is exclusive
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Summary counts the diagnostics of a report
+type Summary struct {
+	Errors   int    `json:"errors"`
+	Warnings int    `json:"warnings"`
+	Text     string `json:"text"` // e.g. "2 error(s), 1 warning(s)"
+}
+
+// NewReport converts diags to their JSON form
+func NewReport(diags []*yaperror.YapError) Report {
+	report := Report{Version: SchemaVersion, Diagnostics: []Diagnostic{}}
+	for _, d := range diags {
+		report.Diagnostics = append(report.Diagnostics, toDiagnostic(d))
+		switch {
+		case d.IsError():
+			report.Summary.Errors++
+		case d.IsWarning():
+			report.Summary.Warnings++
+		}
+	}
+	report.Summary.Text = summarize(diags).Summary()
+	return report
+}
+
+// WriteJSON writes diags as an indented JSON report
+func WriteJSON(w io.Writer, diags []*yaperror.YapError) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(NewReport(diags))
+}
+
+func toDiagnostic(e *yaperror.YapError) Diagnostic {
+	d := Diagnostic{
+		Severity: e.Severity.String(),
+		Message:  e.Message,
+		Notes:    []string{},
+		Hints:    []string{},
+	}
+	if e.Code != 0 {
+		d.Code = e.Code.String()
+		d.Phase = e.Phase.String()
+	}
+	if e.Position.Line > 0 {
+		pos := toPosition(e.Position)
+		d.Position = &pos
+	}
+	if e.Span != nil {
+		d.Span = &Span{Start: toPosition(e.Span.Start), End: toPosition(e.Span.End)}
+	}
+	d.Notes = append(d.Notes, e.Notes...)
+	d.Hints = append(d.Hints, e.Hints...)
+	return d
+}
+
+func toPosition(p yaperror.Position) Position {
+	return Position{File: p.File, Line: p.Line, Column: p.Column}
+}
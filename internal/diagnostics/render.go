@@ -0,0 +1,216 @@
+package diagnostics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// maxSpanLines is the number of lines of a span shown in full. Longer spans
+// show their first and last lines around an ellipsis
+const maxSpanLines = 6
+
+// Renderer writes diagnostics for people, rustc style: a header with the
+// severity and code, the location, the source lines with the span
+// underlined, then the notes and hints
+//
+//	error[E4006]: division by zero
+//	 --> main.yap:3:1
+//	  |
+//	3 | - print: x / 0
+//	  | ^^^^^^^^^^^^^^
+type Renderer struct {
+	Color bool
+
+	// ReadFile loads the source of the files diagnostics point into,
+	// os.ReadFile if nil. If it fails, the Context of the error is shown
+	ReadFile func(name string) ([]byte, error)
+
+	sources map[string][]string
+}
+
+// Render writes diags to w, separated by blank lines
+func (r *Renderer) Render(w io.Writer, diags []*yaperror.YapError) error {
+	bw := bufio.NewWriter(w)
+	for i, d := range diags {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		r.render(bw, d)
+	}
+	return bw.Flush()
+}
+
+func (r *Renderer) render(w *bufio.Writer, e *yaperror.YapError) {
+	color := severityColor(e.Severity)
+
+	// error[E4006]: division by zero
+	header := e.Severity.String()
+	if e.Code != 0 {
+		header += "[" + e.Code.String() + "]"
+	}
+	w.WriteString(r.paint(color, header))
+	w.WriteString(r.paint(ansiBold, ": "+e.Message))
+	w.WriteString("\n")
+
+	lines := r.spanLines(e)
+	gutter := 1
+	if len(lines) > 0 {
+		gutter = len(strconv.Itoa(lines[len(lines)-1].number))
+	}
+	pad := strings.Repeat(" ", gutter)
+	bar := r.paint(ansiBlue, "|")
+
+	if e.Position.Line > 0 {
+		fmt.Fprintf(w, "%s%s %s\n", pad, r.paint(ansiBlue, "-->"), e.Position)
+	} else if e.Position.File != "" {
+		fmt.Fprintf(w, "%s%s %s\n", pad, r.paint(ansiBlue, "-->"), e.Position.File)
+	}
+
+	if len(lines) > 0 {
+		fmt.Fprintf(w, "%s %s\n", pad, bar)
+		for _, l := range lines {
+			if l.number == 0 {
+				fmt.Fprintf(w, "%s\n", r.paint(ansiBlue, "..."))
+				continue
+			}
+			number := r.paint(ansiBlue, fmt.Sprintf("%*d", gutter, l.number))
+			fmt.Fprintf(w, "%s %s %s\n", number, bar, l.text)
+			if l.to > l.from {
+				marker := strings.Repeat(" ", l.from-1) + r.paint(color, strings.Repeat("^", l.to-l.from))
+				fmt.Fprintf(w, "%s %s %s\n", pad, bar, marker)
+			}
+		}
+	}
+
+	for _, note := range e.Notes {
+		fmt.Fprintf(w, "%s %s %s %s\n", pad, r.paint(ansiBlue, "="), r.paint(ansiGreen, "note:"), note)
+	}
+	for _, hint := range e.Hints {
+		fmt.Fprintf(w, "%s %s %s %s\n", pad, r.paint(ansiBlue, "="), r.paint(ansiCyan, "hint:"), hint)
+	}
+}
+
+// sourceLine is a line of a snippet, underlined from column from up to but
+// excluding column to. A zero number stands for lines left out
+type sourceLine struct {
+	number   int
+	text     string
+	from, to int
+}
+
+// spanLines returns the lines of e's span with their underlines, or the line
+// of its position with a caret at the column if e has no span
+func (r *Renderer) spanLines(e *yaperror.YapError) []sourceLine {
+	if e.Position.Line <= 0 {
+		return nil
+	}
+	start, end := e.Position, e.Position
+	end.Column++
+	if e.Span != nil && e.Span.Start.Line > 0 && !spanBefore(e.Span.End, e.Span.Start) {
+		start, end = e.Span.Start, e.Span.End
+		if end.Line > start.Line && end.Column <= 1 {
+			// The span ends at the start of a line, so does its underline
+			end.Line--
+			end.Column = -1
+		}
+	}
+
+	src := r.source(e.Position.File)
+	lineText := func(n int) (string, bool) {
+		if n >= 1 && n <= len(src) {
+			return src[n-1], true
+		}
+		if n == e.Position.Line && e.Context != "" {
+			return e.Context, true
+		}
+		return "", false
+	}
+
+	lines := []sourceLine{}
+	for n := start.Line; n <= end.Line; n++ {
+		if end.Line-start.Line+1 > maxSpanLines && n == start.Line+maxSpanLines/2 {
+			// Skip to the last lines of the span
+			lines = append(lines, sourceLine{})
+			n = end.Line - maxSpanLines/2 + 1
+		}
+		text, ok := lineText(n)
+		if !ok {
+			continue
+		}
+		l := sourceLine{number: n, text: text, from: firstColumn(text), to: len(text) + 1}
+		if n == start.Line {
+			l.from = start.Column
+		}
+		if n == end.Line && end.Column > 0 {
+			l.to = end.Column
+		}
+		l.from = max(l.from, 1)
+		l.to = min(l.to, len(text)+1)
+		if l.to <= l.from && n == start.Line && n == end.Line {
+			l.to = l.from + 1
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// source returns the lines of the file name, nil if it cannot be read
+func (r *Renderer) source(name string) []string {
+	if name == "" {
+		return nil
+	}
+	if lines, ok := r.sources[name]; ok {
+		return lines
+	}
+	if r.sources == nil {
+		r.sources = map[string][]string{}
+	}
+	readFile := r.ReadFile
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+
+	var lines []string
+	if data, err := readFile(name); err == nil {
+		text := strings.ReplaceAll(string(data), "\r\n", "\n")
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+	r.sources[name] = lines
+	return lines
+}
+
+// paint wraps s in the escape sequence code if colours are enabled
+func (r *Renderer) paint(code, s string) string {
+	if !r.Color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func severityColor(s yaperror.Severity) string {
+	switch s {
+	case yaperror.SeverityError:
+		return ansiRed
+	case yaperror.SeverityWarning:
+		return ansiYellow
+	case yaperror.SeverityNote:
+		return ansiGreen
+	default:
+		return ansiCyan
+	}
+}
+
+// firstColumn returns the column of the first non-blank character of line
+func firstColumn(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " ")) + 1
+}
+
+func spanBefore(a, b yaperror.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
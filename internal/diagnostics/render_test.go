@@ -0,0 +1,144 @@
+package diagnostics_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/diagnostics"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const renderSource = `- set:
+  - x: 10
+- if: x > 5
+  then:
+    - print: x / 0
+- print: "done"
+`
+
+func render(t *testing.T, r *diagnostics.Renderer, diags ...*yaperror.YapError) string {
+	t.Helper()
+	if r.ReadFile == nil {
+		r.ReadFile = func(name string) ([]byte, error) {
+			if name != "main.yap" {
+				return nil, errors.New("not found")
+			}
+			return []byte(renderSource), nil
+		}
+	}
+	var out bytes.Buffer
+	require.NoError(t, r.Render(&out, diags))
+	return out.String()
+}
+
+func pos(line, col int) yaperror.Position {
+	return yaperror.Position{File: "main.yap", Line: line, Column: col}
+}
+
+func TestRenderCaret(t *testing.T) {
+	e := &yaperror.YapError{Code: yaperror.ErrUndefinedVariable, Message: `undefined variable "y"`, Position: pos(3, 7)}
+	e.AddNote("variables are defined with set").AddHint("did you mean x?")
+
+	assert.Equal(t, "error["+yaperror.ErrUndefinedVariable.String()+`]: undefined variable "y"
+ --> main.yap:3:7
+  |
+3 | - if: x > 5
+  |       ^
+  = note: variables are defined with set
+  = hint: did you mean x?
+`, render(t, &diagnostics.Renderer{}, e))
+}
+
+func TestRenderSpan(t *testing.T) {
+	e := &yaperror.YapError{Code: yaperror.ErrDivisionByZero, Message: "division by zero", Position: pos(5, 14)}
+	e.WithSpan(pos(5, 14), pos(5, 19))
+
+	assert.Equal(t, "error["+yaperror.ErrDivisionByZero.String()+`]: division by zero
+ --> main.yap:5:14
+  |
+5 |     - print: x / 0
+  |              ^^^^^
+`, render(t, &diagnostics.Renderer{}, e))
+}
+
+func TestRenderMultiLineSpan(t *testing.T) {
+	e := &yaperror.YapError{Severity: yaperror.SeverityWarning, Message: "condition is always true", Position: pos(3, 1)}
+	e.WithSpan(pos(3, 1), pos(6, 1))
+
+	assert.Equal(t, `warning: condition is always true
+ --> main.yap:3:1
+  |
+3 | - if: x > 5
+  | ^^^^^^^^^^^
+4 |   then:
+  |   ^^^^^
+5 |     - print: x / 0
+  |     ^^^^^^^^^^^^^^
+`, render(t, &diagnostics.Renderer{}, e))
+}
+
+func TestRenderLongSpan(t *testing.T) {
+	e := &yaperror.YapError{Message: "too long", Position: pos(1, 1)}
+	e.WithSpan(pos(1, 1), pos(12, 3))
+
+	source := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n")
+	r := &diagnostics.Renderer{ReadFile: func(string) ([]byte, error) { return source, nil }}
+
+	assert.Equal(t, `error: too long
+  --> main.yap:1:1
+   |
+ 1 | a
+   | ^
+ 2 | b
+   | ^
+ 3 | c
+   | ^
+...
+10 | j
+   | ^
+11 | k
+   | ^
+12 | l
+   | ^
+`, render(t, r, e))
+}
+
+func TestRenderContextFallback(t *testing.T) {
+	e := &yaperror.YapError{Message: "division by zero", Position: yaperror.Position{File: "<inline>", Line: 1, Column: 10}}
+	e.WithContext("- print: 1 / 0")
+
+	assert.Equal(t, `error: division by zero
+ --> <inline>:1:10
+  |
+1 | - print: 1 / 0
+  |          ^
+`, render(t, &diagnostics.Renderer{}, e))
+}
+
+func TestRenderWithoutPosition(t *testing.T) {
+	first := &yaperror.YapError{Message: "error finding file"}
+	second := &yaperror.YapError{Severity: yaperror.SeverityNote, Message: "1 file checked"}
+
+	assert.Equal(t, "error: error finding file\n\nnote: 1 file checked\n", render(t, &diagnostics.Renderer{}, first, second))
+}
+
+func TestRenderColor(t *testing.T) {
+	e := &yaperror.YapError{Message: "division by zero", Position: pos(5, 14)}
+
+	out := render(t, &diagnostics.Renderer{Color: true}, e)
+	assert.Contains(t, out, "\x1b[1;31merror\x1b[0m\x1b[1m: division by zero\x1b[0m\n")
+	assert.Contains(t, out, "\x1b[1;31m^\x1b[0m\n")
+}
+
+func TestParseColorMode(t *testing.T) {
+	mode, err := diagnostics.ParseColorMode("always")
+	require.NoError(t, err)
+	assert.True(t, mode.Enabled(nil))
+	assert.False(t, diagnostics.ColorNever.Enabled(nil))
+
+	_, err = diagnostics.ParseColorMode("sometimes")
+	assert.EqualError(t, err, `unknown color mode "sometimes", expected auto, always or never`)
+}
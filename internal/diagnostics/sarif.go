@@ -0,0 +1,140 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	toolName = "yap"
+	toolURI  = "https://github.com/rlamalama/YAP"
+)
+
+// The subset of SARIF 2.1.0 needed to report diagnostics
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId,omitempty"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations,omitempty"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifProperties struct {
+	Phase string   `json:"phase,omitempty"`
+	Notes []string `json:"notes,omitempty"`
+	Hints []string `json:"hints,omitempty"`
+}
+
+// WriteSARIF writes diags as a SARIF log with one run of the yap tool
+func WriteSARIF(w io.Writer, diags []*yaperror.YapError) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationURI: toolURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := map[string]bool{}
+	for _, e := range diags {
+		d := toDiagnostic(e)
+		result := sarifResult{
+			RuleID:     d.Code,
+			Level:      sarifLevel(e.Severity),
+			Message:    sarifMessage{Text: d.Message},
+			Properties: sarifProperties{Phase: d.Phase, Notes: e.Notes, Hints: e.Hints},
+		}
+		if d.Code != "" && !rules[d.Code] {
+			rules[d.Code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: d.Code})
+		}
+		if d.Position != nil {
+			region := sarifRegion{StartLine: d.Position.Line, StartColumn: d.Position.Column}
+			if d.Span != nil {
+				region.EndLine, region.EndColumn = d.Span.End.Line, d.Span.End.Column
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(d.Position.File)},
+				Region:           region,
+			}}}
+		}
+		run.Results = append(run.Results, result)
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// sarifLevel maps a severity to a SARIF result level
+func sarifLevel(s yaperror.Severity) string {
+	switch s {
+	case yaperror.SeverityError:
+		return "error"
+	case yaperror.SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
@@ -68,7 +68,11 @@ const (
 	ErrUnexpectedEOF
 	ErrInvalidNumber
 	ErrInvalidToken
+)
+
+// Each range starts its own block so that iota restarts at 0
 
+const (
 	// Parser errors (2000-2999)
 	ErrUnexpectedToken ErrorCode = 2000 + iota
 	ErrExpectedToken
@@ -78,7 +82,10 @@ const (
 	ErrUnexpectedEndOfInput
 	ErrMissingColon
 	ErrMissingValue
+	ErrInvalidModuleName
+)
 
+const (
 	// Builder/Semantic errors (3000-3999)
 	ErrUnsupportedStatement ErrorCode = 3000 + iota
 	ErrUndefinedVariable
@@ -88,7 +95,11 @@ const (
 	ErrDuplicateDefinition
 	ErrInvalidAssignment
 	ErrInvalidArgCount
+	ErrModuleNotFound
+	ErrImportCycle
+)
 
+const (
 	// Runtime errors (4000-4999)
 	ErrUnknownOpcode ErrorCode = 4000 + iota
 	ErrDivisionByZero
@@ -98,8 +109,23 @@ const (
 	ErrOutOfBounds
 	ErrInvalidType
 	ErrIOError
+	ErrStepLimitExceeded
+	ErrTimeout
+	ErrCallDepthExceeded
+	ErrMemoryLimitExceeded
+	ErrAssertionFailed
+	ErrErrorNotRaised
+	ErrEndOfInput
+	ErrEnvNotAllowed
+	ErrEnvNotSet
+	ErrInvalidDocument
 )
 
+// String returns the identifier of the code used in reports, e.g. E1001
+func (c ErrorCode) String() string {
+	return fmt.Sprintf("E%04d", int(c))
+}
+
 // Position represents a location in the source code
 type Position struct {
 	File   string
@@ -141,6 +167,7 @@ type YapError struct {
 	Message  string
 	Context  string // The source line where the error occurred
 	Notes    []string
+	Hints    []string // Suggestions how to fix the error
 }
 
 // Error implements the error interface
@@ -164,6 +191,15 @@ func (e *YapError) FullError() string {
 	// Main error line
 	sb.WriteString(e.Error())
 	sb.WriteString("\n")
+	sb.WriteString(e.Details())
+
+	return sb.String()
+}
+
+// Details returns the source context with a pointer to the error location, the
+// notes and the hints, everything FullError shows below the error line
+func (e *YapError) Details() string {
+	var sb strings.Builder
 
 	// Source context with pointer
 	if e.Context != "" {
@@ -185,6 +221,11 @@ func (e *YapError) FullError() string {
 		sb.WriteString(note)
 		sb.WriteString("\n")
 	}
+	for _, hint := range e.Hints {
+		sb.WriteString("hint: ")
+		sb.WriteString(hint)
+		sb.WriteString("\n")
+	}
 
 	return sb.String()
 }
@@ -195,6 +236,12 @@ func (e *YapError) AddNote(note string) *YapError {
 	return e
 }
 
+// AddHint adds a suggestion how to fix the error
+func (e *YapError) AddHint(hint string) *YapError {
+	e.Hints = append(e.Hints, hint)
+	return e
+}
+
 // WithContext adds source context to the error
 func (e *YapError) WithContext(line string) *YapError {
 	e.Context = line
@@ -383,6 +430,16 @@ func NewTabCharError(file string, line, col int) *YapError {
 	}
 }
 
+func NewInvalidNumberError(file string, line, col int, num string) *YapError {
+	return &YapError{
+		Code:     ErrInvalidNumber,
+		Severity: SeverityError,
+		Phase:    PhaseLexer,
+		Position: Position{File: file, Line: line, Column: col},
+		Message:  fmt.Sprintf("invalid number %q", num),
+	}
+}
+
 func NewInvalidTokenError(file string, line, col int) *YapError {
 	return &YapError{
 		Code:     ErrInvalidToken,
@@ -425,6 +482,16 @@ func NewUnknownStatementError(file string, line, col int, stmt string) *YapError
 	}
 }
 
+func NewInvalidModuleNameError(file string, line, col int, path string) *YapError {
+	return &YapError{
+		Code:     ErrInvalidModuleName,
+		Severity: SeverityError,
+		Phase:    PhaseParser,
+		Position: Position{File: file, Line: line, Column: col},
+		Message:  fmt.Sprintf("cannot import %q under its file name, name the module with as", path),
+	}
+}
+
 // Builder/Semantic error constructors
 
 func NewUnsupportedStatementError(stmtType string) *YapError {
@@ -446,6 +513,16 @@ func NewUndefinedVariableError(file string, line, col int, name string) *YapErro
 	}
 }
 
+func NewUndefinedFunctionError(file string, line, col int, name string) *YapError {
+	return &YapError{
+		Code:     ErrUndefinedFunction,
+		Severity: SeverityError,
+		Phase:    PhaseBuilder,
+		Position: Position{File: file, Line: line, Column: col},
+		Message:  fmt.Sprintf("undefined function %q", name),
+	}
+}
+
 func NewTypeMismatchError(file string, line, col int, expected, got string) *YapError {
 	return &YapError{
 		Code:     ErrTypeMismatch,
@@ -456,6 +533,40 @@ func NewTypeMismatchError(file string, line, col int, expected, got string) *Yap
 	}
 }
 
+func NewInvalidOperationError(file string, line, col int, msg string) *YapError {
+	return &YapError{
+		Code:     ErrInvalidOperation,
+		Severity: SeverityError,
+		Phase:    PhaseBuilder,
+		Position: Position{File: file, Line: line, Column: col},
+		Message:  msg,
+	}
+}
+
+func NewModuleNotFoundError(file string, line, col int, path string, searched []string) *YapError {
+	err := &YapError{
+		Code:     ErrModuleNotFound,
+		Severity: SeverityError,
+		Phase:    PhaseBuilder,
+		Position: Position{File: file, Line: line, Column: col},
+		Message:  fmt.Sprintf("module %q not found", path),
+	}
+	for _, dir := range searched {
+		err.AddNote(fmt.Sprintf("searched %s", dir))
+	}
+	return err
+}
+
+func NewImportCycleError(file string, line, col int, cycle []string) *YapError {
+	return &YapError{
+		Code:     ErrImportCycle,
+		Severity: SeverityError,
+		Phase:    PhaseBuilder,
+		Position: Position{File: file, Line: line, Column: col},
+		Message:  fmt.Sprintf("import cycle: %s", strings.Join(cycle, " -> ")),
+	}
+}
+
 // Runtime error constructors
 
 func NewInvalidSetIR(val string) *YapError {
@@ -476,6 +587,62 @@ func NewUndefinedVariable(val string) *YapError {
 	}
 }
 
+func NewUndefinedFunction(name string) *YapError {
+	return &YapError{
+		Code:     ErrUndefinedFunction,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("undefined function: %s", name),
+	}
+}
+
+// NewArgCountError reports a call of fn with got arguments, where want
+// describes the accepted number, e.g. "2" or "at least 1"
+func NewArgCountError(fn, want string, got int) *YapError {
+	noun := "arguments"
+	if want == "1" || strings.HasSuffix(want, " 1") {
+		noun = "argument"
+	}
+	return &YapError{
+		Code:     ErrInvalidArgCount,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("%s expects %s %s, got %d", fn, want, noun, got),
+	}
+}
+
+func NewArgTypeError(fn string, n int, expected, got string) *YapError {
+	article := "a"
+	if strings.ContainsAny(expected[:1], "aeiou") {
+		article = "an"
+	}
+	return &YapError{
+		Code:     ErrInvalidType,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("argument %d of %s must be %s %s, got %s", n, fn, article, expected, got),
+	}
+}
+
+// NewConversionError reports a string that does not spell a value of type to
+func NewConversionError(s, to string) *YapError {
+	return &YapError{
+		Code:     ErrInvalidType,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("cannot convert %q to %s", s, to),
+	}
+}
+
+func NewOverflowError(fn string) *YapError {
+	return &YapError{
+		Code:     ErrInvalidType,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("%s: result does not fit in an integer", fn),
+	}
+}
+
 func NewUnknownOpcodeError(opcode int) *YapError {
 	return &YapError{
 		Code:     ErrUnknownOpcode,
@@ -512,6 +679,132 @@ func NewOutOfBoundsError(index, length int) *YapError {
 	}
 }
 
+func NewIOError(err error) *YapError {
+	return &YapError{
+		Code:     ErrIOError,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("i/o error: %v", err),
+	}
+}
+
+// NewFileError reports that op, e.g. "read", failed on the file at path
+func NewFileError(op, path string, err error) *YapError {
+	return &YapError{
+		Code:     ErrIOError,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("cannot %s %q: %v", op, path, err),
+	}
+}
+
+func NewStepLimitError(limit int) *YapError {
+	return &YapError{
+		Code:     ErrStepLimitExceeded,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("step limit exceeded: executed more than %d instructions", limit),
+	}
+}
+
+func NewTimeoutError(cause error) *YapError {
+	return &YapError{
+		Code:     ErrTimeout,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("execution stopped: %v", cause),
+	}
+}
+
+func NewCallDepthError(limit int) *YapError {
+	return &YapError{
+		Code:     ErrCallDepthExceeded,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("call depth exceeded: nesting deeper than %d", limit),
+	}
+}
+
+func NewMemoryLimitError(limit int) *YapError {
+	return &YapError{
+		Code:     ErrMemoryLimitExceeded,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("memory limit exceeded: values larger than %d bytes", limit),
+	}
+}
+
+func NewAssertionError(msg string) *YapError {
+	return &YapError{
+		Code:     ErrAssertionFailed,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("assertion failed: %s", msg),
+	}
+}
+
+func NewErrorNotRaisedError() *YapError {
+	return &YapError{
+		Code:     ErrErrorNotRaised,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  "expected an error, but the block completed",
+	}
+}
+
+func NewEndOfInputError(name string) *YapError {
+	return &YapError{
+		Code:     ErrEndOfInput,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("end of input: no line left to read into %q", name),
+	}
+}
+
+func NewEnvNotAllowedError(name string) *YapError {
+	return &YapError{
+		Code:     ErrEnvNotAllowed,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("cannot read environment variable %q: environment access is not allowed", name),
+	}
+}
+
+func NewEnvNotSetError(name string) *YapError {
+	return &YapError{
+		Code:     ErrEnvNotSet,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("environment variable %q is not set", name),
+	}
+}
+
+// NewDocumentError reports a JSON or YAML document that cannot be parsed, at
+// a line and column of the document. A column of 0 is left out
+func NewDocumentError(format string, line, col int, msg string) *YapError {
+	at := fmt.Sprintf("line %d", line)
+	if col > 0 {
+		at += fmt.Sprintf(", column %d", col)
+	}
+	return &YapError{
+		Code:     ErrInvalidDocument,
+		Severity: SeverityError,
+		Phase:    PhaseRuntime,
+		Message:  fmt.Sprintf("invalid %s at %s: %s", format, at, msg),
+	}
+}
+
+// IsLimitError reports whether err was raised by one of the execution
+// limits of a run rather than by the program itself
+func IsLimitError(err *YapError) bool {
+	switch err.Code {
+	case ErrStepLimitExceeded, ErrTimeout, ErrCallDepthExceeded, ErrMemoryLimitExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
 func NewRuntimeError(msg string) *YapError {
 	return &YapError{
 		Code:     ErrInvalidType,
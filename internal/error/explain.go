@@ -0,0 +1,335 @@
+package error
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Explanation describes an error code for yap explain
+type Explanation struct {
+	Code      ErrorCode
+	Title     string
+	Text      string // What causes the error and how to fix it
+	Erroneous string // A program that reports the error, empty if none can
+	Corrected string // The same program without the error
+}
+
+// Explain returns the explanation of code
+func Explain(code ErrorCode) (Explanation, bool) {
+	e, ok := explanations[code]
+	return e, ok
+}
+
+// ErrorCodes returns every explained error code in ascending order
+func ErrorCodes() []ErrorCode {
+	codes := make([]ErrorCode, 0, len(explanations))
+	for code := range explanations {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// ParseErrorCode parses a code as shown in diagnostics, e.g. E2001. The E is
+// optional and may be lower case
+func ParseErrorCode(s string) (ErrorCode, error) {
+	digits := strings.TrimPrefix(strings.ToUpper(s), "E")
+	n, err := strconv.Atoi(digits)
+	if err != nil || len(digits) != 4 {
+		return 0, fmt.Errorf("invalid error code %q, expected a code like E1001", s)
+	}
+	return ErrorCode(n), nil
+}
+
+// notReported is the text of codes no part of YAP reports yet
+const notReported = "This code is reserved and not reported by this version of YAP."
+
+// internalError is the text of codes that mean YAP itself is broken
+const internalError = "This error means the YAP compiler produced a program the virtual machine cannot run. " +
+	"It is a bug in YAP rather than in your program; please report it with the program that triggers it."
+
+var explanations = map[ErrorCode]Explanation{
+	// Lexer errors
+
+	ErrInvalidCharacter: {
+		Title: "invalid character",
+		Text: "The source contains a character that cannot start any token, outside of a string or comment. " +
+			notReported,
+	},
+	ErrUnterminatedString: {
+		Title: "unterminated string literal",
+		Text: "A string literal was opened with a double quote but the line ended before the closing quote. " +
+			"Strings cannot span lines; close the string on the line it starts.",
+		Erroneous: "- print: \"hello\n",
+		Corrected: "- print: \"hello\"\n",
+	},
+	ErrInvalidIndentation: {
+		Title: "invalid indentation",
+		Text: "A line is indented to a level that does not match any enclosing block. " +
+			"When a block ends, the next line must go back to exactly the indentation of a statement it is nested in.",
+		Erroneous: "- if: True\n  then:\n    - print: \"yes\"\n   - print: \"done\"\n",
+		Corrected: "- if: True\n  then:\n    - print: \"yes\"\n- print: \"done\"\n",
+	},
+	ErrTabCharacter: {
+		Title: "tab character",
+		Text: "YAP, like YAML, uses spaces for indentation and rejects tab characters. " +
+			"Configure your editor to insert spaces; two per level is the convention `yap fmt` uses.",
+		Erroneous: "- if: True\n  then:\n\t- print: \"yes\"\n",
+		Corrected: "- if: True\n  then:\n    - print: \"yes\"\n",
+	},
+	ErrInvalidEscapeSequence: {
+		Title: "invalid escape sequence",
+		Text:  "A backslash in a string literal is followed by a character that does not form an escape sequence. " + notReported,
+	},
+	ErrUnexpectedEOF: {
+		Title: "unexpected end of file",
+		Text:  "The file ended in the middle of a token. " + notReported,
+	},
+	ErrInvalidNumber: {
+		Title: "invalid number",
+		Text: "A numeric literal cannot be represented, usually because it is too large for a 64-bit integer. " +
+			"Integers range from -9223372036854775808 to 9223372036854775807.",
+		Erroneous: "- print: 99999999999999999999\n",
+		Corrected: "- print: 999999999999999999\n",
+	},
+	ErrInvalidToken: {
+		Title: "invalid token",
+		Text: "The lexer found a character sequence that is not part of the language, " +
+			"such as an unknown operator. Expressions are written with " +
+			"`+`, `-`, `*`, `/` and comparison operators only and are evaluated left to right; " +
+			"other operations are functions of builtin modules like `math`.",
+		Erroneous: "- print: 7 % 2\n",
+		Corrected: "- import: \"math\"\n- print: math.mod(7, 2)\n",
+	},
+
+	// Parser errors
+
+	ErrUnexpectedToken: {
+		Title: "unexpected token",
+		Text: "The parser found a token where the grammar does not allow it; the message names what it expected instead. " +
+			"Common causes are a misspelled statement keyword, a missing colon or value, " +
+			"or a `then` or `else` on the wrong line.",
+		Erroneous: "- prnt: \"hello\"\n",
+		Corrected: "- print: \"hello\"\n",
+	},
+	ErrExpectedToken: {
+		Title: "expected token",
+		Text: "The parser ran out of tokens where it expected the one named in the message. " +
+			"Programs that end in the middle of a statement usually report E2000 instead, " +
+			"with the end of the line or file as the unexpected token.",
+	},
+	ErrUnknownStatement: {
+		Title: "unknown statement",
+		Text: "A list item starts with a keyword that is not a statement. " +
+			"`then` and `else` belong to an `if` statement, `in` and `do` to a `for` statement and `True` and `False` are values; " +
+			"statements are `print`, `set`, `input`, `if`, `for`, `call`, `assert`, `expect_error`, `import` and `exit`.",
+		Erroneous: "- then:\n  - print: \"yes\"\n",
+		Corrected: "- if: True\n  then:\n    - print: \"yes\"\n",
+	},
+	ErrMissingExpression: {
+		Title: "missing expression",
+		Text:  "A statement or operator is missing its expression. " + notReported,
+	},
+	ErrInvalidSyntax: {
+		Title: "invalid syntax",
+		Text:  "The source is not valid YAP. " + notReported,
+	},
+	ErrUnexpectedEndOfInput: {
+		Title: "unexpected end of input",
+		Text:  "The program ended inside a statement or block. " + notReported,
+	},
+	ErrMissingColon: {
+		Title: "missing colon",
+		Text:  "A keyword or variable name is not followed by a colon. " + notReported,
+	},
+	ErrMissingValue: {
+		Title: "missing value",
+		Text:  "A key is followed by a colon but no value. " + notReported,
+	},
+	ErrInvalidModuleName: {
+		Title: "invalid module name",
+		Text: "An `import` without `as` names the module after its file, e.g. `lib/util.yap` becomes `util`, " +
+			"so the file name must be a valid identifier. Rename the file or give the module a name with " +
+			"`as: name` on the line below the import.",
+	},
+
+	// Builder/semantic errors
+
+	ErrUnsupportedStatement: {
+		Title: "unsupported statement",
+		Text: "The compiler does not know how to translate a statement the parser accepted. " +
+			"Like an internal error this is a bug in YAP; please report it with the program that triggers it.",
+	},
+	ErrUndefinedVariable: {
+		Title: "undefined variable",
+		Text: "An expression uses a variable that has not been assigned by a `set` statement before it. " +
+			"Check the spelling, which is case sensitive, and that the `set` runs first; " +
+			"a variable set in only one branch of an `if` may be undefined after it.",
+		Erroneous: "- set:\n  - name: \"YAP\"\n- print: nmae\n",
+		Corrected: "- set:\n  - name: \"YAP\"\n- print: name\n",
+	},
+	ErrUndefinedFunction: {
+		Title: "undefined function",
+		Text: "A call names a function that does not exist: neither a builtin like `str` nor a function of the module it is selected from. " +
+			"Check the spelling, which is case sensitive, and that the module is imported.",
+		Erroneous: "- import: \"math\"\n- print: math.sqr(16)\n",
+		Corrected: "- import: \"math\"\n- print: math.sqrt(16)\n",
+	},
+	ErrTypeMismatch: {
+		Title: "type mismatch",
+		Text: "`yap check` found an operand or condition whose type does not fit: " +
+			"both operands of an operator must have the same type and conditions and assertions must be booleans. " +
+			"At run time the same programs fail with E4006.",
+		Erroneous: "- set:\n  - count: 3\n- print: \"count: \" + count\n",
+		Corrected: "- set:\n  - count: 3\n- print: \"count:\", count\n",
+	},
+	ErrInvalidOperation: {
+		Title: "invalid operation",
+		Text: "`yap check` found an operator applied to values of a type that does not support it, " +
+			"such as multiplying strings. Strings support `+` and the comparison operators, booleans only `==` and `!=`.",
+		Erroneous: "- print: \"ab\" * \"c\"\n",
+		Corrected: "- print: \"ab\" + \"c\"\n",
+	},
+	ErrDuplicateDefinition: {
+		Title: "duplicate definition",
+		Text:  "The same name is defined twice in one scope. " + notReported,
+	},
+	ErrInvalidAssignment: {
+		Title: "invalid assignment",
+		Text:  "The target of an assignment is not a variable. " + notReported,
+	},
+	ErrInvalidArgCount: {
+		Title: "wrong number of arguments",
+		Text: "A function is called with more or fewer arguments than it takes. " +
+			"The message shows how many the function expects.",
+		Erroneous: "- import: \"math\"\n- print: math.pow(2)\n",
+		Corrected: "- import: \"math\"\n- print: math.pow(2, 10)\n",
+	},
+	ErrModuleNotFound: {
+		Title: "module not found",
+		Text: "The file named by an `import` does not exist. Relative paths are looked up in the directory of the " +
+			"importing file and then in each directory of the `YAP_PATH` environment variable; " +
+			"the notes list the directories searched.",
+	},
+	ErrImportCycle: {
+		Title: "import cycle",
+		Text: "Modules import each other in a cycle, so none of them can run before the others. " +
+			"The message lists the files of the cycle in import order. " +
+			"Move what the modules share into a module that imports neither of them.",
+	},
+
+	// Runtime errors
+
+	ErrUnknownOpcode: {
+		Title: "unknown opcode",
+		Text:  internalError,
+	},
+	ErrDivisionByZero: {
+		Title: "division by zero",
+		Text:  "A division has zero as its divisor. " + notReported + " Division by zero is currently reported as E4006.",
+	},
+	ErrStackUnderflow: {
+		Title: "stack underflow",
+		Text:  internalError,
+	},
+	ErrStackOverflow: {
+		Title: "stack overflow",
+		Text:  internalError,
+	},
+	ErrNullReference: {
+		Title: "null reference",
+		Text:  "A value was used that does not exist. " + notReported,
+	},
+	ErrOutOfBounds: {
+		Title: "index out of bounds",
+		Text: "An index is negative or larger than the length of the value it indexes, " +
+			"e.g. a `strings.substring` that ends after the last character. Indexes count characters, not bytes.",
+		Erroneous: "- import: \"strings\"\n- print: strings.substring(\"héllo\", 1, 9)\n",
+		Corrected: "- import: \"strings\"\n- print: strings.substring(\"héllo\", 1, 5)\n",
+	},
+	ErrInvalidType: {
+		Title: "invalid operation at run time",
+		Text: "An operation was applied to values it does not support while the program ran: " +
+			"operands of different types, a condition or assertion that is not a boolean, a division by zero, " +
+			"a function argument of the wrong type, a string that does not convert with `int` or `bool`, " +
+			"or a result too large for an integer. " +
+			"Run `yap check` to find type errors before running the program.",
+		Erroneous: "- set:\n  - total: 10\n  - count: 0\n- print: total / count\n",
+		Corrected: "- set:\n  - total: 10\n  - count: 0\n- if: count != 0\n  then:\n    - print: total / count\n  else:\n    - print: \"no items\"\n",
+	},
+	ErrIOError: {
+		Title: "i/o error",
+		Text: "Reading or writing failed, for example because a file read with the `fs` module does not exist, " +
+			"a directory was read as a file or the output of `yap run` was closed while the program was printing. " +
+			"The message includes the path of the file and the error reported by the operating system. " +
+			"Relative paths are resolved against the working directory; use `fs.exists` to check for optional files.",
+		Erroneous: "- import: \"fs\"\n- print: fs.read_file(\"settings.txt\")\n",
+		Corrected: "- import: \"fs\"\n- if: fs.exists(\"settings.txt\")\n  then:\n    - print: fs.read_file(\"settings.txt\")\n",
+	},
+	ErrStepLimitExceeded: {
+		Title: "step limit exceeded",
+		Text: "The program executed more instructions than allowed by `--max-steps`. " +
+			"Raise the limit, or look for a part of the program that does more work than intended.",
+	},
+	ErrTimeout: {
+		Title: "timeout",
+		Text:  "The program ran longer than allowed by `--timeout` and was stopped. Raise the limit or make the program do less work.",
+	},
+	ErrCallDepthExceeded: {
+		Title: "call depth exceeded",
+		Text: "An expression is nested deeper than the virtual machine allows, " +
+			"e.g. a very long chain of operators. Split it with intermediate variables.",
+	},
+	ErrMemoryLimitExceeded: {
+		Title: "memory limit exceeded",
+		Text:  "The program built a value larger than the memory limit of the run, usually a string grown by repeated concatenation.",
+	},
+	ErrAssertionFailed: {
+		Title: "assertion failed",
+		Text: "The condition of an `assert` statement was false. The message shows the condition, " +
+			"or the `message` given with the assertion. Fix the program or the expectation.",
+		Erroneous: "- set:\n  - total: 2 + 2\n- assert: total == 5\n  message: \"2 + 2 should be 5\"\n",
+		Corrected: "- set:\n  - total: 2 + 2\n- assert: total == 4\n  message: \"2 + 2 should be 4\"\n",
+	},
+	ErrErrorNotRaised: {
+		Title: "error not raised",
+		Text: "The statements of an `expect_error` block all completed without an error. " +
+			"The block is used by tests to check that something fails; make sure it contains the failing statement.",
+		Erroneous: "- expect_error:\n  - print: 1 / 1\n",
+		Corrected: "- expect_error:\n  - print: 1 / 0\n",
+	},
+	ErrEndOfInput: {
+		Title: "end of input",
+		Text: "An `input` statement found no line left to read, because the input was empty or every line was already read, " +
+			"for example when a script reading from a pipe gets less data than expected. " +
+			"Provide more input, or wrap the statement in an `expect_error` block to handle the end of the input.",
+	},
+	ErrEnvNotAllowed: {
+		Title: "environment access not allowed",
+		Text: "`env` or `env_or` was called in a run that may not read environment variables. " +
+			"Programs only see the environment when `yap run` is given `--allow-env`, so a script cannot read secrets unasked. " +
+			"Pass the flag, or pass the value as an argument and read it from `args`.",
+	},
+	ErrEnvNotSet: {
+		Title: "environment variable not set",
+		Text: "`env` was called with the name of a variable that is not set. " +
+			"Set it before running the program, or use `env_or` with a default, e.g. `env_or(\"YAP_HOME\", \"/usr/local/yap\")`.",
+	},
+	ErrInvalidDocument: {
+		Title: "invalid document",
+		Text: "`json_parse` or `yaml_parse` was given text that is not a valid document. " +
+			"The message gives the line, and for JSON the column, of the problem in the document rather than in the program. " +
+			"Fix the document, often a file read with `fs.read_file`, or parse it inside an `expect_error` block to handle bad input.",
+		Erroneous: "- print: json_parse(\"[1, 2\")\n",
+		Corrected: "- print: json_parse(\"[1, 2]\")\n",
+	},
+}
+
+func init() {
+	for code, e := range explanations {
+		e.Code = code
+		explanations[code] = e
+	}
+}
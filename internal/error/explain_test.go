@@ -0,0 +1,81 @@
+package error_test
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// declaredErrorCodes type checks the package source and returns the value of
+// every ErrorCode constant by name, so new codes cannot be missed
+func declaredErrorCodes(t *testing.T) map[string]yaperror.ErrorCode {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "error.go", nil, 0)
+	require.NoError(t, err)
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("error", fset, []*ast.File{file}, nil)
+	require.NoError(t, err)
+
+	codes := map[string]yaperror.ErrorCode{}
+	for _, name := range pkg.Scope().Names() {
+		c, ok := pkg.Scope().Lookup(name).(*types.Const)
+		if !ok || c.Type() != pkg.Scope().Lookup("ErrorCode").Type() {
+			continue
+		}
+		value, _ := constant.Int64Val(c.Val())
+		codes[name] = yaperror.ErrorCode(value)
+	}
+	return codes
+}
+
+func TestEveryErrorCodeIsExplained(t *testing.T) {
+	codes := declaredErrorCodes(t)
+	require.NotEmpty(t, codes)
+
+	seen := map[yaperror.ErrorCode]string{}
+	for name, code := range codes {
+		if other, ok := seen[code]; ok {
+			t.Errorf("%s and %s both have code %s", name, other, code)
+		}
+		seen[code] = name
+
+		e, ok := yaperror.Explain(code)
+		if !assert.True(t, ok, "%s (%s) has no explanation", name, code) {
+			continue
+		}
+		assert.Equal(t, code, e.Code, name)
+		assert.NotEmpty(t, e.Title, name)
+		assert.NotEmpty(t, e.Text, name)
+		assert.Equal(t, e.Erroneous == "", e.Corrected == "", "%s needs both examples or neither", name)
+	}
+	assert.Len(t, yaperror.ErrorCodes(), len(codes), "explanations for codes that are not declared")
+}
+
+func TestErrorCodeRanges(t *testing.T) {
+	assert.Equal(t, yaperror.ErrorCode(1000), yaperror.ErrInvalidCharacter)
+	assert.Equal(t, yaperror.ErrorCode(2000), yaperror.ErrUnexpectedToken)
+	assert.Equal(t, yaperror.ErrorCode(3000), yaperror.ErrUnsupportedStatement)
+	assert.Equal(t, yaperror.ErrorCode(4000), yaperror.ErrUnknownOpcode)
+	assert.Equal(t, "E2001", yaperror.ErrExpectedToken.String())
+}
+
+func TestParseErrorCode(t *testing.T) {
+	for _, s := range []string{"E2001", "e2001", "2001"} {
+		code, err := yaperror.ParseErrorCode(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, yaperror.ErrExpectedToken, code, s)
+	}
+	for _, s := range []string{"", "E", "E201", "Eabcd", "E20011"} {
+		_, err := yaperror.ParseErrorCode(s)
+		assert.Error(t, err, s)
+	}
+}
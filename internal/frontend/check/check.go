@@ -0,0 +1,420 @@
+package check
+
+import (
+	"fmt"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/lexer"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/source"
+	"github.com/rlamalama/YAP/internal/frontend/suggest"
+)
+
+// Symbol is a variable defined by an assignment in a set statement, the
+// namespace of an import, the variable of a for statement or the line read by
+// an input statement. Every definition is a new symbol, reassigning a name
+// shadows it. Exactly one of the statement fields is set
+type Symbol struct {
+	Name       string
+	Type       Type
+	Assignment *parser.Assignment
+	Import     *parser.ImportStmt
+	Loop       *parser.ForStmt
+	Input      *parser.InputStmt
+}
+
+// Span returns the span of the symbol's name in its statement, or of the path
+// of its import
+func (s *Symbol) Span() source.Span {
+	switch {
+	case s.Import != nil:
+		return s.Import.PathLoc
+	case s.Loop != nil:
+		return s.Loop.VarLoc
+	case s.Input != nil:
+		return s.Input.NameLoc
+	}
+	return s.Assignment.Loc
+}
+
+// unknown returns a copy of s whose type is unknown, for a name whose type
+// depends on which statements ran
+func (s *Symbol) unknown() *Symbol {
+	c := *s
+	c.Type = TypeUnknown
+	return &c
+}
+
+// Reference is a use of a variable in an expression
+type Reference struct {
+	Ident  *parser.Identifier
+	Symbol *Symbol // nil if the variable is undefined
+}
+
+// Info holds the results of checking a program
+type Info struct {
+	Symbols    []*Symbol             // Symbols in the order they are defined
+	References []*Reference          // Variable uses in source order
+	Types      map[parser.Value]Type // Inferred type of every expression
+	Errors     *yaperror.ErrorList   // Semantic errors found in the program
+}
+
+// SymbolAt returns the symbol defined or referenced at pos, if any
+func (info *Info) SymbolAt(pos source.Position) (*Symbol, source.Span, bool) {
+	for _, ref := range info.References {
+		if ref.Symbol != nil && ref.Ident.Loc.Contains(pos) {
+			return ref.Symbol, ref.Ident.Loc, true
+		}
+	}
+	for _, sym := range info.Symbols {
+		if sym.Span().Contains(pos) {
+			return sym, sym.Span(), true
+		}
+	}
+	return nil, source.Span{}, false
+}
+
+// ValueAt returns the innermost expression at pos, if any
+func (info *Info) ValueAt(pos source.Position) (parser.Value, bool) {
+	var found parser.Value
+	for v := range info.Types {
+		if _, ok := v.(*parser.ExprList); ok {
+			continue
+		}
+		span := v.Span()
+		if !span.Contains(pos) {
+			continue
+		}
+		// Prefer the narrowest expression containing pos
+		if found == nil || spanWidth(span) < spanWidth(found.Span()) {
+			found = v
+		}
+	}
+	return found, found != nil
+}
+
+func spanWidth(s source.Span) int {
+	return (s.End.Line-s.Start.Line)*1_000_000 + s.End.Column - s.Start.Column
+}
+
+// scope maps variable names to the symbol currently assigned to them
+type scope map[string]*Symbol
+
+// candidates returns the names an undefined variable may have been meant to
+// be: the variables in scope and the boolean literals
+func (s scope) candidates() []string {
+	names := []string{lexer.KeywordTrue, lexer.KeywordFalse}
+	for name := range s {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s scope) clone() scope {
+	c := make(scope, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
+
+type checker struct {
+	file string
+	info *Info
+}
+
+// Check infers the types of a program's expressions, resolves its variables
+// and reports undefined variables and type mismatches
+func Check(prog *parser.Program) *Info {
+	c := &checker{
+		info: &Info{
+			Types:  map[parser.Value]Type{},
+			Errors: yaperror.NewErrorList(),
+		},
+	}
+	if prog.File != nil {
+		c.file = prog.File.Path
+	}
+
+	c.checkBlock(prog.Statements, scope{})
+	return c.info
+}
+
+func (c *checker) checkBlock(stmts []parser.Stmt, sc scope) scope {
+	for _, stmt := range stmts {
+		sc = c.checkStmt(stmt, sc)
+	}
+	return sc
+}
+
+func (c *checker) checkStmt(stmt parser.Stmt, sc scope) scope {
+	switch s := stmt.(type) {
+	case parser.PrintStmt:
+		c.checkExpr(s.Expr, sc)
+		if s.Sep != nil {
+			c.expectType(s.Sep, c.checkExpr(s.Sep, sc), TypeString)
+		}
+
+	case parser.SetStmt:
+		sc = sc.clone()
+		for _, assignment := range s.Assignment {
+			sym := &Symbol{
+				Name:       assignment.Name,
+				Type:       c.checkExpr(assignment.Expr, sc),
+				Assignment: assignment,
+			}
+			c.info.Symbols = append(c.info.Symbols, sym)
+			sc[assignment.Name] = sym
+		}
+
+	case parser.IfStmt:
+		c.expectType(s.Condition, c.checkExpr(s.Condition, sc), TypeBool)
+
+		thenScope := c.checkBlock(s.Then, sc.clone())
+		elseScope := c.checkBlock(s.Else, sc.clone())
+
+		// Either branch may run, so keep what either of them assigned
+		merged := sc.clone()
+		for name, sym := range thenScope {
+			merged[name] = sym
+		}
+		for name, sym := range elseScope {
+			if other, ok := thenScope[name]; ok && other != sym && other.Type != sym.Type {
+				merged[name] = sym.unknown()
+				continue
+			}
+			merged[name] = sym
+		}
+		sc = merged
+
+	case parser.AssertStmt:
+		c.expectType(s.Condition, c.checkExpr(s.Condition, sc), TypeBool)
+		if s.Message != nil {
+			c.expectType(s.Message, c.checkExpr(s.Message, sc), TypeString)
+		}
+
+	case parser.ImportStmt:
+		sc = sc.clone()
+		sym := &Symbol{Name: s.Alias, Type: TypeModule, Import: &s}
+		c.info.Symbols = append(c.info.Symbols, sym)
+		sc[s.Alias] = sym
+
+	case parser.ExpectErrorStmt:
+		// The block is meant to fail, so its errors are not reported. Its
+		// symbols and types are still recorded
+		errs := c.info.Errors
+		c.info.Errors = yaperror.NewErrorList()
+		body := c.checkBlock(s.Body, sc.clone())
+		c.info.Errors = errs
+
+		// The block stops at its first error, so its assignments may not run
+		sc = mergeMaybe(sc, body)
+
+	case parser.ForStmt:
+		// Items of lists have no static type yet
+		c.checkExpr(s.List, sc)
+		body := sc.clone()
+		sym := &Symbol{Name: s.Var, Type: TypeUnknown, Loop: &s}
+		c.info.Symbols = append(c.info.Symbols, sym)
+		body[s.Var] = sym
+
+		// The list may be empty, so the body may not run
+		sc = mergeMaybe(sc, c.checkBlock(s.Body, body))
+
+	case parser.CallStmt:
+		c.checkExpr(s.Call, sc)
+
+	case parser.InputStmt:
+		// The prompt is written like a printed value, so any type will do
+		if s.Prompt != nil {
+			c.checkExpr(s.Prompt, sc)
+		}
+		sc = sc.clone()
+		sym := &Symbol{Name: s.Name, Type: TypeString, Input: &s}
+		c.info.Symbols = append(c.info.Symbols, sym)
+		sc[s.Name] = sym
+
+	case parser.ExitStmt:
+		c.expectType(s.Code, c.checkExpr(s.Code, sc), TypeInt)
+	}
+
+	return sc
+}
+
+// mergeMaybe returns sc with the symbols of body, a block that may not run to
+// its end. Names whose type depends on how far it ran have an unknown type
+func mergeMaybe(sc, body scope) scope {
+	merged := sc.clone()
+	for name, sym := range body {
+		if other, ok := sc[name]; ok && other != sym && other.Type != sym.Type {
+			merged[name] = sym.unknown()
+			continue
+		}
+		merged[name] = sym
+	}
+	return merged
+}
+
+func (c *checker) checkExpr(expr parser.Value, sc scope) Type {
+	t := c.inferExpr(expr, sc)
+	c.info.Types[expr] = t
+	return t
+}
+
+func (c *checker) inferExpr(expr parser.Value, sc scope) Type {
+	switch v := expr.(type) {
+	case *parser.NumericLiteral:
+		return TypeInt
+
+	case *parser.FloatLiteral:
+		return TypeFloat
+
+	case *parser.StringLiteral:
+		return TypeString
+
+	case *parser.BooleanLiteral:
+		return TypeBool
+
+	case *parser.Identifier:
+		sym := sc[v.Name]
+		if _, ok := stdlib.Global(v.Name); ok && sym == nil {
+			return TypeUnknown
+		}
+		c.info.References = append(c.info.References, &Reference{Ident: v, Symbol: sym})
+		if sym == nil {
+			pos := v.Loc.Start
+			err := yaperror.NewUndefinedVariableError(c.file, pos.Line, pos.Column, v.Name)
+			if hint := suggest.Hint(v.Name, sc.candidates()); hint != "" {
+				err.AddHint(hint)
+			}
+			c.info.Errors.Add(err)
+			return TypeUnknown
+		}
+		return sym.Type
+
+	case *parser.SelectorExpr:
+		// The variables of a module are only known once it ran
+		c.expectType(v.X, c.checkExpr(v.X, sc), TypeModule)
+		return TypeUnknown
+
+	case *parser.CallExpr:
+		// The functions of modules are only known once they ran
+		result := TypeUnknown
+		if ident, ok := v.Fun.(*parser.Identifier); ok && sc[ident.Name] == nil {
+			if global, ok := stdlib.Global(ident.Name); ok {
+				if fn, ok := global.(*stdlib.Func); ok {
+					result = resultTypes[fn.Result]
+				}
+			} else {
+				c.addUndefinedFunction(ident, sc)
+			}
+		} else {
+			c.checkExpr(v.Fun, sc)
+		}
+		for _, arg := range v.Args {
+			c.checkExpr(arg, sc)
+		}
+		return result
+
+	case *parser.ExprList:
+		for _, value := range v.Values {
+			c.checkExpr(value, sc)
+		}
+		return TypeUnknown
+
+	case *parser.BinaryExpr:
+		left := c.checkExpr(v.Left, sc)
+		right := c.checkExpr(v.Right, sc)
+		return c.inferBinary(v, left, right)
+
+	default:
+		return TypeUnknown
+	}
+}
+
+// inferBinary mirrors the operations the VM supports for each operand type
+func (c *checker) inferBinary(expr *parser.BinaryExpr, left, right Type) Type {
+	if left == TypeUnknown || right == TypeUnknown {
+		if isComparison(expr.Operator) {
+			return TypeBool
+		}
+		return TypeUnknown
+	}
+
+	if left.isNumber() && right.isNumber() && left != right {
+		// An int operand is converted to a float
+		left, right = TypeFloat, TypeFloat
+	}
+	if left != right {
+		c.addTypeMismatch(expr.Right, left, right)
+		return TypeUnknown
+	}
+
+	switch {
+	case isComparison(expr.Operator):
+		if left == TypeBool && !isEquality(expr.Operator) {
+			c.addUnsupported(expr, left)
+			return TypeUnknown
+		}
+		return TypeBool
+	case left.isNumber():
+		return left
+	case left == TypeString && expr.Operator == lexer.ArithmeticAdditionOperator.String():
+		return TypeString
+	default:
+		c.addUnsupported(expr, left)
+		return TypeUnknown
+	}
+}
+
+// resultTypes maps the result types of builtin functions, see
+// stdlib.Func.Result, to static types. Other results are unknown
+var resultTypes = map[string]Type{
+	"int":    TypeInt,
+	"float":  TypeFloat,
+	"string": TypeString,
+	"bool":   TypeBool,
+}
+
+func (c *checker) addUndefinedFunction(ident *parser.Identifier, sc scope) {
+	pos := ident.Loc.Start
+	err := yaperror.NewUndefinedFunctionError(c.file, pos.Line, pos.Column, ident.Name)
+	if hint := suggest.Hint(ident.Name, append(stdlib.GlobalNames(), sc.candidates()...)); hint != "" {
+		err.AddHint(hint)
+	}
+	c.info.Errors.Add(err)
+}
+
+func (c *checker) expectType(expr parser.Value, got, expected Type) {
+	if got != TypeUnknown && got != expected {
+		c.addTypeMismatch(expr, expected, got)
+	}
+}
+
+func (c *checker) addTypeMismatch(expr parser.Value, expected, got Type) {
+	span := expr.Span()
+	err := yaperror.NewTypeMismatchError(c.file, span.Start.Line, span.Start.Column, expected.String(), got.String())
+	c.info.Errors.Add(err.WithSpan(c.position(span.Start), c.position(span.End)))
+}
+
+func (c *checker) addUnsupported(expr *parser.BinaryExpr, t Type) {
+	span := expr.Span()
+	err := yaperror.NewInvalidOperationError(
+		c.file, span.Start.Line, span.Start.Column,
+		fmt.Sprintf("operator %s is not supported for %s values", expr.Operator, t),
+	)
+	c.info.Errors.Add(err.WithSpan(c.position(span.Start), c.position(span.End)))
+}
+
+func (c *checker) position(pos source.Position) yaperror.Position {
+	return yaperror.Position{File: c.file, Line: pos.Line, Column: pos.Column}
+}
+
+func isComparison(op string) bool {
+	return lexer.IsComparisonOperator(op)
+}
+
+func isEquality(op string) bool {
+	return op == lexer.ComparisonEqOperator.String() || op == lexer.ComparisonNeOperator.String()
+}
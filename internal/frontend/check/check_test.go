@@ -0,0 +1,222 @@
+package check_test
+
+import (
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	"github.com/rlamalama/YAP/internal/frontend/check"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func checkSource(t *testing.T, src string) *check.Info {
+	t.Helper()
+	prog, err := parser.NewParserFromBytes([]byte(src), "test.yap").Parse()
+	require.NoError(t, err)
+	return check.Check(prog)
+}
+
+func TestCheckInfersTypes(t *testing.T) {
+	info := checkSource(t, "- set:\n  - a: 1 + 2\n  - b: \"x\" + \"y\"\n  - c: a > 2\n")
+
+	require.False(t, info.Errors.HasErrors())
+	require.Equal(t, 3, len(info.Symbols))
+	assert.Equal(t, check.TypeInt, info.Symbols[0].Type)
+	assert.Equal(t, check.TypeString, info.Symbols[1].Type)
+	assert.Equal(t, check.TypeBool, info.Symbols[2].Type)
+}
+
+func TestCheckUndefinedVariable(t *testing.T) {
+	info := checkSource(t, "- print: missing\n")
+
+	require.Equal(t, 1, len(info.Errors.Errors()))
+	err := info.Errors.Errors()[0]
+	assert.Contains(t, err.Message, `undefined variable "missing"`)
+	assert.Equal(t, 1, err.Position.Line)
+	assert.Equal(t, 10, err.Position.Column)
+}
+
+func TestCheckTypeMismatch(t *testing.T) {
+	info := checkSource(t, "- set:\n  - a: \"s\" - 1\n- if: 1\n  then:\n    - print: 1\n")
+
+	require.Equal(t, 2, len(info.Errors.Errors()))
+	assert.Contains(t, info.Errors.Errors()[0].Message, "type mismatch")
+	assert.Contains(t, info.Errors.Errors()[1].Message, "expected bool, got int")
+}
+
+func TestCheckUnsupportedOperator(t *testing.T) {
+	info := checkSource(t, "- print: \"a\" * \"b\"\n")
+
+	require.Equal(t, 1, len(info.Errors.Errors()))
+	assert.Contains(t, info.Errors.Errors()[0].Message, "operator * is not supported for string values")
+}
+
+func TestCheckIfBranchesMerge(t *testing.T) {
+	src := "- if: True\n  then:\n    - set:\n      - v: 1\n  else:\n    - set:\n      - v: \"one\"\n- print: v\n"
+	info := checkSource(t, src)
+
+	require.False(t, info.Errors.HasErrors())
+	value, ok := info.ValueAt(source.Position{Line: 8, Column: 10})
+	require.True(t, ok)
+	assert.Equal(t, check.TypeUnknown, info.Types[value])
+}
+
+func TestCheckAssert(t *testing.T) {
+	info := checkSource(t, "- assert: 1\n- assert: True\n  message: 2\n")
+
+	require.Equal(t, 2, len(info.Errors.Errors()))
+	assert.Contains(t, info.Errors.Errors()[0].Message, "expected bool, got int")
+	assert.Contains(t, info.Errors.Errors()[1].Message, "expected string, got int")
+}
+
+func TestCheckExpectErrorHidesErrors(t *testing.T) {
+	src := "- expect_error:\n  - print: missing\n  - set:\n    - v: 1\n- print: v + 1\n"
+	info := checkSource(t, src)
+
+	require.False(t, info.Errors.HasErrors())
+	value, ok := info.ValueAt(source.Position{Line: 5, Column: 10})
+	require.True(t, ok)
+	assert.Equal(t, check.TypeInt, info.Types[value])
+}
+
+func TestCheckSymbolAt(t *testing.T) {
+	info := checkSource(t, "- set:\n  - count: 1\n- print: count\n")
+
+	// On the reference
+	sym, span, ok := info.SymbolAt(source.Position{Line: 3, Column: 12})
+	require.True(t, ok)
+	assert.Equal(t, "count", sym.Name)
+	assert.Equal(t, 3, span.Start.Line)
+	assert.Equal(t, 2, sym.Span().Start.Line)
+	assert.Equal(t, 5, sym.Span().Start.Column)
+
+	// On the assignment itself
+	sym, _, ok = info.SymbolAt(source.Position{Line: 2, Column: 5})
+	require.True(t, ok)
+	assert.Equal(t, "count", sym.Name)
+
+	_, _, ok = info.SymbolAt(source.Position{Line: 1, Column: 1})
+	assert.False(t, ok)
+}
+
+func TestCheckUndefinedVariableHints(t *testing.T) {
+	info := checkSource(t, "- set:\n  - total: 1\n- print: totl\n- print: true\n- print: xyz\n")
+
+	errs := info.Errors.Errors()
+	require.Equal(t, 3, len(errs))
+	assert.Equal(t, []string{`did you mean "total"?`}, errs[0].Hints)
+	assert.Equal(t, []string{`did you mean "True"? names are case sensitive`}, errs[1].Hints)
+	assert.Empty(t, errs[2].Hints)
+}
+
+func TestCheckImport(t *testing.T) {
+	info := checkSource(t, "- import: \"lib/util.yap\"\n- print: util.answer\n- set:\n  - n: 1\n- print: n.answer\n")
+
+	require.Equal(t, 2, len(info.Symbols))
+	assert.Equal(t, check.TypeModule, info.Symbols[0].Type)
+	assert.Equal(t, 11, info.Symbols[0].Span().Start.Column)
+
+	errs := info.Errors.Errors()
+	require.Equal(t, 1, len(errs))
+	assert.Equal(t, 5, errs[0].Position.Line)
+}
+
+func TestCheckFloatsAndCalls(t *testing.T) {
+	info := checkSource(t, "- import: \"math\"\n- set:\n  - a: 1.5 * 2\n  - b: math.sqrt(a) + 1\n  - c: a > 1\n- print: a + \"x\", math.pow(a, nope)\n")
+
+	assert.Equal(t, check.TypeFloat, info.Symbols[1].Type)
+	assert.Equal(t, check.TypeUnknown, info.Symbols[2].Type)
+	assert.Equal(t, check.TypeBool, info.Symbols[3].Type)
+
+	errs := info.Errors.Errors()
+	require.Equal(t, 2, len(errs))
+	assert.Contains(t, errs[0].Message, "float")
+	assert.Contains(t, errs[1].Message, `undefined variable "nope"`)
+}
+
+func TestCheckConversions(t *testing.T) {
+	info := checkSource(t, "- set:\n  - a: str(1) + \"!\"\n  - b: int(\"4\") * 2\n  - c: bool(\"True\") == True\n- print: a + b, strr(1)\n")
+
+	assert.Equal(t, check.TypeString, info.Symbols[0].Type)
+	assert.Equal(t, check.TypeInt, info.Symbols[1].Type)
+	assert.Equal(t, check.TypeBool, info.Symbols[2].Type)
+
+	errs := info.Errors.Errors()
+	require.Equal(t, 2, len(errs))
+	assert.Contains(t, errs[0].Message, "type mismatch")
+	assert.Equal(t, `undefined function "strr"`, errs[1].Message)
+	assert.Equal(t, []string{`did you mean "str"?`}, errs[1].Hints)
+}
+
+// Calls of globals have the result type their registration names
+func TestCheckGlobalResultTypes(t *testing.T) {
+	for _, name := range stdlib.GlobalNames() {
+		global, _ := stdlib.Global(name)
+		fn, ok := global.(*stdlib.Func)
+		if !ok {
+			continue
+		}
+		info := checkSource(t, "- set:\n  - x: "+name+"()\n")
+		require.Equal(t, 1, len(info.Symbols), name)
+
+		expected := fn.Result
+		if expected == "" {
+			expected = check.TypeUnknown.String()
+		}
+		assert.Equal(t, expected, info.Symbols[0].Type.String(), name)
+	}
+
+	info := checkSource(t, "- print: env(\"HOME\") + 1, json_parse(\"1\") + 1\n")
+	errs := info.Errors.Errors()
+	require.Equal(t, 1, len(errs))
+	assert.Contains(t, errs[0].Message, "type mismatch")
+}
+
+func TestCheckFor(t *testing.T) {
+	info := checkSource(t, "- import: \"fs\"\n- set:\n  - n: 0\n- for: line\n  in: fs.read_lines(\"in.txt\")\n  do:\n    - set:\n      - n: \"x\"\n    - call: fs.append_line(\"out.txt\", line + nn)\n- print: n + 1, line\n")
+
+	loop := info.Symbols[2]
+	assert.Equal(t, "line", loop.Name)
+	assert.Equal(t, check.TypeUnknown, loop.Type)
+	assert.Equal(t, 8, loop.Span().Start.Column)
+
+	// The body may not run, so n may still be an int after the loop
+	errs := info.Errors.Errors()
+	require.Equal(t, 1, len(errs))
+	assert.Equal(t, `undefined variable "nn"`, errs[0].Message)
+	assert.Equal(t, []string{`did you mean "n"?`}, errs[0].Hints)
+}
+
+func TestCheckInput(t *testing.T) {
+	info := checkSource(t, "- input: age\n  prompt: 1\n- print: age + 1, read_all_stdin() + age\n")
+
+	require.Equal(t, 1, len(info.Symbols))
+	assert.Equal(t, check.TypeString, info.Symbols[0].Type)
+	assert.Equal(t, 10, info.Symbols[0].Span().Start.Column)
+
+	errs := info.Errors.Errors()
+	require.Equal(t, 1, len(errs))
+	assert.Contains(t, errs[0].Message, "type mismatch")
+	assert.Equal(t, 3, errs[0].Position.Line)
+}
+
+func TestCheckExitAndEnv(t *testing.T) {
+	info := checkSource(t, "- print: args\n- exit: \"1\"\n- print: env(\"HOME\") + 1, env_or(\"N\", 1) + 1\n- exit: 0\n")
+
+	errs := info.Errors.Errors()
+	require.Equal(t, 2, len(errs))
+	assert.Equal(t, "type mismatch: expected int, got string", errs[0].Message)
+	assert.Equal(t, 2, errs[0].Position.Line)
+	assert.Contains(t, errs[1].Message, "type mismatch")
+	assert.Equal(t, 3, errs[1].Position.Line)
+}
+
+func TestCheckDocuments(t *testing.T) {
+	info := checkSource(t, "- set:\n  - cfg: json_parse(\"{}\")\n- print: cfg.port + 1, yaml_stringify(cfg) + 1\n")
+
+	errs := info.Errors.Errors()
+	require.Equal(t, 1, len(errs))
+	assert.Equal(t, "type mismatch: expected string, got int", errs[0].Message)
+}
@@ -0,0 +1,34 @@
+package check
+
+// Type is the statically inferred type of an expression
+type Type int
+
+const (
+	TypeUnknown Type = iota // The type cannot be determined before running
+	TypeInt
+	TypeFloat
+	TypeString
+	TypeBool
+	TypeModule // The namespace of an imported module
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	case TypeModule:
+		return "module"
+	default:
+		return "unknown"
+	}
+}
+
+func (t Type) isNumber() bool {
+	return t == TypeInt || t == TypeFloat
+}
@@ -0,0 +1,69 @@
+package format
+
+import (
+	"github.com/rlamalama/YAP/internal/frontend/lexer"
+)
+
+// Line is a node of the concrete syntax tree. YAP is line oriented, so every
+// node is one source line holding its tokens, its comment and the lines
+// indented below it. Together with the line numbers this keeps everything
+// needed to re-emit the program, including the comments the parser discards.
+type Line struct {
+	Number   int            // Line number in the source
+	Tokens   []*lexer.Token // Tokens of the line, excluding the comment
+	Comment  *lexer.Token   // Full-line or trailing comment, nil if there is none
+	Children []*Line        // Lines indented below this one
+}
+
+// LastNumber returns the number of the last source line in the subtree of l
+func (l *Line) LastNumber() int {
+	if len(l.Children) == 0 {
+		return l.Number
+	}
+	return l.Children[len(l.Children)-1].LastNumber()
+}
+
+// BuildCST groups a token stream into a tree of lines using its
+// INDENT and DEDENT tokens
+func BuildCST(tokens []*lexer.Token) []*Line {
+	root := &Line{}
+	stack := []*Line{root}
+	var curr *Line
+
+	for _, tok := range tokens {
+		parent := stack[len(stack)-1]
+
+		switch tok.Kind {
+		case lexer.TokenIndent:
+			// Lines after an indent nest below the previous line
+			if len(parent.Children) > 0 {
+				stack = append(stack, parent.Children[len(parent.Children)-1])
+			} else {
+				stack = append(stack, parent)
+			}
+
+		case lexer.TokenDedent:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+
+		case lexer.TokenNewline:
+			curr = nil
+
+		case lexer.TokenEOF:
+
+		default:
+			if curr == nil {
+				curr = &Line{Number: tok.Line}
+				parent.Children = append(parent.Children, curr)
+			}
+			if tok.Kind == lexer.TokenComment {
+				curr.Comment = tok
+			} else {
+				curr.Tokens = append(curr.Tokens, tok)
+			}
+		}
+	}
+
+	return root.Children
+}
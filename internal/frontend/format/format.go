@@ -0,0 +1,91 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/rlamalama/YAP/internal/frontend/lexer"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+)
+
+// IndentWidth is the number of spaces per indentation level
+const IndentWidth = 2
+
+// Source returns the canonical formatting of a YAP program. The program must
+// parse, otherwise the parse error is returned and nothing is formatted.
+//
+// Formatting normalizes indentation to two spaces per level, puts single
+// spaces between tokens (none before ":" and ","), keeps every comment on its
+// line and collapses runs of blank lines into one.
+func Source(src []byte, filename string) ([]byte, error) {
+	if _, err := parser.NewParserFromBytes(src, filename).Parse(); err != nil {
+		return nil, err
+	}
+
+	tokens, err := lexer.NewLexer(bytes.NewReader(src), filename).Lex()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &printer{}
+	p.printLines(BuildCST(tokens), 0)
+	return p.buf.Bytes(), nil
+}
+
+type printer struct {
+	buf      bytes.Buffer
+	lastLine int // last source line written, 0 before the first line
+}
+
+func (p *printer) printLines(lines []*Line, depth int) {
+	for _, line := range lines {
+		// Keep a single blank line where the source had one or more
+		if p.lastLine > 0 && line.Number > p.lastLine+1 {
+			p.buf.WriteString("\n")
+		}
+
+		p.buf.WriteString(strings.Repeat(" ", depth*IndentWidth))
+		p.buf.WriteString(formatTokens(line.Tokens))
+		if line.Comment != nil {
+			if len(line.Tokens) > 0 {
+				p.buf.WriteString("  ")
+			}
+			p.buf.WriteString(line.Comment.Value)
+		}
+		p.buf.WriteString("\n")
+		p.lastLine = line.Number
+
+		p.printLines(line.Children, depth+1)
+	}
+}
+
+// formatTokens joins the tokens of a line with canonical spacing
+func formatTokens(tokens []*lexer.Token) string {
+	var sb strings.Builder
+	for i, tok := range tokens {
+		if i > 0 && spaceBetween(tokens[i-1], tok) {
+			sb.WriteString(" ")
+		}
+		if tok.Kind == lexer.TokenString {
+			sb.WriteString(`"` + tok.Value + `"`)
+		} else {
+			sb.WriteString(tok.Value)
+		}
+	}
+	return sb.String()
+}
+
+// spaceBetween reports whether formatted source separates tokens prev and tok
+// with a space. Selectors and calls are written without any, e.g. math.pow(x, 2)
+func spaceBetween(prev, tok *lexer.Token) bool {
+	switch {
+	case tok.Kind == lexer.TokenColon, tok.Kind == lexer.TokenComma:
+		return false
+	case tok.Kind == lexer.TokenDot, prev.Kind == lexer.TokenDot:
+		return false
+	case tok.Kind == lexer.TokenLParen, prev.Kind == lexer.TokenLParen, tok.Kind == lexer.TokenRParen:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,158 @@
+package format_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/frontend/format"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFileDir = "../../.."
+
+func TestFormatNormalizesSpacing(t *testing.T) {
+	src := `-print:"a"
+-    print    :      x+1
+- set:
+    -  y:   x*2  >=  3
+`
+	expected := `- print: "a"
+- print: x + 1
+- set:
+  - y: x * 2 >= 3
+`
+	out, err := format.Source([]byte(src), "spacing.yap")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+}
+
+func TestFormatNormalizesIndentation(t *testing.T) {
+	src := `- if: x > 5
+    then:
+          - print: "big"
+          - if: x < 20
+               then:
+                  - print: "not that big"
+    else:
+          - print: "small"
+`
+	expected := `- if: x > 5
+  then:
+    - print: "big"
+    - if: x < 20
+      then:
+        - print: "not that big"
+  else:
+    - print: "small"
+`
+	out, err := format.Source([]byte(src), "indent.yap")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+}
+
+func TestFormatKeepsComments(t *testing.T) {
+	src := `// header comment
+- set:
+    - x: 10   // trailing
+    // - y: 5
+- print: x //inline
+`
+	expected := `// header comment
+- set:
+  - x: 10  // trailing
+  // - y: 5
+- print: x  //inline
+`
+	out, err := format.Source([]byte(src), "comments.yap")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+}
+
+func TestFormatCollapsesBlankLines(t *testing.T) {
+	src := "\n\n- print: 1\n\n\n\n- print: 2\n- print: 3\n\n\n"
+	expected := "- print: 1\n\n- print: 2\n- print: 3\n"
+
+	out, err := format.Source([]byte(src), "blank.yap")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+}
+
+func TestFormatPrintOptions(t *testing.T) {
+	src := "- print: 1 ,2,3\n      sep:\", \"\n      no_newline:True\n"
+	expected := "- print: 1, 2, 3\n  sep: \", \"\n  no_newline: True\n"
+
+	out, err := format.Source([]byte(src), "options.yap")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+}
+
+func TestFormatImport(t *testing.T) {
+	src := "- import:\"lib/util.yap\"\n    as:   u\n- print: u . answer\n"
+	expected := "- import: \"lib/util.yap\"\n  as: u\n- print: u.answer\n"
+
+	out, err := format.Source([]byte(src), "import.yap")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+}
+
+func TestFormatCall(t *testing.T) {
+	src := "- print: math.pow ( x ,2.5 )+math.abs( 1 ), f( )\n"
+	expected := "- print: math.pow(x, 2.5) + math.abs(1), f()\n"
+
+	out, err := format.Source([]byte(src), "call.yap")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+}
+
+func TestFormatEmptyFile(t *testing.T) {
+	out, err := format.Source([]byte{}, "empty.yap")
+	require.NoError(t, err)
+	assert.Equal(t, "", string(out))
+}
+
+func TestFormatInvalidProgram(t *testing.T) {
+	_, err := format.Source([]byte("- then:\n  - print: 1\n"), "invalid.yap")
+	assert.NotNil(t, err)
+}
+
+// Formatting must be idempotent and keep every comment of the test programs
+func TestFormatTestFilesIdempotent(t *testing.T) {
+	files := []string{
+		test_util.OneLinePrintYAP,
+		test_util.MultiLinePrintYAP,
+		test_util.SetPrintYAP,
+		test_util.SetPrintBinaryExpYAP,
+		test_util.BooleanComparisonYAP,
+		test_util.CommentsIgnoredYAP,
+		test_util.CommentsIgnoreInBlockYAP,
+		test_util.IfThenElseYAP,
+		test_util.EmptyElseYAP,
+		test_util.EmptyThenYAP,
+		test_util.PrintMultipleYAP,
+	}
+
+	for _, name := range files {
+		src, err := os.ReadFile(test_util.GetTestFilepath(name, testFileDir))
+		require.NoError(t, err)
+
+		once, err := format.Source(src, name)
+		require.NoError(t, err, name)
+		twice, err := format.Source(once, name)
+		require.NoError(t, err, name)
+
+		assert.Equal(t, string(once), string(twice), name)
+		assert.Equal(t, strings.Count(string(src), "//"), strings.Count(string(once), "//"), name)
+	}
+}
+
+func TestFormatFor(t *testing.T) {
+	src := "- for:   line\n    in: fs.read_lines( \"in.txt\" )\n    do:\n       - call: fs.append_line(\"out.txt\",line)\n"
+	expected := "- for: line\n  in: fs.read_lines(\"in.txt\")\n  do:\n    - call: fs.append_line(\"out.txt\", line)\n"
+
+	out, err := format.Source([]byte(src), "for.yap")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+}
@@ -10,6 +10,19 @@ const (
 	KeywordIf    = "if"
 	KeywordThen  = "then"
 	KeywordElse  = "else"
+
+	KeywordAssert      = "assert"
+	KeywordExpectError = "expect_error"
+
+	KeywordImport = "import"
+
+	KeywordFor = "for"
+	KeywordIn  = "in"
+	KeywordDo  = "do"
+
+	KeywordCall  = "call"
+	KeywordInput = "input"
+	KeywordExit  = "exit"
 )
 
 var Keywords = []Keyword{
@@ -20,6 +33,15 @@ var Keywords = []Keyword{
 	KeywordIf,
 	KeywordThen,
 	KeywordElse,
+	KeywordAssert,
+	KeywordExpectError,
+	KeywordImport,
+	KeywordFor,
+	KeywordIn,
+	KeywordDo,
+	KeywordCall,
+	KeywordInput,
+	KeywordExit,
 }
 
 func IsKeyword(s string) bool {
@@ -47,7 +47,7 @@ func (l *Lexer) Lex() ([]*Token, error) {
 	for numIndent > 1 {
 		l.indentStack.Pop()
 		numIndent = l.indentStack.Length()
-		l.emit(TokenDedent, "", l.scanner.line, 0)
+		l.emit(TokenDedent, "", l.scanner.line, 1)
 	}
 
 	return l.tokens, nil
@@ -63,30 +63,22 @@ func (l *Lexer) handleIndent(indent int) error {
 
 	if indent > prevIndent {
 		l.indentStack.Push(indent)
-		l.emit(TokenIndent, "", currLine, indent)
+		l.emit(TokenIndent, "", currLine, indent+1)
 		return nil
 	}
 
-	if indent < prevIndent {
-		for {
-			prevIndent, ok = l.indentStack.Peek()
-			if !ok {
-				return yaperror.NewInvalidIndentError(l.filename, currLine, 1, indent, prevIndent)
-			}
-			if indent == prevIndent {
-				break
-			} else if indent < prevIndent {
-				l.emit(TokenDedent, "", currLine, indent)
-				prevIndent, _ = l.indentStack.Pop()
-			}
-			if l.indentStack.Length() == 0 {
-				return yaperror.NewInvalidIndentError(l.filename, currLine, 1, indent, prevIndent)
-			}
-		}
-		if indent != prevIndent {
-			return yaperror.NewInvalidIndentError(l.filename, currLine, 1, indent, prevIndent)
+	// Close blocks until the indentation matches an enclosing one
+	for indent < prevIndent {
+		l.indentStack.Pop()
+		l.emit(TokenDedent, "", currLine, indent+1)
+		if prevIndent, ok = l.indentStack.Peek(); !ok {
+			return yaperror.NewInvalidIndentError(l.filename, currLine, 1, indent, 0)
 		}
 	}
+	if indent != prevIndent {
+		// Dedented between two levels, e.g. to 2 from 4 when the enclosing block is at 0
+		return yaperror.NewInvalidIndentError(l.filename, currLine, 1, indent, prevIndent)
+	}
 
 	return nil
 }
@@ -118,6 +110,25 @@ lineLoop:
 			i++
 			col++
 
+		case isComma(line[i]):
+			l.emit(TokenComma, ",", l.scanner.line, col)
+			i++
+			col++
+
+		case isDot(line[i]):
+			l.emit(TokenDot, ".", l.scanner.line, col)
+			i++
+			col++
+
+		case isParen(line[i]):
+			tk := TokenLParen
+			if line[i] == ')' {
+				tk = TokenRParen
+			}
+			l.emit(tk, string(line[i]), l.scanner.line, col)
+			i++
+			col++
+
 		// Keyword or Identifier
 		case isAlpha(line[i]):
 			start := i
@@ -155,10 +166,18 @@ lineLoop:
 			for i < len(line) && isNum(line[i]) {
 				i++
 			}
+			// A fraction makes it a float, e.g. 1.5
+			if i+1 < len(line) && isDot(line[i]) && isNum(line[i+1]) {
+				i++
+				for i < len(line) && isNum(line[i]) {
+					i++
+				}
+			}
 			l.emit(TokenNumerical, line[start:i], l.scanner.line, col)
 			col += i - start
 		case isComment(line[i]) && i < len(line)-1 && isComment(line[i+1]):
-			l.emit(TokenComment, "", l.scanner.line, col)
+			// Keep the comment text so tools like the formatter can re-emit it
+			l.emit(TokenComment, strings.TrimRight(line[i:], " \t\r"), l.scanner.line, col)
 			break lineLoop
 		case StartsWithOperator(line[i]):
 			// Check for two-character comparison operators first
@@ -230,6 +249,18 @@ func isColon(c byte) bool {
 	return c == ':'
 }
 
+func isComma(c byte) bool {
+	return c == ','
+}
+
+func isDot(c byte) bool {
+	return c == '.'
+}
+
+func isParen(c byte) bool {
+	return c == '(' || c == ')'
+}
+
 func isQuote(c byte) bool {
 	return c == '"'
 }
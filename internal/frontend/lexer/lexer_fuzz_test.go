@@ -0,0 +1,29 @@
+package lexer_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/frontend/lexer"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/require"
+)
+
+const fuzzFile = "fuzz.yap"
+
+// Lexing any input must not panic, and must either produce tokens with
+// positions in the input or a positioned error
+func FuzzLex(f *testing.F) {
+	test_util.AddFuzzSeeds(f, testFileDirPrefix)
+	f.Fuzz(func(t *testing.T, src []byte) {
+		tokens, err := lexer.NewLexer(bytes.NewReader(src), fuzzFile).Lex()
+		if err != nil {
+			test_util.RequireYapError(t, err, fuzzFile)
+			return
+		}
+		for _, tok := range tokens {
+			require.GreaterOrEqual(t, tok.Line, 1, "%s token has no line", tok.Kind)
+			require.GreaterOrEqual(t, tok.Col, 1, "%s token has no column", tok.Kind)
+		}
+	})
+}
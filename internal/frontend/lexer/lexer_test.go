@@ -380,11 +380,19 @@ func TestLexCommentsIgnored(t *testing.T) {
 		}
 	}
 
-	// Verify that no comment content appears as tokens
+	// Verify that no comment content appears as other tokens
+	comments := []string{}
 	for _, tok := range toks {
+		if tok.Kind == lexer.TokenComment {
+			comments = append(comments, tok.Value)
+			continue
+		}
 		assert.NotEqual(t, "world", tok.Value, "comment content 'world' should not appear as a token")
 		assert.NotContains(t, tok.Value, "//", "comment marker should not appear in any token value")
 	}
+
+	// Comment tokens keep their text, including the marker
+	assert.Equal(t, []string{`//print: "hello"`, `// print: "hello"`, "// world", "// + y"}, comments)
 }
 
 // Test file content:
@@ -571,3 +579,95 @@ func TestLexIfThenElse(t *testing.T) {
 		}
 	}
 }
+
+func TestLexComma(t *testing.T) {
+	lex := lexer.NewLexer(strings.NewReader(`- print: "a", x,1`), "comma.yap")
+	toks, err := lex.Lex()
+	assert.Nil(t, err)
+
+	expectedTok := []lexer.Token{
+		{Kind: lexer.TokenDash, Value: "-", Col: 1},
+		{Kind: lexer.TokenKeyword, Value: lexer.KeywordPrint, Col: 3},
+		{Kind: lexer.TokenColon, Value: ":", Col: 8},
+		{Kind: lexer.TokenString, Value: "a", Col: 10},
+		{Kind: lexer.TokenComma, Value: ",", Col: 13},
+		{Kind: lexer.TokenIdentifier, Value: "x", Col: 15},
+		{Kind: lexer.TokenComma, Value: ",", Col: 16},
+		{Kind: lexer.TokenNumerical, Value: "1", Col: 17},
+		{Kind: lexer.TokenNewline, Value: "", Col: 18},
+	}
+
+	assert.Equal(t, len(expectedTok), len(toks), "token count mismatch")
+	for i, tok := range toks {
+		assert.Equal(t, expectedTok[i].Kind.String(), tok.Kind.String(), "token kind mismatch at %d", i)
+		assert.Equal(t, expectedTok[i].Value, tok.Value, "token value mismatch at %d", i)
+		assert.Equal(t, expectedTok[i].Col, tok.Col, "token col mismatch at %d", i)
+	}
+}
+
+func TestLexImportAndDot(t *testing.T) {
+	lex := lexer.NewLexer(strings.NewReader("- import: \"lib/util.yap\"\n- print: util.x\n"), "import.yap")
+	toks, err := lex.Lex()
+	assert.Nil(t, err)
+
+	expectedTok := []lexer.Token{
+		{Kind: lexer.TokenDash, Value: "-", Col: 1},
+		{Kind: lexer.TokenKeyword, Value: lexer.KeywordImport, Col: 3},
+		{Kind: lexer.TokenColon, Value: ":", Col: 9},
+		{Kind: lexer.TokenString, Value: "lib/util.yap", Col: 11},
+		{Kind: lexer.TokenNewline, Value: "", Col: 25},
+		{Kind: lexer.TokenDash, Value: "-", Col: 1},
+		{Kind: lexer.TokenKeyword, Value: lexer.KeywordPrint, Col: 3},
+		{Kind: lexer.TokenColon, Value: ":", Col: 8},
+		{Kind: lexer.TokenIdentifier, Value: "util", Col: 10},
+		{Kind: lexer.TokenDot, Value: ".", Col: 14},
+		{Kind: lexer.TokenIdentifier, Value: "x", Col: 15},
+		{Kind: lexer.TokenNewline, Value: "", Col: 16},
+	}
+
+	assert.Equal(t, len(expectedTok), len(toks), "token count mismatch")
+	for i, tok := range toks {
+		assert.Equal(t, expectedTok[i].Kind.String(), tok.Kind.String(), "token kind mismatch at %d", i)
+		assert.Equal(t, expectedTok[i].Value, tok.Value, "token value mismatch at %d", i)
+		assert.Equal(t, expectedTok[i].Col, tok.Col, "token col mismatch at %d", i)
+	}
+}
+
+// Dedenting to a level that no enclosing block uses is an error, found by
+// FuzzParse when it used to loop forever
+func TestLexDedentBetweenLevels(t *testing.T) {
+	src := "- if: True\n    then:\n      - print: 1\n  - print: 2\n"
+	lex := lexer.NewLexer(strings.NewReader(src), "dedent.yap")
+	_, err := lex.Lex()
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "dedent.yap:4:1")
+}
+
+func TestLexCallAndFloat(t *testing.T) {
+	lex := lexer.NewLexer(strings.NewReader("- print: math.pow(2, 0.5)\n"), "call.yap")
+	toks, err := lex.Lex()
+	assert.Nil(t, err)
+
+	expectedTok := []lexer.Token{
+		{Kind: lexer.TokenDash, Value: "-", Col: 1},
+		{Kind: lexer.TokenKeyword, Value: lexer.KeywordPrint, Col: 3},
+		{Kind: lexer.TokenColon, Value: ":", Col: 8},
+		{Kind: lexer.TokenIdentifier, Value: "math", Col: 10},
+		{Kind: lexer.TokenDot, Value: ".", Col: 14},
+		{Kind: lexer.TokenIdentifier, Value: "pow", Col: 15},
+		{Kind: lexer.TokenLParen, Value: "(", Col: 18},
+		{Kind: lexer.TokenNumerical, Value: "2", Col: 19},
+		{Kind: lexer.TokenComma, Value: ",", Col: 20},
+		{Kind: lexer.TokenNumerical, Value: "0.5", Col: 22},
+		{Kind: lexer.TokenRParen, Value: ")", Col: 25},
+		{Kind: lexer.TokenNewline, Value: "", Col: 26},
+	}
+
+	assert.Equal(t, len(expectedTok), len(toks), "token count mismatch")
+	for i, tok := range toks {
+		assert.Equal(t, expectedTok[i].Kind.String(), tok.Kind.String(), "token kind mismatch at %d", i)
+		assert.Equal(t, expectedTok[i].Value, tok.Value, "token value mismatch at %d", i)
+		assert.Equal(t, expectedTok[i].Col, tok.Col, "token col mismatch at %d", i)
+	}
+}
@@ -15,6 +15,10 @@ const (
 	TokenDedent
 	TokenNewline
 	TokenComment
+	TokenComma
+	TokenDot
+	TokenLParen
+	TokenRParen
 	TokenEOF
 )
 
@@ -32,6 +36,10 @@ func (tk TokenKind) String() string {
 		"Dedent",
 		"Newline",
 		"Comment",
+		"Comma",
+		"Dot",
+		"LParen",
+		"RParen",
 		"EOF",
 	}
 
@@ -1,7 +1,10 @@
 package parser
 
+import "github.com/rlamalama/YAP/internal/frontend/source"
+
 type Program struct {
 	Statements []Stmt
+	File       *source.File // The parsed source
 }
 
 type StmtType int
@@ -11,33 +14,48 @@ const (
 	StmtTypePrint
 	StmtTypeSet
 	StmtTypeIf
+	StmtTypeAssert
+	StmtTypeExpectError
+	StmtTypeImport
+	StmtTypeFor
+	StmtTypeCall
+	StmtTypeInput
+	StmtTypeExit
 )
 
 // Stmt is the interface for all statements
 type Stmt interface {
 	stmt()
 	Type() StmtType
-	// Span() source.Span
+	Span() source.Span // Span of the statement's first line, from the dash
 }
 
 type PrintStmt struct {
-	Expr Value
+	Expr      Value // A single value, or an *ExprList when printing several
+	Sep       Value // Separator between values, nil for a single space
+	NoNewline bool  // Omit the trailing newline
+	Stderr    bool  // Print to the error output instead of the standard output
+	Loc       source.Span
 }
 
-func (PrintStmt) stmt()          {}
-func (PrintStmt) Type() StmtType { return StmtTypePrint }
+func (PrintStmt) stmt()               {}
+func (PrintStmt) Type() StmtType      { return StmtTypePrint }
+func (s PrintStmt) Span() source.Span { return s.Loc }
 
 type SetStmt struct {
 	Assignment []*Assignment
+	Loc        source.Span
 }
 
-func (s SetStmt) Type() StmtType { return StmtTypeSet }
+func (s SetStmt) Type() StmtType    { return StmtTypeSet }
+func (s SetStmt) Span() source.Span { return s.Loc }
 
 func (SetStmt) stmt() {}
 
 type Assignment struct {
 	Name string
 	Expr Value
+	Loc  source.Span // Span of the assigned name
 }
 
 // IfStmt represents an if-then-else statement
@@ -45,7 +63,93 @@ type IfStmt struct {
 	Condition Value  // The conditional expression
 	Then      []Stmt // Statements to execute if condition is true
 	Else      []Stmt // Statements to execute if condition is false (can be nil)
+	Loc       source.Span
+}
+
+func (IfStmt) stmt()               {}
+func (IfStmt) Type() StmtType      { return StmtTypeIf }
+func (s IfStmt) Span() source.Span { return s.Loc }
+
+// AssertStmt fails the program when its condition is false
+type AssertStmt struct {
+	Condition Value // Must evaluate to a bool
+	Message   Value // Reported when the assertion fails, nil for the condition itself
+	Loc       source.Span
+}
+
+func (AssertStmt) stmt()               {}
+func (AssertStmt) Type() StmtType      { return StmtTypeAssert }
+func (s AssertStmt) Span() source.Span { return s.Loc }
+
+// ExpectErrorStmt runs a block that must fail with a runtime error. The
+// error is recovered and the program continues after the block
+type ExpectErrorStmt struct {
+	Body []Stmt
+	Loc  source.Span
+}
+
+func (ExpectErrorStmt) stmt()               {}
+func (ExpectErrorStmt) Type() StmtType      { return StmtTypeExpectError }
+func (s ExpectErrorStmt) Span() source.Span { return s.Loc }
+
+// ImportStmt runs another .yap file once and makes its top-level variables
+// available under a namespace, e.g. util.name
+type ImportStmt struct {
+	Path    string      // As written, resolved relative to the importing file
+	PathLoc source.Span // Span of the path string
+	Alias   string      // Namespace of the module, from the file name unless set with as
+	Loc     source.Span
+}
+
+func (ImportStmt) stmt()               {}
+func (ImportStmt) Type() StmtType      { return StmtTypeImport }
+func (s ImportStmt) Span() source.Span { return s.Loc }
+
+// ForStmt runs its body once for every item of a list, e.g. the lines of a
+// file read with fs.read_lines
+type ForStmt struct {
+	Var    string      // Assigned the current item before each run of the body
+	VarLoc source.Span // Span of the variable name
+	List   Value       // Must evaluate to a list
+	Body   []Stmt
+	Loc    source.Span
+}
+
+func (ForStmt) stmt()               {}
+func (ForStmt) Type() StmtType      { return StmtTypeFor }
+func (s ForStmt) Span() source.Span { return s.Loc }
+
+// CallStmt calls a function for its effect and discards the result, e.g.
+// fs.write_file(path, text)
+type CallStmt struct {
+	Call *CallExpr
+	Loc  source.Span
+}
+
+func (CallStmt) stmt()               {}
+func (CallStmt) Type() StmtType      { return StmtTypeCall }
+func (s CallStmt) Span() source.Span { return s.Loc }
+
+// InputStmt reads a line of input into a variable, after writing an optional
+// prompt
+type InputStmt struct {
+	Name    string      // Assigned the line, without its line ending
+	NameLoc source.Span // Span of the variable name
+	Prompt  Value       // Written before reading, nil for none
+	Loc     source.Span
+}
+
+func (InputStmt) stmt()               {}
+func (InputStmt) Type() StmtType      { return StmtTypeInput }
+func (s InputStmt) Span() source.Span { return s.Loc }
+
+// ExitStmt ends the program with an exit code, e.g. 1 to report a failure to
+// the shell that ran it
+type ExitStmt struct {
+	Code Value // Must evaluate to an int from 0 to 255
+	Loc  source.Span
 }
 
-func (IfStmt) stmt()          {}
-func (IfStmt) Type() StmtType { return StmtTypeIf }
+func (ExitStmt) stmt()               {}
+func (ExitStmt) Type() StmtType      { return StmtTypeExit }
+func (s ExitStmt) Span() source.Span { return s.Loc }
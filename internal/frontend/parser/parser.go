@@ -1,16 +1,43 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
 
 	yaperror "github.com/rlamalama/YAP/internal/error"
 	"github.com/rlamalama/YAP/internal/frontend/lexer"
+	"github.com/rlamalama/YAP/internal/frontend/source"
+	"github.com/rlamalama/YAP/internal/frontend/suggest"
+)
+
+// Settings accepted in the indented block below a print statement
+const (
+	PrintOptionSep       = "sep"
+	PrintOptionNoNewline = "no_newline"
+	PrintOptionStderr    = "stderr"
+)
+
+// Settings accepted in the indented block below an assert statement
+const (
+	AssertOptionMessage = "message"
+
+	// Option of the import statement
+	ImportOptionAs = "as"
+
+	// Option of the input statement
+	InputOptionPrompt = "prompt"
 )
 
 type Parser struct {
 	filename string
+	src      []byte    // Source text, nil to read r or filename
+	r        io.Reader // Source to read, nil to read filename from disk
+	file     *source.File
 	tokens   []*lexer.Token
 	pos      int
 }
@@ -23,14 +50,40 @@ func NewParser(file string) *Parser {
 	}
 }
 
+// NewParserFromReader creates a parser that reads the source text from r.
+// filename is only used to report positions
+func NewParserFromReader(r io.Reader, filename string) *Parser {
+	p := NewParser(filename)
+	p.r = r
+	return p
+}
+
+// NewParserFromBytes creates a parser for source text that is already in
+// memory. filename is only used to report positions
+func NewParserFromBytes(src []byte, filename string) *Parser {
+	p := NewParser(filename)
+	p.src = src
+	return p
+}
+
 func (p *Parser) peek() *lexer.Token {
 	if p.pos >= len(p.tokens) {
-		return &lexer.Token{Kind: lexer.TokenEOF}
-
+		return p.eof()
 	}
 	return p.tokens[p.pos]
 }
 
+// eof returns the token past the end of the input. It is positioned at the
+// last token so errors about a truncated program point into the source
+func (p *Parser) eof() *lexer.Token {
+	line, col := 1, 1
+	if n := len(p.tokens); n > 0 {
+		last := p.tokens[n-1]
+		line, col = last.Line, last.Col
+	}
+	return &lexer.Token{Kind: lexer.TokenEOF, Line: line, Col: col}
+}
+
 func (p *Parser) next() *lexer.Token {
 	tok := p.peek()
 	p.pos++
@@ -44,33 +97,123 @@ func (p *Parser) expect(kind lexer.TokenKind) (*lexer.Token, error) {
 	}
 
 	if tok.Kind != kind {
-		return tok, yaperror.NewUnexpectedTokenError(
+		err := yaperror.NewUnexpectedTokenError(
 			p.filename, tok.Line, tok.Col,
 			tok.Kind.String(), kind.String(),
 		)
+		if kind == lexer.TokenKeyword && tok.Kind == lexer.TokenIdentifier {
+			// Most likely a misspelled keyword
+			withHint(err, tok.Value, keywordNames())
+		}
+		return tok, err
 	}
 	return tok, nil
 }
 
+// statementKeywords are the keywords that start a statement
+var statementKeywords = []string{
+	lexer.KeywordPrint,
+	lexer.KeywordSet,
+	lexer.KeywordIf,
+	lexer.KeywordAssert,
+	lexer.KeywordExpectError,
+	lexer.KeywordImport,
+	lexer.KeywordFor,
+	lexer.KeywordCall,
+	lexer.KeywordInput,
+	lexer.KeywordExit,
+}
+
+func keywordNames() []string {
+	names := make([]string, len(lexer.Keywords))
+	for i, key := range lexer.Keywords {
+		names[i] = string(key)
+	}
+	return names
+}
+
+// withHint adds a "did you mean" hint to err if name looks like a misspelling
+// of one of candidates
+func withHint(err *yaperror.YapError, name string, candidates []string) *yaperror.YapError {
+	if hint := suggest.Hint(name, candidates); hint != "" {
+		err.AddHint(hint)
+	}
+	return err
+}
+
 func (p *Parser) Parse() (*Program, error) {
-	file, err := os.Open(p.filename)
-	if err != nil {
-		return nil, err
+	src := p.src
+	if src == nil {
+		var text []byte
+		var err error
+		if p.r != nil {
+			text, err = io.ReadAll(p.r)
+		} else {
+			text, err = os.ReadFile(p.filename)
+		}
+		if err != nil {
+			return nil, err
+		}
+		src = text
 	}
-	defer file.Close()
+	p.file = source.NewFile(p.filename, src)
 
-	lexer := lexer.NewLexer(file, p.filename)
+	lexer := lexer.NewLexer(bytes.NewReader(src), p.filename)
 
+	var err error
 	p.tokens, err = lexer.Lex()
 	if err != nil {
-		return nil, err
+		return nil, p.withContext(err)
 	}
 
-	return p.parseProgram()
+	prog, err := p.parseProgram()
+	if err != nil {
+		return nil, p.withContext(err)
+	}
+	return prog, nil
+}
+
+// withContext adds the source line err points at, so it can be shown below
+// the error
+func (p *Parser) withContext(err error) error {
+	var yerr *yaperror.YapError
+	if errors.As(err, &yerr) && yerr.Context == "" {
+		if line, ok := p.file.Line(yerr.Position.Line); ok {
+			yerr.WithContext(line)
+		}
+	}
+	return err
+}
+
+// spanOf returns the source span covered by tok
+func (p *Parser) spanOf(tok *lexer.Token) source.Span {
+	length := len(tok.Value)
+	if tok.Kind == lexer.TokenString {
+		length += 2 // quotes
+	}
+	return source.Span{
+		File:  p.file,
+		Start: source.Position{Line: tok.Line, Column: tok.Col},
+		End:   source.Position{Line: tok.Line, Column: tok.Col + length},
+	}
+}
+
+// lineSpan returns the span from the token at index start to the end of
+// its line, excluding any trailing comment
+func (p *Parser) lineSpan(start int) source.Span {
+	span := p.spanOf(p.tokens[start])
+	for i := start; i < len(p.tokens); i++ {
+		tok := p.tokens[i]
+		if tok.Kind == lexer.TokenNewline || tok.Kind == lexer.TokenComment {
+			break
+		}
+		span.End = p.spanOf(tok).End
+	}
+	return span
 }
 
 func (p *Parser) parseProgram() (*Program, error) {
-	prog := &Program{}
+	prog := &Program{File: p.file}
 
 	if len(p.tokens) == 0 {
 		return prog, nil
@@ -104,9 +247,11 @@ func (p *Parser) parseProgram() (*Program, error) {
 }
 
 func (p *Parser) parseStmt() (Stmt, error) {
+	start := p.pos
 	if _, err := p.expect(lexer.TokenDash); err != nil {
 		return nil, err
 	}
+	span := p.lineSpan(start)
 
 	key, err := p.expect(lexer.TokenKeyword)
 	if err != nil {
@@ -119,15 +264,30 @@ func (p *Parser) parseStmt() (Stmt, error) {
 
 	switch key.Value {
 	case lexer.KeywordPrint:
-		return p.parsePrint()
+		return p.parsePrint(span)
 	case lexer.KeywordSet:
-		return p.parseSet()
+		return p.parseSet(span)
 	case lexer.KeywordIf:
-		return p.parseIf()
+		return p.parseIf(span)
+	case lexer.KeywordAssert:
+		return p.parseAssert(span)
+	case lexer.KeywordExpectError:
+		return p.parseExpectError(span)
+	case lexer.KeywordImport:
+		return p.parseImport(span)
+	case lexer.KeywordFor:
+		return p.parseFor(span)
+	case lexer.KeywordCall:
+		return p.parseCallStmt(span)
+	case lexer.KeywordInput:
+		return p.parseInput(span)
+	case lexer.KeywordExit:
+		return p.parseExit(span)
 	default:
-		return nil, yaperror.NewUnknownStatementError(
+		err := yaperror.NewUnknownStatementError(
 			p.filename, key.Line, key.Col, key.Value,
 		)
+		return nil, withHint(err, key.Value, statementKeywords)
 	}
 }
 
@@ -140,49 +300,99 @@ func (p *Parser) parseValue() (Value, error) {
 	switch p.peek().Kind {
 	case lexer.TokenIdentifier:
 		tok := p.next()
-		return &Identifier{Name: tok.Value}, nil
+		var val Value = &Identifier{Name: tok.Value, Loc: p.spanOf(tok)}
+		for {
+			switch p.peek().Kind {
+			case lexer.TokenDot:
+				p.next()
+				name, err := p.expect(lexer.TokenIdentifier)
+				if err != nil {
+					return nil, err
+				}
+				span := val.Span()
+				span.End = p.spanOf(name).End
+				val = &SelectorExpr{X: val, Name: name.Value, Loc: span}
+
+			case lexer.TokenLParen:
+				call, err := p.parseCall(val)
+				if err != nil {
+					return nil, err
+				}
+				val = call
+
+			default:
+				return val, nil
+			}
+		}
 
 	case lexer.TokenString:
 		tok := p.next()
-		return &StringLiteral{Value: tok.Value}, nil
+		return &StringLiteral{Value: tok.Value, Loc: p.spanOf(tok)}, nil
 
 	case lexer.TokenNumerical:
 		tok := p.next()
+		if strings.Contains(tok.Value, ".") {
+			num, err := strconv.ParseFloat(tok.Value, 64)
+			if err != nil {
+				return nil, yaperror.NewInvalidNumberError(p.filename, tok.Line, tok.Col, tok.Value)
+			}
+			return &FloatLiteral{Value: num, Loc: p.spanOf(tok)}, nil
+		}
 		num, err := strconv.Atoi(tok.Value)
 		if err != nil {
-			return nil, err
+			return nil, yaperror.NewInvalidNumberError(p.filename, tok.Line, tok.Col, tok.Value)
 		}
-		return &NumericLiteral{Value: num}, nil
+		return &NumericLiteral{Value: num, Loc: p.spanOf(tok)}, nil
 
 	case lexer.TokenKeyword:
 		tok := p.peek()
 		// Handle boolean literals
 		if tok.Value == lexer.KeywordTrue {
 			p.next()
-			return &BooleanLiteral{Value: true}, nil
+			return &BooleanLiteral{Value: true, Loc: p.spanOf(tok)}, nil
 		}
 		if tok.Value == lexer.KeywordFalse {
 			p.next()
-			return &BooleanLiteral{Value: false}, nil
+			return &BooleanLiteral{Value: false, Loc: p.spanOf(tok)}, nil
 		}
-		return nil, fmt.Errorf(
-			"unexpected keyword %q at line %d, expected value",
-			tok.Value,
-			tok.Line,
+		return nil, yaperror.NewUnexpectedTokenError(
+			p.filename, tok.Line, tok.Col,
+			tok.Value, "value",
 		)
 
 	default:
 		tok := p.peek()
-		return nil, fmt.Errorf(
-			"expected value, got %v at line %d",
-			tok.Kind,
-			tok.Line,
+		return nil, yaperror.NewUnexpectedTokenError(
+			p.filename, tok.Line, tok.Col,
+			tok.Kind.String(), "value",
 		)
 	}
 }
 
-func (p *Parser) parsePrint() (Stmt, error) {
-	expr, err := p.parseExpr()
+// parseCall parses the parenthesized arguments of a call to fun
+func (p *Parser) parseCall(fun Value) (Value, error) {
+	p.next() // consume (
+	call := &CallExpr{Fun: fun, Args: []Value{}}
+	for p.peek().Kind != lexer.TokenRParen {
+		if len(call.Args) > 0 {
+			if _, err := p.expect(lexer.TokenComma); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+	}
+	rparen := p.next()
+	call.Loc = fun.Span()
+	call.Loc.End = p.spanOf(rparen).End
+	return call, nil
+}
+
+func (p *Parser) parsePrint(span source.Span) (Stmt, error) {
+	expr, err := p.parseExprList()
 	if err != nil {
 		return nil, err
 	}
@@ -196,9 +406,127 @@ func (p *Parser) parsePrint() (Stmt, error) {
 		return nil, err
 	}
 
-	return PrintStmt{
+	stmt := PrintStmt{
 		Expr: expr,
-	}, nil
+		Loc:  span,
+	}
+
+	// Optional indented settings, e.g. "sep:" or "no_newline:"
+	if p.peek().Kind == lexer.TokenIndent {
+		if err := p.parsePrintOptions(&stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	return stmt, nil
+}
+
+// parseExprList parses one or more comma separated expressions. A single
+// expression is returned as is, several are wrapped in an ExprList
+func (p *Parser) parseExprList() (Value, error) {
+	first, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().Kind != lexer.TokenComma {
+		return first, nil
+	}
+
+	list := &ExprList{Values: []Value{first}}
+	for p.peek().Kind == lexer.TokenComma {
+		p.next() // consume comma
+
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list.Values = append(list.Values, expr)
+	}
+	return list, nil
+}
+
+// parsePrintOptions parses the indented settings block following a print
+func (p *Parser) parsePrintOptions(stmt *PrintStmt) error {
+	return p.parseOptions(func(key *lexer.Token) error {
+		var err error
+		switch key.Value {
+		case PrintOptionSep:
+			stmt.Sep, err = p.parseExpr()
+		case PrintOptionNoNewline:
+			stmt.NoNewline, err = p.parseBoolLiteral()
+		case PrintOptionStderr:
+			stmt.Stderr, err = p.parseBoolLiteral()
+		default:
+			err := yaperror.NewUnexpectedTokenError(
+				p.filename, key.Line, key.Col,
+				key.Value, fmt.Sprintf("%s, %s or %s", PrintOptionSep, PrintOptionNoNewline, PrintOptionStderr),
+			)
+			return withHint(err, key.Value, []string{PrintOptionSep, PrintOptionNoNewline, PrintOptionStderr})
+		}
+		return err
+	})
+}
+
+// parseOptions parses an indented block of "name: value" settings below a
+// statement. parseValue is called with the name token to parse each value
+func (p *Parser) parseOptions(parseValue func(key *lexer.Token) error) error {
+	// Consume the indent
+	p.next()
+
+	for p.peek().Kind != lexer.TokenDedent && p.peek().Kind != lexer.TokenEOF {
+		// Skip comment lines
+		if p.peek().Kind == lexer.TokenComment {
+			p.next()
+			if p.peek().Kind == lexer.TokenNewline {
+				p.next()
+			}
+			continue
+		}
+
+		key, err := p.expect(lexer.TokenIdentifier)
+		if err != nil {
+			return err
+		}
+
+		if _, err := p.expect(lexer.TokenColon); err != nil {
+			return err
+		}
+
+		if err := parseValue(key); err != nil {
+			return err
+		}
+
+		// Skip any trailing comment
+		for p.peek().Kind == lexer.TokenComment {
+			p.next()
+		}
+
+		if _, err := p.expect(lexer.TokenNewline); err != nil {
+			return err
+		}
+	}
+
+	// Expect dedent to close the settings block
+	_, err := p.expect(lexer.TokenDedent)
+	return err
+}
+
+// parseBoolLiteral parses a True or False keyword
+func (p *Parser) parseBoolLiteral() (bool, error) {
+	tok := p.next()
+	if tok.Kind == lexer.TokenKeyword {
+		switch tok.Value {
+		case lexer.KeywordTrue:
+			return true, nil
+		case lexer.KeywordFalse:
+			return false, nil
+		}
+	}
+	err := yaperror.NewUnexpectedTokenError(
+		p.filename, tok.Line, tok.Col,
+		tok.Value, fmt.Sprintf("%s or %s", lexer.KeywordTrue, lexer.KeywordFalse),
+	)
+	return false, withHint(err, tok.Value, []string{lexer.KeywordTrue, lexer.KeywordFalse})
 }
 
 func (p *Parser) parseExpr() (Value, error) {
@@ -230,7 +558,12 @@ func (p *Parser) parseExpr() (Value, error) {
 	return left, nil
 }
 
-func (p *Parser) parseSet() (Stmt, error) {
+func (p *Parser) parseSet(span source.Span) (Stmt, error) {
+	// Skip any trailing comment after "set:"
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
 	val := p.next()
 	switch val.Kind {
 	case lexer.TokenNewline:
@@ -276,6 +609,11 @@ func (p *Parser) parseSet() (Stmt, error) {
 				return nil, err
 			}
 
+			// Skip any trailing comment before newline
+			for p.peek().Kind == lexer.TokenComment {
+				p.next()
+			}
+
 			_, err = p.expect(lexer.TokenNewline)
 			if err != nil {
 				return nil, err
@@ -283,6 +621,7 @@ func (p *Parser) parseSet() (Stmt, error) {
 			assignments = append(assignments, &Assignment{
 				Name: key.Value,
 				Expr: expr,
+				Loc:  p.spanOf(key),
 			})
 		}
 
@@ -294,6 +633,7 @@ func (p *Parser) parseSet() (Stmt, error) {
 
 		return SetStmt{
 			Assignment: assignments,
+			Loc:        span,
 		}, nil
 
 	default:
@@ -304,7 +644,7 @@ func (p *Parser) parseSet() (Stmt, error) {
 	}
 }
 
-func (p *Parser) parseIf() (Stmt, error) {
+func (p *Parser) parseIf(span source.Span) (Stmt, error) {
 	// Parse the condition expression (e.g., "x > 5")
 	condition, err := p.parseExpr()
 	if err != nil {
@@ -394,6 +734,7 @@ func (p *Parser) parseIf() (Stmt, error) {
 		Condition: condition,
 		Then:      thenStmts,
 		Else:      elseStmts,
+		Loc:       span,
 	}, nil
 }
 
@@ -433,3 +774,325 @@ func (p *Parser) parseBlock() ([]Stmt, error) {
 
 	return stmts, nil
 }
+
+func (p *Parser) parseAssert(span source.Span) (Stmt, error) {
+	condition, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip any trailing comment before newline
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
+	if _, err := p.expect(lexer.TokenNewline); err != nil {
+		return nil, err
+	}
+
+	stmt := AssertStmt{
+		Condition: condition,
+		Loc:       span,
+	}
+
+	// Optional indented "message:"
+	if p.peek().Kind == lexer.TokenIndent {
+		err := p.parseOptions(func(key *lexer.Token) error {
+			if key.Value != AssertOptionMessage {
+				err := yaperror.NewUnexpectedTokenError(
+					p.filename, key.Line, key.Col,
+					key.Value, AssertOptionMessage,
+				)
+				return withHint(err, key.Value, []string{AssertOptionMessage})
+			}
+			var err error
+			stmt.Message, err = p.parseExpr()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseExpectError(span source.Span) (Stmt, error) {
+	// Skip any trailing comment after "expect_error:"
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
+	if _, err := p.expect(lexer.TokenNewline); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return ExpectErrorStmt{
+		Body: body,
+		Loc:  span,
+	}, nil
+}
+
+func (p *Parser) parseImport(span source.Span) (Stmt, error) {
+	path, err := p.expect(lexer.TokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip any trailing comment before newline
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
+	if _, err := p.expect(lexer.TokenNewline); err != nil {
+		return nil, err
+	}
+
+	stmt := ImportStmt{
+		Path:    path.Value,
+		PathLoc: p.spanOf(path),
+		Loc:     span,
+	}
+
+	// Optional indented "as:"
+	if p.peek().Kind == lexer.TokenIndent {
+		err := p.parseOptions(func(key *lexer.Token) error {
+			if key.Value != ImportOptionAs {
+				err := yaperror.NewUnexpectedTokenError(
+					p.filename, key.Line, key.Col,
+					key.Value, ImportOptionAs,
+				)
+				return withHint(err, key.Value, []string{ImportOptionAs})
+			}
+			alias, err := p.expect(lexer.TokenIdentifier)
+			if err != nil {
+				return err
+			}
+			stmt.Alias = alias.Value
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if stmt.Alias == "" {
+		stmt.Alias = ModuleName(stmt.Path)
+		if !isIdentifier(stmt.Alias) {
+			return nil, yaperror.NewInvalidModuleNameError(
+				p.filename, path.Line, path.Col, stmt.Path,
+			)
+		}
+	}
+	return stmt, nil
+}
+
+// ModuleName returns the namespace a module is imported under by default:
+// the name of its file without the extension, e.g. util for lib/util.yap
+func ModuleName(path string) string {
+	base := path
+	if i := strings.LastIndexAny(base, `/\`); i >= 0 {
+		base = base[i+1:]
+	}
+	if i := strings.LastIndex(base, "."); i > 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// isIdentifier reports whether s can be used as a variable name
+func isIdentifier(s string) bool {
+	if s == "" || lexer.IsKeyword(s) {
+		return false
+	}
+	for i, c := range s {
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Parser) parseFor(span source.Span) (Stmt, error) {
+	name, err := p.expect(lexer.TokenIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip any trailing comment before newline
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
+	if _, err := p.expect(lexer.TokenNewline); err != nil {
+		return nil, err
+	}
+
+	// Expect indent for the in/do block
+	if _, err := p.expect(lexer.TokenIndent); err != nil {
+		return nil, err
+	}
+
+	if err := p.expectKeyword(lexer.KeywordIn); err != nil {
+		return nil, err
+	}
+	list, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip any trailing comment
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
+	if _, err := p.expect(lexer.TokenNewline); err != nil {
+		return nil, err
+	}
+
+	if err := p.expectKeyword(lexer.KeywordDo); err != nil {
+		return nil, err
+	}
+
+	// Skip any trailing comment
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
+	if _, err := p.expect(lexer.TokenNewline); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Expect dedent to close the for statement
+	if _, err := p.expect(lexer.TokenDedent); err != nil {
+		return nil, err
+	}
+
+	return ForStmt{
+		Var:    name.Value,
+		VarLoc: p.spanOf(name),
+		List:   list,
+		Body:   body,
+		Loc:    span,
+	}, nil
+}
+
+// expectKeyword consumes keyword and the colon after it, e.g. "do:" of a for
+// statement
+func (p *Parser) expectKeyword(keyword string) error {
+	key, err := p.expect(lexer.TokenKeyword)
+	if err != nil {
+		return err
+	}
+	if key.Value != keyword {
+		return yaperror.NewUnexpectedTokenError(
+			p.filename, key.Line, key.Col,
+			key.Value, keyword,
+		)
+	}
+	_, err = p.expect(lexer.TokenColon)
+	return err
+}
+
+func (p *Parser) parseCallStmt(span source.Span) (Stmt, error) {
+	start := p.peek()
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	call, ok := expr.(*CallExpr)
+	if !ok {
+		return nil, yaperror.NewExpectedTokenError(
+			p.filename, start.Line, start.Col, "a function call",
+		)
+	}
+
+	// Skip any trailing comment before newline
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
+	if _, err := p.expect(lexer.TokenNewline); err != nil {
+		return nil, err
+	}
+
+	return CallStmt{
+		Call: call,
+		Loc:  span,
+	}, nil
+}
+
+func (p *Parser) parseInput(span source.Span) (Stmt, error) {
+	name, err := p.expect(lexer.TokenIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip any trailing comment before newline
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
+	if _, err := p.expect(lexer.TokenNewline); err != nil {
+		return nil, err
+	}
+
+	stmt := InputStmt{
+		Name:    name.Value,
+		NameLoc: p.spanOf(name),
+		Loc:     span,
+	}
+
+	// Optional indented "prompt:"
+	if p.peek().Kind == lexer.TokenIndent {
+		err := p.parseOptions(func(key *lexer.Token) error {
+			if key.Value != InputOptionPrompt {
+				err := yaperror.NewUnexpectedTokenError(
+					p.filename, key.Line, key.Col,
+					key.Value, InputOptionPrompt,
+				)
+				return withHint(err, key.Value, []string{InputOptionPrompt})
+			}
+			var err error
+			stmt.Prompt, err = p.parseExpr()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseExit(span source.Span) (Stmt, error) {
+	code, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip any trailing comment before newline
+	for p.peek().Kind == lexer.TokenComment {
+		p.next()
+	}
+
+	if _, err := p.expect(lexer.TokenNewline); err != nil {
+		return nil, err
+	}
+
+	return ExitStmt{
+		Code: code,
+		Loc:  span,
+	}, nil
+}
@@ -0,0 +1,26 @@
+package parser_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/require"
+)
+
+const fuzzFile = "fuzz.yap"
+
+// Parsing any input must not panic, and must either produce a program or a
+// positioned error
+func FuzzParse(f *testing.F) {
+	test_util.AddFuzzSeeds(f, testFileDir)
+	f.Fuzz(func(t *testing.T, src []byte) {
+		prog, err := parser.NewParserFromReader(bytes.NewReader(src), fuzzFile).Parse()
+		if err != nil {
+			test_util.RequireYapError(t, err, fuzzFile)
+			return
+		}
+		require.NotNil(t, prog)
+	})
+}
@@ -1,11 +1,14 @@
 package parser_test
 
 import (
+	"strings"
 	"testing"
 
+	yaperror "github.com/rlamalama/YAP/internal/error"
 	"github.com/rlamalama/YAP/internal/frontend/parser"
 	test_util "github.com/rlamalama/YAP/test/test-util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const testFileDir = "../../.."
@@ -293,3 +296,283 @@ func TestParseIfThenElse(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "x is small", elseStrLit.Value)
 }
+
+// Test parsing assert statements and expect_error blocks
+func TestParseAssertAndExpectError(t *testing.T) {
+	p := parser.NewParser(test_util.GetTestFilepath(test_util.AssertYAP, testFileDir))
+	prog, err := p.Parse()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, prog)
+	// set + 2 asserts + expect_error + print = 5 statements
+	assert.Equal(t, 5, len(prog.Statements))
+
+	// - assert: total == 5
+	first, ok := prog.Statements[1].(parser.AssertStmt)
+	assert.True(t, ok)
+	assert.Equal(t, parser.StmtTypeAssert, first.Type())
+	assert.Equal(t, "(total == 5)", first.Condition.String())
+	assert.Nil(t, first.Message)
+
+	// - assert: name == "YAP"
+	//   message: "name should be " + "YAP"
+	second := prog.Statements[2].(parser.AssertStmt)
+	assert.Equal(t, "(name should be  + YAP)", second.Message.String())
+
+	// - expect_error:
+	expectError, ok := prog.Statements[3].(parser.ExpectErrorStmt)
+	assert.True(t, ok)
+	assert.Equal(t, parser.StmtTypeExpectError, expectError.Type())
+	assert.Equal(t, 2, len(expectError.Body))
+	assert.Equal(t, 7, expectError.Span().Start.Line)
+}
+
+// Test parsing print statements with several values and settings
+func TestParsePrintMultiple(t *testing.T) {
+	p := parser.NewParser(test_util.GetTestFilepath(test_util.PrintMultipleYAP, testFileDir))
+	prog, err := p.Parse()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, prog)
+	// 1 set statement + 5 print statements = 6 statements
+	assert.Equal(t, 6, len(prog.Statements))
+
+	// - print: "name:", name
+	first := prog.Statements[1].(parser.PrintStmt)
+	list, ok := first.Expr.(*parser.ExprList)
+	assert.True(t, ok, "print with several values should hold an ExprList")
+	assert.Equal(t, 2, len(list.Values))
+	assert.Nil(t, first.Sep)
+	assert.False(t, first.NoNewline)
+
+	// - print: 1, 2, 3
+	//   sep: ", "
+	withSep := prog.Statements[3].(parser.PrintStmt)
+	assert.Equal(t, "1, 2, 3", withSep.Expr.String())
+	sep, ok := withSep.Sep.(*parser.StringLiteral)
+	assert.True(t, ok)
+	assert.Equal(t, ", ", sep.Value)
+
+	// - print: "no newline "
+	//   no_newline: True
+	noNewline := prog.Statements[4].(parser.PrintStmt)
+	_, ok = noNewline.Expr.(*parser.StringLiteral)
+	assert.True(t, ok, "print with a single value should not hold an ExprList")
+	assert.True(t, noNewline.NoNewline)
+}
+
+func TestParseSpans(t *testing.T) {
+	src := "- set:\n  - total: 1 + 20\n- print: \"hi\"\n"
+	prog, err := parser.NewParserFromBytes([]byte(src), "spans.yap").Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(prog.Statements))
+
+	set := prog.Statements[0].(parser.SetStmt)
+	name := set.Assignment[0].Loc
+	assert.Equal(t, 2, name.Start.Line)
+	assert.Equal(t, 5, name.Start.Column)
+	assert.Equal(t, 10, name.End.Column)
+
+	expr := set.Assignment[0].Expr.Span()
+	assert.Equal(t, 12, expr.Start.Column)
+	assert.Equal(t, 18, expr.End.Column)
+
+	// String spans include the quotes
+	print := prog.Statements[1].(parser.PrintStmt)
+	assert.Equal(t, 10, print.Expr.Span().Start.Column)
+	assert.Equal(t, 14, print.Expr.Span().End.Column)
+}
+
+// Errors at the end of a truncated program point at its last token
+func TestParseTruncatedProgramPosition(t *testing.T) {
+	_, err := parser.NewParserFromReader(strings.NewReader("- set:"), "truncated.yap").Parse()
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "truncated.yap:1:7")
+}
+
+// Errors carry the source line they point at
+func TestParseErrorContext(t *testing.T) {
+	src := []byte("- set:\n  - x: 4\n- print: x +\n")
+	_, err := parser.NewParserFromBytes(src, "context.yap").Parse()
+
+	var yerr *yaperror.YapError
+	assert.ErrorAs(t, err, &yerr)
+	assert.Equal(t, "- print: x +", yerr.Context)
+	assert.Equal(t, "    - print: x +\n                ^\n", yerr.Details())
+}
+
+// Misspelled keywords and options get a "did you mean" hint
+func TestParseErrorHints(t *testing.T) {
+	tests := []struct {
+		src  string
+		hint string
+	}{
+		{"- prnt: 1\n", `did you mean "print"?`},
+		{"- Set:\n  - x: 1\n", `did you mean "set"? names are case sensitive`},
+		{"- if: True\n  thn:\n    - print: 1\n", `did you mean "then"?`},
+		{"- print: 1\n  sepp: \", \"\n", `did you mean "sep"?`},
+		{"- print: 1\n  no_newline: true\n", `did you mean "True"? names are case sensitive`},
+		{"- assert: True\n  mesage: \"m\"\n", `did you mean "message"?`},
+	}
+	for _, tt := range tests {
+		_, err := parser.NewParserFromBytes([]byte(tt.src), "hint.yap").Parse()
+
+		var yerr *yaperror.YapError
+		require.ErrorAs(t, err, &yerr, tt.src)
+		assert.Equal(t, yaperror.ErrUnexpectedToken, yerr.Code, tt.src)
+		assert.Equal(t, []string{tt.hint}, yerr.Hints, tt.src)
+	}
+
+	_, err := parser.NewParserFromBytes([]byte("- bogus: 1\n"), "hint.yap").Parse()
+	var yerr *yaperror.YapError
+	require.ErrorAs(t, err, &yerr)
+	assert.Empty(t, yerr.Hints)
+}
+
+func TestParseImport(t *testing.T) {
+	src := []byte("- import: \"lib/util.yap\"\n- import: \"lib/util.yap\"\n  as: u\n- print: u.answer\n")
+	prog, err := parser.NewParserFromBytes(src, "import.yap").Parse()
+	require.NoError(t, err)
+	require.Len(t, prog.Statements, 3)
+
+	imp, ok := prog.Statements[0].(parser.ImportStmt)
+	require.True(t, ok)
+	assert.Equal(t, "lib/util.yap", imp.Path)
+	assert.Equal(t, "util", imp.Alias)
+	assert.Equal(t, 11, imp.PathLoc.Start.Column)
+
+	imp, ok = prog.Statements[1].(parser.ImportStmt)
+	require.True(t, ok)
+	assert.Equal(t, "u", imp.Alias)
+
+	print, ok := prog.Statements[2].(parser.PrintStmt)
+	require.True(t, ok)
+	sel, ok := print.Expr.(*parser.SelectorExpr)
+	require.True(t, ok)
+	assert.Equal(t, "answer", sel.Name)
+	assert.Equal(t, &parser.Identifier{Name: "u", Loc: sel.X.Span()}, sel.X)
+}
+
+func TestParseImportInvalidModuleName(t *testing.T) {
+	_, err := parser.NewParserFromBytes([]byte("- import: \"my-lib.yap\"\n"), "import.yap").Parse()
+
+	var yerr *yaperror.YapError
+	require.ErrorAs(t, err, &yerr)
+	assert.Equal(t, yaperror.ErrInvalidModuleName, yerr.Code)
+
+	_, err = parser.NewParserFromBytes([]byte("- import: \"my-lib.yap\"\n  as: mylib\n"), "import.yap").Parse()
+	assert.NoError(t, err)
+}
+
+func TestParseCall(t *testing.T) {
+	src := []byte("- print: math.pow(x, 2.5) + math.pi, f()\n")
+	prog, err := parser.NewParserFromBytes(src, "call.yap").Parse()
+	require.NoError(t, err)
+
+	print, ok := prog.Statements[0].(parser.PrintStmt)
+	require.True(t, ok)
+	list, ok := print.Expr.(*parser.ExprList)
+	require.True(t, ok)
+	assert.Equal(t, "(math.pow(x, 2.5) + math.pi), f()", list.String())
+
+	sum := list.Values[0].(*parser.BinaryExpr)
+	call, ok := sum.Left.(*parser.CallExpr)
+	require.True(t, ok)
+	assert.Equal(t, "math.pow", call.Fun.String())
+	require.Len(t, call.Args, 2)
+	assert.Equal(t, &parser.FloatLiteral{Value: 2.5, Loc: call.Args[1].Span()}, call.Args[1])
+	assert.Equal(t, 10, call.Loc.Start.Column)
+	assert.Equal(t, 26, call.Loc.End.Column)
+
+	empty, ok := list.Values[1].(*parser.CallExpr)
+	require.True(t, ok)
+	assert.Empty(t, empty.Args)
+
+	for _, src := range []string{"- print: f(1\n", "- print: f(1 2)\n", "- print: f(,)\n"} {
+		_, err := parser.NewParserFromBytes([]byte(src), "call.yap").Parse()
+		assert.Error(t, err, src)
+	}
+}
+
+func TestParseFor(t *testing.T) {
+	src := []byte("- for: line  // each line\n  in: fs.read_lines(\"in.txt\")\n  do:\n    - print: line\n- print: \"done\"\n")
+	prog, err := parser.NewParserFromBytes(src, "for.yap").Parse()
+	require.NoError(t, err)
+	require.Len(t, prog.Statements, 2)
+
+	loop, ok := prog.Statements[0].(parser.ForStmt)
+	require.True(t, ok)
+	assert.Equal(t, parser.StmtTypeFor, loop.Type())
+	assert.Equal(t, "line", loop.Var)
+	assert.Equal(t, 8, loop.VarLoc.Start.Column)
+	assert.Equal(t, "fs.read_lines(in.txt)", loop.List.String())
+	require.Len(t, loop.Body, 1)
+	assert.Equal(t, parser.StmtTypePrint, loop.Body[0].Type())
+
+	for _, src := range []string{
+		"- for: x\n  do:\n    - print: x\n",
+		"- for: 1\n  in: l\n  do:\n",
+		"- for: x\n  in: l\n",
+	} {
+		_, err := parser.NewParserFromBytes([]byte(src), "for.yap").Parse()
+		assert.Error(t, err, src)
+	}
+}
+
+func TestParseCallStmt(t *testing.T) {
+	src := []byte("- call: fs.write_file(\"out.txt\", text)\n")
+	prog, err := parser.NewParserFromBytes(src, "call.yap").Parse()
+	require.NoError(t, err)
+
+	stmt, ok := prog.Statements[0].(parser.CallStmt)
+	require.True(t, ok)
+	assert.Equal(t, parser.StmtTypeCall, stmt.Type())
+	assert.Equal(t, "fs.write_file(out.txt, text)", stmt.Call.String())
+
+	_, err = parser.NewParserFromBytes([]byte("- call: 1 + 2\n"), "call.yap").Parse()
+	var yerr *yaperror.YapError
+	require.ErrorAs(t, err, &yerr)
+	assert.Equal(t, yaperror.ErrExpectedToken, yerr.Code)
+	assert.Equal(t, "expected a function call", yerr.Message)
+}
+
+func TestParseInput(t *testing.T) {
+	src := []byte("- input: name\n  prompt: \"Name? \"\n- input: line  // no prompt\n")
+	prog, err := parser.NewParserFromBytes(src, "input.yap").Parse()
+	require.NoError(t, err)
+	require.Len(t, prog.Statements, 2)
+
+	first, ok := prog.Statements[0].(parser.InputStmt)
+	require.True(t, ok)
+	assert.Equal(t, parser.StmtTypeInput, first.Type())
+	assert.Equal(t, "name", first.Name)
+	assert.Equal(t, 10, first.NameLoc.Start.Column)
+	assert.Equal(t, "Name? ", first.Prompt.String())
+
+	second := prog.Statements[1].(parser.InputStmt)
+	assert.Equal(t, "line", second.Name)
+	assert.Nil(t, second.Prompt)
+
+	_, err = parser.NewParserFromBytes([]byte("- input: name\n  promt: \"?\"\n"), "input.yap").Parse()
+	var yerr *yaperror.YapError
+	require.ErrorAs(t, err, &yerr)
+	assert.Equal(t, []string{`did you mean "prompt"?`}, yerr.Hints)
+
+	_, err = parser.NewParserFromBytes([]byte("- input: \"name\"\n"), "input.yap").Parse()
+	assert.Error(t, err)
+}
+
+func TestParseExit(t *testing.T) {
+	src := []byte("- exit: code + 1  // failure\n")
+	prog, err := parser.NewParserFromBytes(src, "exit.yap").Parse()
+	require.NoError(t, err)
+
+	stmt, ok := prog.Statements[0].(parser.ExitStmt)
+	require.True(t, ok)
+	assert.Equal(t, parser.StmtTypeExit, stmt.Type())
+	assert.Equal(t, "(code + 1)", stmt.Code.String())
+
+	_, err = parser.NewParserFromBytes([]byte("- exit:\n"), "exit.yap").Parse()
+	assert.Error(t, err)
+}
@@ -1,36 +1,88 @@
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rlamalama/YAP/internal/frontend/source"
+)
 
 type Value interface {
 	value()
 	String() string
+	Span() source.Span
 }
 
 type StringLiteral struct {
 	Value string
+	Loc   source.Span
 }
 
-func (*StringLiteral) value()           {}
-func (s *StringLiteral) String() string { return s.Value }
+func (*StringLiteral) value()              {}
+func (s *StringLiteral) String() string    { return s.Value }
+func (s *StringLiteral) Span() source.Span { return s.Loc }
 
-// Numbers only support int right now
+// NumericLiteral is an integer, numbers with a fraction are a FloatLiteral
 type NumericLiteral struct {
 	Value int
+	Loc   source.Span
 }
 
-func (*NumericLiteral) value()           {}
-func (n *NumericLiteral) String() string { return fmt.Sprintf("%d", n.Value) }
+func (*NumericLiteral) value()              {}
+func (n *NumericLiteral) String() string    { return fmt.Sprintf("%d", n.Value) }
+func (n *NumericLiteral) Span() source.Span { return n.Loc }
+
+// FloatLiteral is a number with a fraction, e.g. 1.5
+type FloatLiteral struct {
+	Value float64
+	Loc   source.Span
+}
+
+func (*FloatLiteral) value()              {}
+func (f *FloatLiteral) String() string    { return strconv.FormatFloat(f.Value, 'g', -1, 64) }
+func (f *FloatLiteral) Span() source.Span { return f.Loc }
 
 type Identifier struct {
 	Name string
+	Loc  source.Span
+}
+
+func (*Identifier) value()              {}
+func (i *Identifier) String() string    { return i.Name }
+func (i *Identifier) Span() source.Span { return i.Loc }
+
+// SelectorExpr selects a name from a namespace, e.g. util.name
+type SelectorExpr struct {
+	X    Value  // The namespace
+	Name string // The selected name
+	Loc  source.Span
+}
+
+func (*SelectorExpr) value()              {}
+func (s *SelectorExpr) String() string    { return s.X.String() + "." + s.Name }
+func (s *SelectorExpr) Span() source.Span { return s.Loc }
+
+// CallExpr calls a function, e.g. math.sqrt(x)
+type CallExpr struct {
+	Fun  Value // The called function
+	Args []Value
+	Loc  source.Span // From the function to the closing parenthesis
 }
 
-func (*Identifier) value()           {}
-func (i *Identifier) String() string { return i.Name }
+func (*CallExpr) value() {}
+func (c *CallExpr) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+	return c.Fun.String() + "(" + strings.Join(args, ", ") + ")"
+}
+func (c *CallExpr) Span() source.Span { return c.Loc }
 
 type BooleanLiteral struct {
 	Value bool
+	Loc   source.Span
 }
 
 func (*BooleanLiteral) value() {}
@@ -40,6 +92,7 @@ func (b *BooleanLiteral) String() string {
 	}
 	return "False"
 }
+func (b *BooleanLiteral) Span() source.Span { return b.Loc }
 
 type BinaryExpr struct {
 	Left     Value
@@ -51,3 +104,28 @@ func (*BinaryExpr) value() {}
 func (b *BinaryExpr) String() string {
 	return fmt.Sprintf("(%s %s %s)", b.Left.String(), b.Operator, b.Right.String())
 }
+func (b *BinaryExpr) Span() source.Span {
+	left, right := b.Left.Span(), b.Right.Span()
+	return source.Span{File: left.File, Start: left.Start, End: right.End}
+}
+
+// ExprList is a comma separated list of expressions, e.g. the values of a print
+type ExprList struct {
+	Values []Value
+}
+
+func (*ExprList) value() {}
+func (l *ExprList) String() string {
+	parts := make([]string, len(l.Values))
+	for i, v := range l.Values {
+		parts[i] = v.String()
+	}
+	return strings.Join(parts, ", ")
+}
+func (l *ExprList) Span() source.Span {
+	if len(l.Values) == 0 {
+		return source.Span{}
+	}
+	first, last := l.Values[0].Span(), l.Values[len(l.Values)-1].Span()
+	return source.Span{File: first.File, Start: first.Start, End: last.End}
+}
@@ -1,9 +1,17 @@
 package source
 
+import (
+	"strings"
+	"sync"
+)
+
 type File struct {
 	Path     string
 	Text     []byte
 	NumBytes int
+
+	splitOnce sync.Once
+	lines     []string
 }
 
 func NewFile(path string, text []byte) *File {
@@ -13,3 +21,28 @@ func NewFile(path string, text []byte) *File {
 		NumBytes: len(text),
 	}
 }
+
+// Line returns the text of the 1-based line n without its line ending
+func (f *File) Line(n int) (string, bool) {
+	lines := f.splitLines()
+	if n < 1 || n > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[n-1], "\r"), true
+}
+
+// LineCount returns the number of lines in the file
+func (f *File) LineCount() int {
+	return len(f.splitLines())
+}
+
+// splitLines splits the text on first use. Files are shared by compiled
+// programs, so this must be safe to call from many goroutines
+func (f *File) splitLines() []string {
+	f.splitOnce.Do(func() {
+		if len(f.Text) > 0 {
+			f.lines = strings.Split(strings.TrimSuffix(string(f.Text), "\n"), "\n")
+		}
+	})
+	return f.lines
+}
@@ -1,12 +1,29 @@
 package source
 
+// Position is a 1-based line and column (in bytes) in a source file
 type Position struct {
 	Line   int
 	Column int
 }
 
+// Span is a range in a source file. End is exclusive: it is the position
+// just after the last character of the range
 type Span struct {
 	File  *File
 	Start Position
 	End   Position
 }
+
+// Contains reports whether pos lies within the span
+func (s Span) Contains(pos Position) bool {
+	if pos.Line < s.Start.Line || pos.Line > s.End.Line {
+		return false
+	}
+	if pos.Line == s.Start.Line && pos.Column < s.Start.Column {
+		return false
+	}
+	if pos.Line == s.End.Line && pos.Column >= s.End.Column {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+// Package suggest finds the names a misspelled keyword or variable was
+// probably meant to be, for "did you mean" hints
+package suggest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Distance returns the edit distance between a and b: the number of single
+// byte insertions, deletions, substitutions and swaps of adjacent bytes that
+// turn a into b. Swaps count once, as they are a common typo
+func Distance(a, b string) int {
+	// d[i][j] is the distance between a[:i] and b[:j]
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// Closest returns the candidate name was most likely meant to be: one that
+// differs only in case, or else the nearest one within a third of the length
+// of name. Ties go to the candidate that sorts first
+func Closest(name string, candidates []string) (string, bool) {
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+
+	for _, c := range sorted {
+		if c != name && strings.EqualFold(c, name) {
+			return c, true
+		}
+	}
+
+	best, bestDist := "", max(1, len(name)/3)+1
+	for _, c := range sorted {
+		if c == name {
+			continue
+		}
+		if d := Distance(name, c); d < bestDist && d < len(name) {
+			best, bestDist = c, d
+		}
+	}
+	return best, best != ""
+}
+
+// Hint returns a "did you mean" hint for name, or "" if no candidate is close
+func Hint(name string, candidates []string) string {
+	c, ok := Closest(name, candidates)
+	switch {
+	case !ok:
+		return ""
+	case strings.EqualFold(c, name):
+		return fmt.Sprintf("did you mean %q? names are case sensitive", c)
+	default:
+		return fmt.Sprintf("did you mean %q?", c)
+	}
+}
@@ -0,0 +1,59 @@
+package suggest_test
+
+import (
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/frontend/suggest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"print", "print", 0},
+		{"prnt", "print", 1},
+		{"pritn", "print", 1},
+		{"ab", "ba", 1},
+		{"", "set", 3},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, suggest.Distance(tt.a, tt.b), "%s -> %s", tt.a, tt.b)
+		assert.Equal(t, tt.want, suggest.Distance(tt.b, tt.a), "%s -> %s", tt.b, tt.a)
+	}
+}
+
+func TestClosest(t *testing.T) {
+	keywords := []string{"print", "set", "if", "then", "else", "True", "False", "assert", "expect_error"}
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"prnt", "print", true},
+		{"Print", "print", true},
+		{"true", "True", true},
+		{"FALSE", "False", true},
+		{"asert", "assert", true},
+		{"pirnt", "print", true},
+		{"expect_eror", "expect_error", true},
+		{"sett", "set", true},
+		{"print", "", false}, // Not misspelled
+		{"x", "", false},     // Too short to guess
+		{"foo", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := suggest.Closest(tt.name, keywords)
+		assert.Equal(t, tt.ok, ok, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+}
+
+func TestHint(t *testing.T) {
+	assert.Equal(t, `did you mean "total"?`, suggest.Hint("totl", []string{"count", "total"}))
+	assert.Equal(t, `did you mean "True"? names are case sensitive`, suggest.Hint("true", []string{"True", "False"}))
+	assert.Equal(t, "", suggest.Hint("zzz", []string{"total"}))
+}
@@ -0,0 +1,151 @@
+package lsp
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/check"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/frontend/source"
+)
+
+// document is an open text document and the result of analysing it
+type document struct {
+	uri  string
+	path string
+	text string
+	prog *parser.Program // nil if the document does not parse
+	info *check.Info     // nil if the document does not parse
+	err  error           // lexer or parser error
+
+	imports []*build.ImportError // imports that cannot be loaded
+}
+
+func newDocument(uri, text string) *document {
+	doc := &document{uri: uri, path: uriToPath(uri), text: text}
+	doc.analyze()
+	return doc
+}
+
+func (d *document) analyze() {
+	prog, err := parser.NewParserFromBytes([]byte(d.text), d.path).Parse()
+	if err != nil {
+		d.err = err
+		return
+	}
+	d.prog = prog
+	d.info = check.Check(prog)
+	d.imports = build.NewLoader(build.SearchPathFromEnv()).CheckImports(d.info)
+}
+
+// diagnostics returns the lexer, parser and type checker errors of the document
+func (d *document) diagnostics() []Diagnostic {
+	diags := []Diagnostic{}
+	if d.err != nil {
+		var yerr *yaperror.YapError
+		if errors.As(d.err, &yerr) {
+			diags = append(diags, toDiagnostic(yerr))
+		} else {
+			diags = append(diags, Diagnostic{Severity: SeverityError, Source: diagnosticSource, Message: d.err.Error()})
+		}
+		return diags
+	}
+
+	for _, yerr := range d.info.Errors.Errors() {
+		diags = append(diags, toDiagnostic(yerr))
+	}
+	for _, imp := range d.imports {
+		diags = append(diags, importDiagnostic(imp))
+	}
+	return diags
+}
+
+// importDiagnostic reports an import that cannot be loaded. Errors located
+// in the imported module are shown on the path of the import
+func importDiagnostic(imp *build.ImportError) Diagnostic {
+	var yerr *yaperror.YapError
+	if errors.As(imp.Err, &yerr) && imp.Import.Loc.File != nil && yerr.Position.File == imp.Import.Loc.File.Path {
+		return toDiagnostic(yerr)
+	}
+
+	loc := imp.Import.PathLoc
+	diag := Diagnostic{
+		Range: Range{
+			Start: Position{Line: max(loc.Start.Line-1, 0), Character: max(loc.Start.Column-1, 0)},
+			End:   Position{Line: max(loc.End.Line-1, 0), Character: max(loc.End.Column-1, 0)},
+		},
+		Severity: SeverityError,
+		Source:   diagnosticSource,
+		Message:  "error in imported module: " + imp.Err.Error(),
+	}
+	if yerr != nil {
+		diag.Code = yerr.Code.String()
+	}
+	return diag
+}
+
+// lineCount returns the number of lines in the document
+func (d *document) lineCount() int {
+	return strings.Count(d.text, "\n") + 1
+}
+
+const diagnosticSource = "yap"
+
+func toDiagnostic(err *yaperror.YapError) Diagnostic {
+	start := Position{Line: max(err.Position.Line-1, 0), Character: max(err.Position.Column-1, 0)}
+	end := Position{Line: start.Line, Character: start.Character + 1}
+	if err.Span != nil {
+		end = Position{Line: max(err.Span.End.Line-1, 0), Character: max(err.Span.End.Column-1, 0)}
+	}
+
+	// Clients show the message as is, so hints go on lines below it
+	message := err.Message
+	for _, hint := range err.Hints {
+		message += "\nhint: " + hint
+	}
+
+	return Diagnostic{
+		Range:    Range{Start: start, End: end},
+		Severity: toSeverity(err.Severity),
+		Code:     err.Code.String(),
+		Source:   diagnosticSource,
+		Message:  message,
+	}
+}
+
+func toSeverity(s yaperror.Severity) int {
+	switch s {
+	case yaperror.SeverityWarning:
+		return SeverityWarning
+	case yaperror.SeverityNote:
+		return SeverityInformation
+	case yaperror.SeverityHint:
+		return SeverityHint
+	default:
+		return SeverityError
+	}
+}
+
+// toSourcePosition converts a 0-based LSP position to a 1-based source position
+func toSourcePosition(pos Position) source.Position {
+	return source.Position{Line: pos.Line + 1, Column: pos.Character + 1}
+}
+
+func toRange(span source.Span) Range {
+	return Range{
+		Start: Position{Line: span.Start.Line - 1, Character: span.Start.Column - 1},
+		End:   Position{Line: span.End.Line - 1, Character: span.End.Column - 1},
+	}
+}
+
+// uriToPath returns the file system path of a file:// URI, or the URI itself
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
@@ -0,0 +1,301 @@
+// Package lsp implements a Language Server Protocol server for YAP
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/rlamalama/YAP/internal/frontend/format"
+	"github.com/rlamalama/YAP/internal/frontend/lexer"
+	"github.com/rlamalama/YAP/internal/rpc"
+)
+
+const serverName = "yap-lsp"
+
+// Server answers LSP requests for the documents a client has opened
+type Server struct {
+	conn        *rpc.Conn
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		conn: rpc.NewConn(r, w),
+		docs: map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends exit or closes the connection
+func (s *Server) Serve() error {
+	for {
+		body, err := s.conn.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+
+		result, rerr := s.handle(&req)
+		if req.ID == nil {
+			continue // notifications have no response
+		}
+
+		if rerr != nil {
+			err = s.conn.Write(errorResponse{JSONRPC: "2.0", ID: req.ID, Error: rerr})
+		} else {
+			err = s.conn.Write(response{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req *request) (interface{}, *responseError) {
+	if !s.initialized && req.Method != "initialize" {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	}
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		s.initialized = true
+		return s.initialize(), nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return nil, s.open(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// Full sync: the last change holds the whole document
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.open(params.TextDocument.URI, text)
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.publish(PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+
+	case "textDocument/hover":
+		return s.withPosition(req, hover)
+	case "textDocument/definition":
+		return s.withPosition(req, definition)
+	case "textDocument/completion":
+		return s.withPosition(req, completion)
+
+	case "textDocument/documentSymbol":
+		doc, rerr := s.document(req)
+		if rerr != nil {
+			return nil, rerr
+		}
+		return documentSymbols(doc), nil
+
+	case "textDocument/formatting":
+		doc, rerr := s.document(req)
+		if rerr != nil {
+			return nil, rerr
+		}
+		return formatting(doc), nil
+
+	default:
+		if req.ID == nil {
+			return nil, nil // unknown notifications are ignored
+		}
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+}
+
+func (s *Server) initialize() InitializeResult {
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           TextDocumentSyncKindFull,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			DocumentSymbolProvider:     true,
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: serverName},
+	}
+}
+
+// open analyses the text of a document and publishes its diagnostics
+func (s *Server) open(uri, text string) *responseError {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	return s.publish(PublishDiagnosticsParams{URI: uri, Diagnostics: doc.diagnostics()})
+}
+
+func (s *Server) publish(params PublishDiagnosticsParams) *responseError {
+	err := s.conn.Write(notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: params})
+	if err != nil {
+		return &responseError{Code: codeInvalidRequest, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) document(req *request) (*document, *responseError) {
+	var params DocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, invalidParams(err)
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown document: %s", params.TextDocument.URI)}
+	}
+	return doc, nil
+}
+
+// withPosition decodes text document position params and calls fn with the document
+func (s *Server) withPosition(req *request, fn func(*document, Position) interface{}) (interface{}, *responseError) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, invalidParams(err)
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown document: %s", params.TextDocument.URI)}
+	}
+	return fn(doc, params.Position), nil
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// hover shows the inferred type of the variable or expression under the cursor
+func hover(doc *document, pos Position) interface{} {
+	if doc.info == nil {
+		return nil
+	}
+	srcPos := toSourcePosition(pos)
+
+	if sym, span, ok := doc.info.SymbolAt(srcPos); ok {
+		r := toRange(span)
+		return Hover{
+			Contents: MarkupContent{Kind: "markdown", Value: fmt.Sprintf("```yap\n%s: %s\n```", sym.Name, sym.Type)},
+			Range:    &r,
+		}
+	}
+
+	if value, ok := doc.info.ValueAt(srcPos); ok {
+		r := toRange(value.Span())
+		return Hover{
+			Contents: MarkupContent{Kind: "markdown", Value: fmt.Sprintf("```yap\n%s\n```", doc.info.Types[value])},
+			Range:    &r,
+		}
+	}
+	return nil
+}
+
+// definition returns the assignment of the variable under the cursor
+func definition(doc *document, pos Position) interface{} {
+	if doc.info == nil {
+		return nil
+	}
+	sym, _, ok := doc.info.SymbolAt(toSourcePosition(pos))
+	if !ok {
+		return nil
+	}
+	return Location{URI: doc.uri, Range: toRange(sym.Span())}
+}
+
+// completion offers the keywords and the variables assigned above the cursor
+func completion(doc *document, pos Position) interface{} {
+	items := []CompletionItem{}
+	for _, kw := range lexer.Keywords {
+		items = append(items, CompletionItem{Label: string(kw), Kind: CompletionItemKindKeyword})
+	}
+	if doc.info == nil {
+		return items
+	}
+
+	line := toSourcePosition(pos).Line
+	seen := map[string]string{}
+	for _, sym := range doc.info.Symbols {
+		if sym.Span().Start.Line < line {
+			seen[sym.Name] = sym.Type.String()
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionItemKindVariable, Detail: seen[name]})
+	}
+	return items
+}
+
+// documentSymbols lists every variable assignment of the document
+func documentSymbols(doc *document) interface{} {
+	symbols := []DocumentSymbol{}
+	if doc.info == nil {
+		return symbols
+	}
+	for _, sym := range doc.info.Symbols {
+		r := toRange(sym.Span())
+		symbols = append(symbols, DocumentSymbol{
+			Name:           sym.Name,
+			Detail:         sym.Type.String(),
+			Kind:           SymbolKindVariable,
+			Range:          r,
+			SelectionRange: r,
+		})
+	}
+	return symbols
+}
+
+// formatting replaces the whole document with its canonical formatting
+func formatting(doc *document) interface{} {
+	out, err := format.Source([]byte(doc.text), doc.path)
+	if err != nil || string(out) == doc.text {
+		return []TextEdit{}
+	}
+	return []TextEdit{{
+		Range: Range{
+			Start: Position{Line: 0, Character: 0},
+			End:   Position{Line: doc.lineCount(), Character: 0},
+		},
+		NewText: string(out),
+	}}
+}
@@ -0,0 +1,320 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/lsp"
+	"github.com/rlamalama/YAP/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testURI = "file:///work/demo.yap"
+
+const testDoc = `- set:
+  - x: 10
+  - name: "YAP"
+- print: name + x
+- if: x > 5
+  then:
+    - print: y
+`
+
+// client is a scripted LSP client talking to a server over pipes
+type client struct {
+	t             *testing.T
+	conn          *rpc.Conn
+	nextID        int
+	notifications []map[string]json.RawMessage
+	done          chan error
+}
+
+func startServer(t *testing.T) *client {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	c := &client{t: t, conn: rpc.NewConn(clientR, clientW), done: make(chan error, 1)}
+	go func() {
+		err := lsp.NewServer(serverR, serverW).Serve()
+		serverW.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { clientW.Close() })
+	return c
+}
+
+func (c *client) read() map[string]json.RawMessage {
+	body, err := c.conn.Read()
+	require.NoError(c.t, err)
+	var msg map[string]json.RawMessage
+	require.NoError(c.t, json.Unmarshal(body, &msg))
+	return msg
+}
+
+// call sends a request and returns its response, collecting any notifications
+func (c *client) call(method string, params interface{}) map[string]json.RawMessage {
+	c.nextID++
+	require.NoError(c.t, c.conn.Write(map[string]interface{}{
+		"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params,
+	}))
+	for {
+		msg := c.read()
+		if _, ok := msg["id"]; ok {
+			return msg
+		}
+		c.notifications = append(c.notifications, msg)
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	require.NoError(c.t, c.conn.Write(map[string]interface{}{
+		"jsonrpc": "2.0", "method": method, "params": params,
+	}))
+}
+
+// result decodes the result of a response into v
+func (c *client) result(msg map[string]json.RawMessage, v interface{}) {
+	_, hasErr := msg["error"]
+	require.False(c.t, hasErr, "unexpected error: %s", msg["error"])
+	require.NoError(c.t, json.Unmarshal(msg["result"], v))
+}
+
+// diagnostics waits for the next publishDiagnostics notification
+func (c *client) diagnostics() lsp.PublishDiagnosticsParams {
+	var params lsp.PublishDiagnosticsParams
+	msg := c.read()
+	require.Equal(c.t, `"textDocument/publishDiagnostics"`, string(msg["method"]))
+	require.NoError(c.t, json.Unmarshal(msg["params"], &params))
+	return params
+}
+
+func (c *client) initialize() {
+	var res lsp.InitializeResult
+	c.result(c.call("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}), &res)
+	c.notify("initialized", map[string]interface{}{})
+}
+
+func (c *client) open(text string) lsp.PublishDiagnosticsParams {
+	c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: testURI, LanguageID: "yap", Version: 1, Text: text},
+	})
+	return c.diagnostics()
+}
+
+func position(line, char int) lsp.TextDocumentPositionParams {
+	return lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: testURI},
+		Position:     lsp.Position{Line: line, Character: char},
+	}
+}
+
+func TestLSPInitialize(t *testing.T) {
+	c := startServer(t)
+
+	var res lsp.InitializeResult
+	c.result(c.call("initialize", map[string]interface{}{}), &res)
+
+	assert.Equal(t, lsp.TextDocumentSyncKindFull, res.Capabilities.TextDocumentSync)
+	assert.True(t, res.Capabilities.HoverProvider)
+	assert.True(t, res.Capabilities.DefinitionProvider)
+	assert.True(t, res.Capabilities.DocumentSymbolProvider)
+	assert.True(t, res.Capabilities.DocumentFormattingProvider)
+}
+
+func TestLSPRequestBeforeInitialize(t *testing.T) {
+	c := startServer(t)
+
+	msg := c.call("textDocument/hover", position(0, 0))
+	assert.Contains(t, string(msg["error"]), "not initialized")
+}
+
+func TestLSPShutdownAndExit(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+
+	msg := c.call("shutdown", nil)
+	assert.Equal(t, "null", string(msg["result"]))
+	c.notify("exit", nil)
+
+	require.NoError(t, <-c.done)
+}
+
+func TestLSPDiagnostics(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+
+	// Type checker: string + int and the undefined y
+	diags := c.open(testDoc)
+	assert.Equal(t, testURI, diags.URI)
+	require.Equal(t, 2, len(diags.Diagnostics))
+
+	mismatch := diags.Diagnostics[0]
+	assert.Equal(t, lsp.SeverityError, mismatch.Severity)
+	assert.Contains(t, mismatch.Message, "type mismatch")
+	assert.Equal(t, lsp.Range{Start: lsp.Position{Line: 3, Character: 16}, End: lsp.Position{Line: 3, Character: 17}}, mismatch.Range)
+
+	undefined := diags.Diagnostics[1]
+	assert.Contains(t, undefined.Message, `undefined variable "y"`)
+	assert.Equal(t, lsp.Position{Line: 6, Character: 13}, undefined.Range.Start)
+
+	// Parser errors replace the checker diagnostics on change
+	c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: "- then:\n"}},
+	})
+	diags = c.diagnostics()
+	require.Equal(t, 1, len(diags.Diagnostics))
+	assert.Contains(t, diags.Diagnostics[0].Message, "unknown statement")
+	assert.Equal(t, lsp.Position{Line: 0, Character: 2}, diags.Diagnostics[0].Range.Start)
+
+	// Hints follow the message
+	c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: "- prnt: 1\n"}},
+	})
+	diags = c.diagnostics()
+	require.Equal(t, 1, len(diags.Diagnostics))
+	assert.Equal(t, "unexpected token \"Identifer\", expected Keyword\nhint: did you mean \"print\"?", diags.Diagnostics[0].Message)
+
+	// Fixing the document clears the diagnostics
+	c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: "- print: 1\n"}},
+	})
+	assert.Empty(t, c.diagnostics().Diagnostics)
+}
+
+// Imports are loaded like yap run loads them. A cycle closed in the imported
+// module is shown on the import of this document
+func TestLSPImportDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	text := "- import: \"b.yap\"\n- import: \"missing.yap\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yap"), []byte(text), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.yap"), []byte("- import: \"a.yap\"\n"), 0o644))
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "a.yap"))
+
+	c := startServer(t)
+	c.initialize()
+	c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: uri, LanguageID: "yap", Version: 1, Text: text},
+	})
+	diags := c.diagnostics()
+	require.Equal(t, 2, len(diags.Diagnostics))
+
+	cycle := diags.Diagnostics[0]
+	assert.Equal(t, "E3009", cycle.Code)
+	assert.Contains(t, cycle.Message, "error in imported module")
+	assert.Equal(t, lsp.Range{Start: lsp.Position{Line: 0, Character: 10}, End: lsp.Position{Line: 0, Character: 17}}, cycle.Range)
+
+	missing := diags.Diagnostics[1]
+	assert.Equal(t, "E3008", missing.Code)
+	assert.Contains(t, missing.Message, `module "missing.yap" not found`)
+	assert.Equal(t, lsp.Position{Line: 1, Character: 10}, missing.Range.Start)
+}
+
+func TestLSPHover(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.open(testDoc)
+
+	// Hover over "x" in "- if: x > 5"
+	var h lsp.Hover
+	c.result(c.call("textDocument/hover", position(4, 6)), &h)
+	assert.Equal(t, "```yap\nx: int\n```", h.Contents.Value)
+	require.NotNil(t, h.Range)
+	assert.Equal(t, lsp.Range{Start: lsp.Position{Line: 4, Character: 6}, End: lsp.Position{Line: 4, Character: 7}}, *h.Range)
+
+	// Hover over the name of an assignment
+	c.result(c.call("textDocument/hover", position(2, 5)), &h)
+	assert.Equal(t, "```yap\nname: string\n```", h.Contents.Value)
+
+	// Hover over a literal shows its type
+	c.result(c.call("textDocument/hover", position(4, 10)), &h)
+	assert.Equal(t, "```yap\nint\n```", h.Contents.Value)
+
+	// Nothing to show on a keyword
+	msg := c.call("textDocument/hover", position(0, 2))
+	assert.Equal(t, "null", string(msg["result"]))
+}
+
+func TestLSPDefinition(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.open(testDoc)
+
+	// "name" in "- print: name + x" is assigned on line 3
+	var loc lsp.Location
+	c.result(c.call("textDocument/definition", position(3, 10)), &loc)
+	assert.Equal(t, testURI, loc.URI)
+	assert.Equal(t, lsp.Range{Start: lsp.Position{Line: 2, Character: 4}, End: lsp.Position{Line: 2, Character: 8}}, loc.Range)
+
+	// Undefined variables have no definition
+	msg := c.call("textDocument/definition", position(6, 13))
+	assert.Equal(t, "null", string(msg["result"]))
+}
+
+func TestLSPDocumentSymbols(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.open(testDoc)
+
+	var symbols []lsp.DocumentSymbol
+	c.result(c.call("textDocument/documentSymbol", lsp.DocumentParams{TextDocument: lsp.TextDocumentIdentifier{URI: testURI}}), &symbols)
+
+	require.Equal(t, 2, len(symbols))
+	assert.Equal(t, "x", symbols[0].Name)
+	assert.Equal(t, "int", symbols[0].Detail)
+	assert.Equal(t, lsp.SymbolKindVariable, symbols[0].Kind)
+	assert.Equal(t, "name", symbols[1].Name)
+	assert.Equal(t, "string", symbols[1].Detail)
+}
+
+func TestLSPCompletion(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.open(testDoc)
+
+	var items []lsp.CompletionItem
+	c.result(c.call("textDocument/completion", position(6, 13)), &items)
+
+	labels := map[string]int{}
+	for _, item := range items {
+		labels[item.Label] = item.Kind
+	}
+	assert.Equal(t, lsp.CompletionItemKindKeyword, labels["print"])
+	assert.Equal(t, lsp.CompletionItemKindKeyword, labels["True"])
+	assert.Equal(t, lsp.CompletionItemKindVariable, labels["x"])
+	assert.Equal(t, lsp.CompletionItemKindVariable, labels["name"])
+
+	// Variables assigned below the cursor are not offered
+	c.result(c.call("textDocument/completion", position(1, 0)), &items)
+	for _, item := range items {
+		assert.NotEqual(t, "name", item.Label)
+	}
+}
+
+func TestLSPFormatting(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.open("-print:   1+2\n")
+
+	var edits []lsp.TextEdit
+	c.result(c.call("textDocument/formatting", lsp.DocumentParams{TextDocument: lsp.TextDocumentIdentifier{URI: testURI}}), &edits)
+
+	require.Equal(t, 1, len(edits))
+	assert.Equal(t, "- print: 1 + 2\n", edits[0].NewText)
+	assert.Equal(t, lsp.Position{Line: 0, Character: 0}, edits[0].Range.Start)
+}
+
+func TestLSPUnknownMethod(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+
+	msg := c.call("workspace/unknown", nil)
+	assert.Contains(t, string(msg["error"]), "method not found")
+}
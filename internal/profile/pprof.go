@@ -0,0 +1,217 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// Field numbers of the pprof profile.proto messages that are written
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format read
+// by go tool pprof. Each line is a location in its function, with samples of
+// the instruction count and time spent there. Time spent in a builtin
+// function is a sample of the function called from the line
+func (p *Profiler) WritePprof(w io.Writer) error {
+	table := newStringTable()
+	var out protoBuffer
+
+	for _, vt := range [][2]string{{"instructions", "count"}, {"time", "nanoseconds"}} {
+		var m protoBuffer
+		m.int64Field(valueTypeType, table.index(vt[0]))
+		m.int64Field(valueTypeUnit, table.index(vt[1]))
+		out.messageField(profileSampleType, &m)
+	}
+
+	type function struct {
+		name, file string
+	}
+	funcIDs := map[function]uint64{}
+	var funcs []function
+	funcID := func(fn function) uint64 {
+		fid, ok := funcIDs[fn]
+		if !ok {
+			funcs = append(funcs, fn)
+			fid = uint64(len(funcs))
+			funcIDs[fn] = fid
+		}
+		return fid
+	}
+
+	var locations uint64
+	location := func(fid uint64, line int) uint64 {
+		locations++
+		var l protoBuffer
+		l.uint64Field(lineFunctionID, fid)
+		l.int64Field(lineLine, int64(line))
+		var loc protoBuffer
+		loc.uint64Field(locationID, locations)
+		loc.messageField(locationLine, &l)
+		out.messageField(profileLocation, &loc)
+		return locations
+	}
+	sample := func(stack []uint64, count int, d time.Duration) {
+		var s protoBuffer
+		s.packedUint64(sampleLocationID, stack)
+		s.packedInt64(sampleValue, []int64{int64(count), int64(d)})
+		out.messageField(profileSample, &s)
+	}
+
+	// Builtin functions have a location without a line, called from the
+	// location of each line calling them
+	builtins := map[string]uint64{}
+	for _, line := range p.Lines() {
+		lid := location(funcID(function{line.Function, line.File}), line.Line)
+		self := line.Time
+		for _, call := range line.Calls {
+			self -= call.Time
+		}
+		sample([]uint64{lid}, line.Count, self)
+
+		for _, call := range line.Calls {
+			cid, ok := builtins[call.Function]
+			if !ok {
+				cid = location(funcID(function{call.Function, ""}), 0)
+				builtins[call.Function] = cid
+			}
+			sample([]uint64{cid, lid}, 0, call.Time)
+		}
+	}
+
+	for i, fn := range funcs {
+		var f protoBuffer
+		f.uint64Field(functionID, uint64(i+1))
+		f.int64Field(functionName, table.index(fn.name))
+		f.int64Field(functionSystemName, table.index(fn.name))
+		f.int64Field(functionFilename, table.index(fn.file))
+		out.messageField(profileFunction, &f)
+	}
+
+	if !p.start.IsZero() {
+		out.int64Field(profileTimeNanos, p.start.UnixNano())
+	}
+	out.int64Field(profileDurationNanos, int64(p.total/time.Nanosecond))
+
+	// The string table goes last so it holds every string used above
+	for _, s := range table.strings {
+		out.stringField(profileStringTable, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(out.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// stringTable interns the strings of a profile. Index 0 is the empty string
+type stringTable struct {
+	strings []string
+	indexes map[string]int64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{strings: []string{""}, indexes: map[string]int64{"": 0}}
+}
+
+func (t *stringTable) index(s string) int64 {
+	if i, ok := t.indexes[s]; ok {
+		return i
+	}
+	i := int64(len(t.strings))
+	t.strings = append(t.strings, s)
+	t.indexes[s] = i
+	return i
+}
+
+// protoBuffer encodes protocol buffer fields
+type protoBuffer struct {
+	data []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protoBuffer) key(field, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *protoBuffer) uint64Field(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int64Field(field int, x int64) {
+	b.uint64Field(field, uint64(x))
+}
+
+func (b *protoBuffer) bytesField(field int, data []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+// stringField writes s even when it is empty, as repeated string table
+// entries must keep their positions
+func (b *protoBuffer) stringField(field int, s string) {
+	b.bytesField(field, []byte(s))
+}
+
+func (b *protoBuffer) messageField(field int, m *protoBuffer) {
+	b.bytesField(field, m.data)
+}
+
+func (b *protoBuffer) packedUint64(field int, xs []uint64) {
+	var p protoBuffer
+	for _, x := range xs {
+		p.varint(x)
+	}
+	b.bytesField(field, p.data)
+}
+
+func (b *protoBuffer) packedInt64(field int, xs []int64) {
+	var p protoBuffer
+	for _, x := range xs {
+		p.varint(uint64(x))
+	}
+	b.bytesField(field, p.data)
+}
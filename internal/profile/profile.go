@@ -0,0 +1,211 @@
+// Package profile measures where a YAP program spends its time. It counts
+// the instructions executed for each source line and function and the time
+// spent on them
+package profile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// MainFunction is the function the statements of a program are attributed
+// to. Time spent in builtin functions is also attributed to the function
+// called, see Line.Calls
+const MainFunction = "main"
+
+// Line holds the measurements of one source line
+type Line struct {
+	File     string
+	Line     int
+	Function string
+	Count    int           // Executed instructions
+	Time     time.Duration // Time spent executing them, including Calls
+	Calls    []*Call       // Builtin functions called by the line
+}
+
+// Call holds the measurements of the calls of a builtin function by a line
+type Call struct {
+	Function string
+	Count    int
+	Time     time.Duration
+}
+
+type lineKey struct {
+	file string
+	line int
+}
+
+// Profiler is a VM hook that attributes every executed instruction and the
+// time until the next one to the line of its statement. Stop must be called
+// after the run to account for the last instruction
+type Profiler struct {
+	lines   map[lineKey]*Line
+	current *Line     // line of the running instruction
+	since   time.Time // when the running instruction started
+	called  time.Time // when the running builtin function was called
+	total   time.Duration
+	start   time.Time
+	now     func() time.Time
+}
+
+func New() *Profiler {
+	return &Profiler{lines: map[lineKey]*Line{}, now: time.Now}
+}
+
+// BeforeInstruction implements vm.Hook
+func (p *Profiler) BeforeInstruction(m *vm.VM, pc int, instr ir.Instruction) *yaperror.YapError {
+	now := p.now()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	p.account(now)
+
+	// Jumps added by the builder belong to the statement that ran before them
+	if span := instr.Span; span.File != nil && span.Start.Line > 0 {
+		key := lineKey{span.File.Path, span.Start.Line}
+		line, ok := p.lines[key]
+		if !ok {
+			line = &Line{File: key.file, Line: key.line, Function: MainFunction}
+			p.lines[key] = line
+		}
+		p.current = line
+	}
+	if p.current != nil {
+		p.current.Count++
+	}
+	p.since = now
+	return nil
+}
+
+// BeforeCall implements vm.CallHook
+func (p *Profiler) BeforeCall(m *vm.VM, fn *stdlib.Func) {
+	p.called = p.now()
+}
+
+// AfterCall implements vm.CallHook
+func (p *Profiler) AfterCall(m *vm.VM, fn *stdlib.Func) {
+	if p.current == nil {
+		return
+	}
+	var call *Call
+	for _, c := range p.current.Calls {
+		if c.Function == fn.Name {
+			call = c
+		}
+	}
+	if call == nil {
+		call = &Call{Function: fn.Name}
+		p.current.Calls = append(p.current.Calls, call)
+	}
+	call.Count++
+	call.Time += p.now().Sub(p.called)
+}
+
+// Stop ends the measurement of the last instruction
+func (p *Profiler) Stop() {
+	p.account(p.now())
+	p.current = nil
+}
+
+func (p *Profiler) account(now time.Time) {
+	if p.current == nil {
+		return
+	}
+	d := now.Sub(p.since)
+	p.current.Time += d
+	p.total += d
+}
+
+// Lines returns the measured lines, most expensive first
+func (p *Profiler) Lines() []*Line {
+	lines := make([]*Line, 0, len(p.lines))
+	for _, line := range p.lines {
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		a, b := lines[i], lines[j]
+		if a.Time != b.Time {
+			return a.Time > b.Time
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return lines
+}
+
+// Function holds the measurements of all lines of a function, or of all
+// calls of a builtin function
+type Function struct {
+	Name  string
+	Count int           // Executed instructions, or calls of a builtin function
+	Time  time.Duration // Time spent in the function and the functions it called
+}
+
+// Functions returns the measured functions, most expensive first
+func (p *Profiler) Functions() []*Function {
+	byName := map[string]*Function{}
+	var funcs []*Function
+	add := func(name string, count int, d time.Duration) {
+		f, ok := byName[name]
+		if !ok {
+			f = &Function{Name: name}
+			byName[name] = f
+			funcs = append(funcs, f)
+		}
+		f.Count += count
+		f.Time += d
+	}
+	for _, line := range p.Lines() {
+		add(line.Function, line.Count, line.Time)
+		for _, call := range line.Calls {
+			add(call.Function, call.Count, call.Time)
+		}
+	}
+	sort.SliceStable(funcs, func(i, j int) bool { return funcs[i].Time > funcs[j].Time })
+	return funcs
+}
+
+// WriteText writes a table of lines and one of functions, most expensive
+// first, with the share of the total time and the running sum of shares
+func (p *Profiler) WriteText(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	var totalCount int
+	for _, line := range p.lines {
+		totalCount += line.Count
+	}
+	fmt.Fprintf(w, "Total: %d instructions, %v\n", totalCount, p.total)
+
+	fmt.Fprintf(w, "%12s %12s %7s %7s  %s\n", "count", "flat", "flat%", "sum%", "line")
+	var sum time.Duration
+	for _, line := range p.Lines() {
+		sum += line.Time
+		fmt.Fprintf(w, "%12d %12v %6.2f%% %6.2f%%  %s:%d\n",
+			line.Count, line.Time, p.share(line.Time), p.share(sum), line.File, line.Line)
+	}
+
+	fmt.Fprintf(w, "\n%12s %12s %7s  %s\n", "count", "cum", "cum%", "function")
+	for _, f := range p.Functions() {
+		fmt.Fprintf(w, "%12d %12v %6.2f%%  %s\n", f.Count, f.Time, p.share(f.Time), f.Name)
+	}
+	return w.Flush()
+}
+
+func (p *Profiler) share(d time.Duration) float64 {
+	if p.total == 0 {
+		return 0
+	}
+	return 100 * float64(d) / float64(p.total)
+}
@@ -0,0 +1,170 @@
+package profile_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const src = `- set:
+  - x: 1
+- if: x > 5
+  then:
+    - print: "big"
+  else:
+    - print: "small"
+- print: x
+`
+
+func runProfiled(t *testing.T) *profile.Profiler {
+	t.Helper()
+	return profileSource(t, src)
+}
+
+func profileSource(t *testing.T, src string) *profile.Profiler {
+	t.Helper()
+	prog, err := parser.NewParserFromBytes([]byte(src), "profile.yap").Parse()
+	require.NoError(t, err)
+	program, err := build.New().Build(prog.Statements)
+	require.NoError(t, err)
+
+	p := profile.New()
+	require.Nil(t, vm.New(program, vm.WithHook(p), vm.WithStdout(io.Discard)).Run())
+	p.Stop()
+	return p
+}
+
+func TestProfilerCountsPerLine(t *testing.T) {
+	p := runProfiled(t)
+
+	counts := map[int]int{}
+	for _, line := range p.Lines() {
+		assert.Equal(t, "profile.yap", line.File)
+		assert.Equal(t, profile.MainFunction, line.Function)
+		counts[line.Line] = line.Count
+	}
+	assert.Equal(t, map[int]int{2: 1, 3: 1, 7: 1, 8: 1}, counts)
+
+	funcs := p.Functions()
+	require.Equal(t, 1, len(funcs))
+	assert.Equal(t, profile.MainFunction, funcs[0].Name)
+	assert.Equal(t, 4, funcs[0].Count)
+}
+
+func TestProfilerCountsCalls(t *testing.T) {
+	p := profileSource(t, `- import: "strings"
+- set:
+  - s: strings.repeat("ab", 3)
+- print: strings.upper(strings.repeat(s, 2))
+`)
+
+	calls := map[int]map[string]int{}
+	for _, line := range p.Lines() {
+		calls[line.Line] = map[string]int{}
+		for _, call := range line.Calls {
+			calls[line.Line][call.Function] = call.Count
+		}
+	}
+	assert.Equal(t, map[int]map[string]int{
+		1: {},
+		3: {"strings.repeat": 1},
+		4: {"strings.repeat": 1, "strings.upper": 1},
+	}, calls)
+
+	counts := map[string]int{}
+	for _, f := range p.Functions() {
+		counts[f.Name] = f.Count
+	}
+	assert.Equal(t, map[string]int{profile.MainFunction: 3, "strings.repeat": 2, "strings.upper": 1}, counts)
+
+	var out bytes.Buffer
+	require.NoError(t, p.WritePprof(&out))
+	zr, err := gzip.NewReader(&out)
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	_, fields := protoFields(t, data)
+	assert.Equal(t, 6, len(fields[2]), "samples")
+	assert.Equal(t, 5, len(fields[4]), "locations")
+	assert.Equal(t, 3, len(fields[5]), "functions")
+}
+
+func TestProfilerText(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, runProfiled(t).WriteText(&out))
+	text := out.String()
+
+	assert.Contains(t, text, "Total: 4 instructions")
+	assert.Contains(t, text, "profile.yap:7\n")
+	assert.Contains(t, text, "100.00%  main\n")
+}
+
+// protoFields decodes the top-level fields of a protocol buffer message,
+// returning the varints and the length-delimited values by field number
+func protoFields(t *testing.T, data []byte) (map[int][]uint64, map[int][][]byte) {
+	t.Helper()
+	varints, bytesFields := map[int][]uint64{}, map[int][][]byte{}
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		require.Greater(t, n, 0)
+		data = data[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case 0:
+			v, n := binary.Uvarint(data)
+			require.Greater(t, n, 0)
+			varints[field] = append(varints[field], v)
+			data = data[n:]
+		case 2:
+			l, n := binary.Uvarint(data)
+			require.Greater(t, n, 0)
+			data = data[n:]
+			bytesFields[field] = append(bytesFields[field], data[:l])
+			data = data[l:]
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+	return varints, bytesFields
+}
+
+func TestProfilerPprof(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, runProfiled(t).WritePprof(&out))
+
+	zr, err := gzip.NewReader(&out)
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	_, fields := protoFields(t, data)
+	var stringTable []string
+	for _, s := range fields[6] {
+		stringTable = append(stringTable, string(s))
+	}
+	assert.Equal(t, []string{"", "instructions", "count", "time", "nanoseconds", "main", "profile.yap"}, stringTable)
+	assert.Equal(t, 2, len(fields[1]), "sample types")
+	assert.Equal(t, 4, len(fields[2]), "samples")
+	assert.Equal(t, 4, len(fields[4]), "locations")
+	assert.Equal(t, 1, len(fields[5]), "functions")
+
+	// Every sample has one location and values for count and time
+	var total uint64
+	for _, sample := range fields[2] {
+		_, sf := protoFields(t, sample)
+		require.Equal(t, 1, len(sf[1]))
+		count, n := binary.Uvarint(sf[2][0])
+		require.Greater(t, n, 0)
+		total += count
+	}
+	assert.Equal(t, uint64(4), total)
+}
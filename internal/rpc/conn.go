@@ -0,0 +1,83 @@
+// Package rpc implements the base protocol shared by the Language Server
+// Protocol and the Debug Adapter Protocol: JSON messages framed by a
+// Content-Length header.
+package rpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const headerContentLength = "Content-Length"
+
+// Conn reads and writes framed messages. Writes are safe for concurrent use
+type Conn struct {
+	r  *bufio.Reader
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{
+		r: bufio.NewReader(r),
+		w: w,
+	}
+}
+
+// Read returns the body of the next message
+func (c *Conn) Read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break // end of headers
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), headerContentLength) {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", headerContentLength, value)
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("missing %s header", headerContentLength)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Write marshals v to JSON and writes it as a single message
+func (c *Conn) Write(v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.w, "%s: %d\r\n\r\n", headerContentLength, len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
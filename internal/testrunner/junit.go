@@ -0,0 +1,87 @@
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// JUnit XML as read by CI servers. Every test file is a test case of a
+// single suite
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// SuiteName is the name of the test suite in JUnit reports
+const SuiteName = "yap"
+
+// WriteJUnit writes results as a JUnit XML report
+func WriteJUnit(w io.Writer, results []*Result) error {
+	suite := junitSuite{Name: SuiteName, Tests: len(results), Cases: []junitCase{}}
+	var total time.Duration
+	for _, r := range results {
+		total += r.Duration
+		c := junitCase{
+			Name:      r.File,
+			ClassName: SuiteName,
+			Time:      seconds(r.Duration),
+			SystemOut: r.Output,
+		}
+		if !r.Passed() {
+			suite.Failures++
+			c.Failure = &junitFailure{
+				Message: r.Message(),
+				Text:    fmt.Sprintf("%s: %s", r.Location(), r.Message()),
+			}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Time = seconds(total)
+
+	doc := junitSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
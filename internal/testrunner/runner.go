@@ -0,0 +1,141 @@
+// Package testrunner discovers and runs YAP test files. Every *_test.yap
+// file is one test: it passes if it runs to the end, and fails at the first
+// failed assert or runtime error it does not expect
+package testrunner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+)
+
+// FileSuffix is the suffix of the file names of YAP tests
+const FileSuffix = "_test.yap"
+
+// IsTestFile reports whether path names a YAP test file
+func IsTestFile(path string) bool {
+	return strings.HasSuffix(strings.ToLower(filepath.Base(path)), FileSuffix)
+}
+
+// Discover returns the test files in paths. Directories are searched
+// recursively, files are kept whatever their name. Only the files whose path
+// matches filter, if any, are returned
+func Discover(paths []string, filter *regexp.Regexp) ([]string, error) {
+	files := []string{}
+	add := func(path string) {
+		if filter == nil || filter.MatchString(path) {
+			files = append(files, path)
+		}
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error finding file: %w", err)
+		}
+		if !info.IsDir() {
+			add(path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && IsTestFile(p) {
+				add(p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error reading directory: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// Result is the outcome of running one test file
+type Result struct {
+	File     string
+	Err      error  // Why the test failed, nil if it passed
+	Line     int    // Line of the failing statement, 0 if unknown
+	Column   int    // Column of the failing statement, 0 if unknown
+	Output   string // Everything the test printed to stdout and stderr
+	Duration time.Duration
+}
+
+// Passed reports whether the test passed
+func (r *Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Location returns the position of the failure as file:line:col, or just
+// the file if the position is unknown
+func (r *Result) Location() string {
+	if r.Line == 0 {
+		return r.File
+	}
+	return fmt.Sprintf("%s:%d:%d", r.File, r.Line, r.Column)
+}
+
+// Message describes the failure without its position, which Location reports
+func (r *Result) Message() string {
+	var yerr *yaperror.YapError
+	if errors.As(r.Err, &yerr) {
+		return yerr.Message
+	}
+	return r.Err.Error()
+}
+
+// Run runs the test file in a VM of its own. Output is captured in the result
+// and opts are applied after the runner's own options
+func Run(file string, opts ...vm.Option) *Result {
+	result := &Result{File: file}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	result.Err = run(file, result, opts)
+	var yerr *yaperror.YapError
+	if errors.As(result.Err, &yerr) {
+		result.Line, result.Column = yerr.Position.Line, yerr.Position.Column
+	}
+	return result
+}
+
+// run compiles and runs file, capturing its output in result
+func run(file string, result *Result, opts []vm.Option) error {
+	prog, err := parser.NewParser(file).Parse()
+	if err != nil {
+		return fmt.Errorf("error parsing program: %w", err)
+	}
+	program, err := build.New().Build(prog.Statements)
+	if err != nil {
+		return fmt.Errorf("error building program: %w", err)
+	}
+
+	var output bytes.Buffer
+	// Tests run unattended, so input statements find an empty input
+	opts = append([]vm.Option{vm.WithStdin(strings.NewReader("")), vm.WithStdout(&output), vm.WithStderr(&output)}, opts...)
+	v := vm.New(program, opts...)
+	yerr := v.Run()
+	result.Output = output.String()
+	if yerr != nil {
+		return yerr
+	}
+	// A test may end early with exit: 0, any other code fails it
+	if code := v.ExitCode(); code != 0 {
+		return fmt.Errorf("test exited with code %d", code)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package testrunner_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/testrunner"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFileDir = "../.."
+
+var testsDir = test_util.GetTestFilepath(test_util.TestsDir, testFileDir)
+
+func TestDiscover(t *testing.T) {
+	files, err := testrunner.Discover([]string{testsDir}, nil)
+	require.NoError(t, err)
+
+	// helper.yap is not a test file
+	assert.Equal(t, []string{
+		filepath.Join(testsDir, "error_test.yap"),
+		filepath.Join(testsDir, "math_test.yap"),
+		filepath.Join(testsDir, "nested", "strings_test.yap"),
+	}, files)
+}
+
+func TestDiscoverFilter(t *testing.T) {
+	files, err := testrunner.Discover([]string{testsDir}, regexp.MustCompile(`nested/`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(testsDir, "nested", "strings_test.yap")}, files)
+}
+
+func TestRunPassing(t *testing.T) {
+	r := testrunner.Run(filepath.Join(testsDir, "math_test.yap"))
+
+	assert.True(t, r.Passed())
+	assert.Empty(t, r.Output)
+}
+
+func TestRunFailedAssertion(t *testing.T) {
+	file := filepath.Join(testsDir, "nested", "strings_test.yap")
+	r := testrunner.Run(file)
+
+	require.False(t, r.Passed())
+	assert.Equal(t, file+":5:1", r.Location())
+	assert.Equal(t, "assertion failed: greeting should be goodbye", r.Message())
+	assert.Equal(t, "hello\n", r.Output)
+}
+
+func TestRunErrorNotRaised(t *testing.T) {
+	file := filepath.Join(testsDir, "error_test.yap")
+	r := testrunner.Run(file)
+
+	require.False(t, r.Passed())
+	var yerr *yaperror.YapError
+	require.ErrorAs(t, r.Err, &yerr)
+	assert.Equal(t, yaperror.ErrErrorNotRaised, yerr.Code)
+	// The failure is reported at the start of the block
+	assert.Equal(t, file+":1:1", r.Location())
+}
+
+func TestWriteJUnit(t *testing.T) {
+	results := []*testrunner.Result{
+		{File: "ok_test.yap"},
+		testrunner.Run(filepath.Join(testsDir, "nested", "strings_test.yap")),
+	}
+	results[1].File = "strings_test.yap"
+	results[1].Duration = 0
+
+	var out bytes.Buffer
+	require.NoError(t, testrunner.WriteJUnit(&out, results))
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="2" failures="1" time="0.000">
+  <testsuite name="yap" tests="2" failures="1" time="0.000">
+    <testcase name="ok_test.yap" classname="yap" time="0.000"></testcase>
+    <testcase name="strings_test.yap" classname="yap" time="0.000">
+      <failure message="assertion failed: greeting should be goodbye">strings_test.yap:5:1: assertion failed: greeting should be goodbye</failure>
+      <system-out>hello&#xA;</system-out>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, out.String())
+}
+
+func TestRunExit(t *testing.T) {
+	dir := t.TempDir()
+	passing := filepath.Join(dir, "early_test.yap")
+	require.NoError(t, os.WriteFile(passing, []byte("- exit: 0\n- assert: False\n"), 0o644))
+	failing := filepath.Join(dir, "exit_test.yap")
+	require.NoError(t, os.WriteFile(failing, []byte("- print: \"bye\"\n- exit: 2\n"), 0o644))
+
+	assert.True(t, testrunner.Run(passing).Passed())
+
+	r := testrunner.Run(failing)
+	require.False(t, r.Passed())
+	assert.Equal(t, "test exited with code 2", r.Message())
+	assert.Equal(t, "bye\n", r.Output)
+}
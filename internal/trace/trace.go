@@ -0,0 +1,228 @@
+// Package trace records every instruction a VM executes, for auditing runs
+// and diffing the behaviour of two versions of a script
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+)
+
+// Format selects how events are written
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json" // one JSON object per line
+)
+
+// Validate reports an error unless f is a known format
+func (f Format) Validate() error {
+	if f != FormatText && f != FormatJSON {
+		return fmt.Errorf("unknown trace format %q, expected %s or %s", f, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+// LineRange limits a trace to instructions from lines From to To inclusive.
+// A zero bound is open
+type LineRange struct {
+	From int
+	To   int
+}
+
+// Contains reports whether line lies in the range. Instructions the builder
+// added have no line and only appear in unfiltered traces
+func (r LineRange) Contains(line int) bool {
+	if r == (LineRange{}) {
+		return true
+	}
+	if line == 0 {
+		return false
+	}
+	return (r.From == 0 || line >= r.From) && (r.To == 0 || line <= r.To)
+}
+
+// ParseLineRange parses "N", "N-M", "N-" or "-M"
+func ParseLineRange(s string) (LineRange, error) {
+	if s == "" {
+		return LineRange{}, nil
+	}
+	from, to, isRange := strings.Cut(s, "-")
+	if !isRange {
+		to = from
+	}
+
+	var r LineRange
+	var err error
+	if from != "" {
+		if r.From, err = strconv.Atoi(from); err != nil || r.From < 1 {
+			return LineRange{}, fmt.Errorf("invalid line range %q", s)
+		}
+	}
+	if to != "" {
+		if r.To, err = strconv.Atoi(to); err != nil || r.To < 1 {
+			return LineRange{}, fmt.Errorf("invalid line range %q", s)
+		}
+	}
+	if r == (LineRange{}) || (r.To != 0 && r.From > r.To) {
+		return LineRange{}, fmt.Errorf("invalid line range %q", s)
+	}
+	return r, nil
+}
+
+// Event is one executed instruction
+type Event struct {
+	PC   int    `json:"pc"`
+	Op   string `json:"op"`
+	Line int    `json:"line,omitempty"`
+
+	// Result of the condition of an OpJumpIfFalse
+	Cond *bool `json:"cond,omitempty"`
+
+	// Variable written by an OpSet. Old is omitted if it was not set before
+	Var string      `json:"var,omitempty"`
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// Tracer is a VM hook that writes an Event for each executed instruction.
+// An event is written once its instruction has finished, so Flush must be
+// called after the run
+type Tracer struct {
+	w      *bufio.Writer
+	format Format
+	lines  LineRange
+
+	pending *Event
+	err     error // first write error
+}
+
+func New(w io.Writer, format Format, lines LineRange) (*Tracer, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	return &Tracer{w: bufio.NewWriter(w), format: format, lines: lines}, nil
+}
+
+// BeforeInstruction implements vm.Hook
+func (t *Tracer) BeforeInstruction(m *vm.VM, pc int, instr ir.Instruction) *yaperror.YapError {
+	t.emit()
+	if t.lines.Contains(instr.Span.Start.Line) {
+		t.pending = &Event{PC: pc, Op: instr.Op.String(), Line: instr.Span.Start.Line}
+	}
+	return nil
+}
+
+// AfterSet implements vm.SetHook
+func (t *Tracer) AfterSet(m *vm.VM, pc int, name string, old interface{}, existed bool, val interface{}) {
+	if t.pending == nil {
+		return
+	}
+	t.pending.Var = name
+	t.pending.New = val
+	if existed {
+		t.pending.Old = old
+	}
+}
+
+// AfterBranch implements vm.BranchHook
+func (t *Tracer) AfterBranch(m *vm.VM, pc int, cond bool) {
+	if t.pending == nil {
+		return
+	}
+	t.pending.Cond = &cond
+}
+
+// Flush writes the last event and any buffered output
+func (t *Tracer) Flush() error {
+	t.emit()
+	if t.err != nil {
+		return t.err
+	}
+	return t.w.Flush()
+}
+
+func (t *Tracer) emit() {
+	if t.pending == nil || t.err != nil {
+		t.pending = nil
+		return
+	}
+	e := t.pending
+	t.pending = nil
+
+	if t.format == FormatJSON {
+		enc := *e
+		if e.Old != nil {
+			enc.Old = jsonValue(e.Old)
+		}
+		if e.New != nil {
+			enc.New = jsonValue(e.New)
+		}
+		data, err := marshal(enc)
+		if err != nil {
+			t.err = err
+			return
+		}
+		_, t.err = t.w.Write(data)
+		return
+	}
+	_, t.err = io.WriteString(t.w, formatText(e)+"\n")
+}
+
+// formatText writes an event as "pc op line=N" followed by its details,
+// e.g. "0003 SET line=4 x: 1 -> 2"
+func formatText(e *Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%04d %s", e.PC, e.Op)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, " line=%d", e.Line)
+	}
+	if e.Cond != nil {
+		fmt.Fprintf(&b, " cond=%t", *e.Cond)
+	}
+	if e.Var != "" {
+		old := "<unset>"
+		if e.Old != nil {
+			old = formatValue(e.Old)
+		}
+		fmt.Fprintf(&b, " %s: %s -> %s", e.Var, old, formatValue(e.New))
+	}
+	return b.String()
+}
+
+// jsonValue encodes a value for a JSON event. Values JSON cannot hold, such
+// as functions and NaN, are written as their text
+func jsonValue(val interface{}) json.RawMessage {
+	data, err := marshal(val)
+	if err != nil {
+		data, _ = marshal(vm.FormatValue(val))
+	}
+	return data
+}
+
+// marshal encodes v as a line of JSON, keeping <, > and & as they are
+func marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func formatValue(val interface{}) string {
+	if s, ok := val.(string); ok {
+		return strconv.Quote(s)
+	}
+	return vm.FormatValue(val)
+}
@@ -0,0 +1,113 @@
+package trace_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/rlamalama/YAP/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const src = `- set:
+  - x: 1
+- if: x > 5
+  then:
+    - print: "big"
+  else:
+    - set:
+      - x: x + 1
+- print: x
+`
+
+func runTraced(t *testing.T, format trace.Format, lines trace.LineRange) string {
+	t.Helper()
+	return traceSource(t, src, format, lines)
+}
+
+func traceSource(t *testing.T, src string, format trace.Format, lines trace.LineRange) string {
+	t.Helper()
+	prog, err := parser.NewParserFromBytes([]byte(src), "trace.yap").Parse()
+	require.NoError(t, err)
+	program, err := build.New().Build(prog.Statements)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	tracer, err := trace.New(&out, format, lines)
+	require.NoError(t, err)
+	require.Nil(t, vm.New(program, vm.WithHook(tracer), vm.WithStdout(io.Discard)).Run())
+	require.NoError(t, tracer.Flush())
+	return out.String()
+}
+
+func TestTraceText(t *testing.T) {
+	expected := `0000 SET line=2 x: <unset> -> 1
+0001 JUMP_IF_FALSE line=3 cond=false
+0004 SET line=8 x: 1 -> 2
+0005 PRINT line=9
+`
+	assert.Equal(t, expected, runTraced(t, trace.FormatText, trace.LineRange{}))
+}
+
+func TestTraceJSON(t *testing.T) {
+	expected := `{"pc":0,"op":"SET","line":2,"var":"x","new":1}
+{"pc":1,"op":"JUMP_IF_FALSE","line":3,"cond":false}
+{"pc":4,"op":"SET","line":8,"var":"x","old":1,"new":2}
+{"pc":5,"op":"PRINT","line":9}
+`
+	assert.Equal(t, expected, runTraced(t, trace.FormatJSON, trace.LineRange{}))
+}
+
+func TestTraceJSONValues(t *testing.T) {
+	src := `- import: "math"
+- set:
+  - f: str
+  - x: math.pow(0.0 - 1.0, 0.5)
+  - d: yaml_parse("a:")
+`
+	expected := `{"pc":0,"op":"IMPORT","line":1}
+{"pc":1,"op":"SET","line":3,"var":"f","new":"<function str>"}
+{"pc":2,"op":"SET","line":4,"var":"x","new":"NaN"}
+{"pc":3,"op":"SET","line":5,"var":"d","new":{"a":null}}
+`
+	assert.Equal(t, expected, traceSource(t, src, trace.FormatJSON, trace.LineRange{}))
+}
+
+func TestTraceLineRange(t *testing.T) {
+	output := runTraced(t, trace.FormatText, trace.LineRange{From: 3, To: 8})
+
+	assert.Equal(t, "0001 JUMP_IF_FALSE line=3 cond=false\n0004 SET line=8 x: 1 -> 2\n", output)
+}
+
+func TestTraceUnknownFormat(t *testing.T) {
+	_, err := trace.New(io.Discard, "xml", trace.LineRange{})
+
+	assert.EqualError(t, err, `unknown trace format "xml", expected text or json`)
+}
+
+func TestParseLineRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected trace.LineRange
+	}{
+		{"", trace.LineRange{}},
+		{"7", trace.LineRange{From: 7, To: 7}},
+		{"10-20", trace.LineRange{From: 10, To: 20}},
+		{"10-", trace.LineRange{From: 10}},
+		{"-20", trace.LineRange{To: 20}},
+	}
+	for _, tt := range tests {
+		r, err := trace.ParseLineRange(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.expected, r, tt.in)
+	}
+
+	for _, in := range []string{"-", "a-b", "0", "20-10", "1-2-3"} {
+		_, err := trace.ParseLineRange(in)
+		assert.Error(t, err, in)
+	}
+}
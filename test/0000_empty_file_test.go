@@ -6,11 +6,12 @@ import (
 
 	"github.com/rlamalama/YAP/cmd/yap/commands"
 	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEmptyFile(t *testing.T) {
 	filepath := filepath.Join(test_util.TestFilesDir, test_util.EmptyFileYAP)
 	args := []string{filepath}
 
-	commands.RunCmd(args)
+	require.NoError(t, commands.RunCmd(args))
 }
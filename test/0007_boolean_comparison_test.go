@@ -1,21 +1,24 @@
 package test
 
 import (
+	"bytes"
 	"path/filepath"
 	"testing"
 
 	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
 	test_util "github.com/rlamalama/YAP/test/test-util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBooleanComparison(t *testing.T) {
 	filepath := filepath.Join(test_util.TestFilesDir, test_util.BooleanComparisonYAP)
 	args := []string{filepath}
 
-	output := test_util.CaptureStdout(t, func() {
-		commands.RunCmd(args)
-	})
+	var out bytes.Buffer
+	require.NoError(t, commands.RunCmd(args, vm.WithStdout(&out)))
+	output := out.String()
 
 	expected :=
 		`true
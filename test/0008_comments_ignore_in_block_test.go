@@ -1,6 +1,7 @@
 package test
 
 import (
+	"bytes"
 	"path/filepath"
 	"testing"
 
@@ -25,15 +26,15 @@ func TestCommentsIgnoreInBlockUndefinedVar(t *testing.T) {
 	program, err := builder.Build(ast.Statements)
 	assert.Nil(t, err, "building should succeed")
 
-	output := test_util.CaptureStdout(t, func() {
-		v := vm.New(program)
-		err = v.Run()
+	var out bytes.Buffer
+	v := vm.New(program, vm.WithStdout(&out))
+	runErr := v.Run()
 
-		// Should error because 	output := test_util.CaptureStdout(t, func() {is undefined (it was commented out)
-		assert.NotNil(t, err, "should error because y is undefined")
-		assert.Contains(t, err.Error(), "y", "error should mention undefined variable y")
+	// Should error because y is undefined (it was commented out)
+	assert.NotNil(t, runErr, "should error because y is undefined")
+	assert.Contains(t, runErr.Error(), "y", "error should mention undefined variable y")
 
-	})
+	output := out.String()
 	expected :=
 		`10
 `
@@ -1,22 +1,25 @@
 package test
 
 import (
+	"bytes"
 	"path/filepath"
 	"testing"
 
 	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
 	"github.com/rlamalama/YAP/internal/frontend/parser"
 	test_util "github.com/rlamalama/YAP/test/test-util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIfThenElse(t *testing.T) {
 	filepath := filepath.Join(test_util.TestFilesDir, test_util.IfThenElseYAP)
 	args := []string{filepath}
 
-	output := test_util.CaptureStdout(t, func() {
-		commands.RunCmd(args)
-	})
+	var out bytes.Buffer
+	require.NoError(t, commands.RunCmd(args, vm.WithStdout(&out)))
+	output := out.String()
 
 	expected :=
 		`x is big
@@ -30,9 +33,9 @@ func TestEmptyElse(t *testing.T) {
 	filepath := filepath.Join(test_util.TestFilesDir, test_util.EmptyElseYAP)
 	args := []string{filepath}
 
-	output := test_util.CaptureStdout(t, func() {
-		commands.RunCmd(args)
-	})
+	var out bytes.Buffer
+	require.NoError(t, commands.RunCmd(args, vm.WithStdout(&out)))
+	output := out.String()
 
 	// x = 10, x > 5 is true, so "x is big" prints, empty else does nothing
 	expected := "x is big\n"
@@ -44,9 +47,9 @@ func TestEmptyThen(t *testing.T) {
 	filepath := filepath.Join(test_util.TestFilesDir, test_util.EmptyThenYAP)
 	args := []string{filepath}
 
-	output := test_util.CaptureStdout(t, func() {
-		commands.RunCmd(args)
-	})
+	var out bytes.Buffer
+	require.NoError(t, commands.RunCmd(args, vm.WithStdout(&out)))
+	output := out.String()
 
 	// x = 10, x > 5 is true, so empty then runs (nothing prints)
 	// else block is not executed
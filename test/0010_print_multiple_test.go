@@ -0,0 +1,30 @@
+package test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintMultiple(t *testing.T) {
+	filepath := filepath.Join(test_util.TestFilesDir, test_util.PrintMultipleYAP)
+	args := []string{filepath}
+
+	var out bytes.Buffer
+	require.NoError(t, commands.RunCmd(args, vm.WithStdout(&out)))
+	output := out.String()
+
+	expected :=
+		`name: YAP
+x = 10
+1, 2, 3
+no newline here
+`
+	assert.Equal(t, expected, output)
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const formattedUnformattedYAP = `// variables
+- set:  // all of them
+  - x: 10 + 5
+  - name: "YAP"
+
+- print: name, x
+- if: x > 10
+  then:
+    - print: "big"  // inline
+  else:
+    - print: "small"
+`
+
+func TestFmtPrintsFormattedSource(t *testing.T) {
+	filepath := filepath.Join(test_util.TestFilesDir, test_util.FmtUnformattedYAP)
+
+	var out bytes.Buffer
+	require.NoError(t, commands.FmtCmd([]string{filepath}, commands.FmtOptions{}, &out))
+
+	assert.Equal(t, formattedUnformattedYAP, out.String())
+}
+
+func TestFmtCheck(t *testing.T) {
+	unformatted := filepath.Join(test_util.TestFilesDir, test_util.FmtUnformattedYAP)
+	formatted := filepath.Join(test_util.TestFilesDir, test_util.PrintMultipleYAP)
+
+	var out bytes.Buffer
+	err := commands.FmtCmd([]string{unformatted}, commands.FmtOptions{Check: true}, &out)
+	assert.NotNil(t, err, "check should fail for an unformatted file")
+	assert.Equal(t, unformatted+"\n", out.String())
+
+	out.Reset()
+	err = commands.FmtCmd([]string{formatted}, commands.FmtOptions{Check: true}, &out)
+	assert.Nil(t, err, "check should pass for a formatted file")
+	assert.Equal(t, "", out.String())
+}
+
+func TestFmtDiff(t *testing.T) {
+	filepath := filepath.Join(test_util.TestFilesDir, test_util.FmtUnformattedYAP)
+
+	var out bytes.Buffer
+	require.NoError(t, commands.FmtCmd([]string{filepath}, commands.FmtOptions{Diff: true}, &out))
+
+	assert.Contains(t, out.String(), "--- "+filepath+".orig\n+++ "+filepath+"\n")
+	assert.Contains(t, out.String(), "\n--   set:   // all of them\n")
+	assert.Contains(t, out.String(), "\n+- set:  // all of them\n")
+}
+
+func TestFmtWrite(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join(test_util.TestFilesDir, test_util.FmtUnformattedYAP))
+	require.NoError(t, err)
+
+	tmp := filepath.Join(t.TempDir(), test_util.FmtUnformattedYAP)
+	require.NoError(t, os.WriteFile(tmp, src, 0o644))
+
+	var out bytes.Buffer
+	require.NoError(t, commands.FmtCmd([]string{tmp}, commands.FmtOptions{Write: true}, &out))
+	assert.Equal(t, "", out.String())
+
+	written, err := os.ReadFile(tmp)
+	require.NoError(t, err)
+	assert.Equal(t, formattedUnformattedYAP, string(written))
+
+	// The formatted program still runs the same way
+	var runOut bytes.Buffer
+	require.NoError(t, commands.RunCmd([]string{tmp}, vm.WithStdout(&runOut)))
+	assert.Equal(t, "YAP 15\nbig\n", runOut.String())
+}
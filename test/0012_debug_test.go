@@ -0,0 +1,84 @@
+package test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// debug runs the debug test file with the given commands and returns the session
+func debug(t *testing.T, breakpoints []int, commandLines ...string) string {
+	t.Helper()
+	fp := filepath.Join(test_util.TestFilesDir, test_util.DebugYAP)
+	input := ""
+	for _, line := range commandLines {
+		input += line + "\n"
+	}
+	in := strings.NewReader(input)
+
+	var out bytes.Buffer
+	require.NoError(t, commands.DebugCmd([]string{fp}, breakpoints, in, &out))
+	return out.String()
+}
+
+func TestDebugStep(t *testing.T) {
+	output := debug(t, nil, "s", "s", "s", "s", "s", "s")
+
+	expected := ` =>    2 |   - x: 10
+(yap)  =>    3 |   - name: "YAP"
+(yap)  =>    4 | - if: x > 5
+(yap)  =>    6 |     - print: "big"
+(yap) big
+ =>    7 |     - print: name
+(yap) YAP
+ =>   10 | - print: "done"
+(yap) done
+`
+	assert.Equal(t, expected, output)
+}
+
+func TestDebugNextStepsOverBlocks(t *testing.T) {
+	output := debug(t, nil, "n", "n", "n", "n")
+
+	assert.Contains(t, output, "=>    4 | - if: x > 5\n(yap) big\nYAP\n =>   10 | - print: \"done\"")
+}
+
+func TestDebugBreakpointAndContinue(t *testing.T) {
+	output := debug(t, []int{7}, "c", "vars", "c")
+
+	assert.Contains(t, output, "(yap) big\nBreakpoint at test-files/0012-debug.yap:7\n*=>    7 |     - print: name\n")
+	assert.Contains(t, output, "name = \"YAP\"\nx = 10\n")
+	assert.True(t, strings.HasSuffix(output, "(yap) YAP\ndone\n"))
+}
+
+func TestDebugModifyVariable(t *testing.T) {
+	// Changing x before the if takes the else branch
+	output := debug(t, nil, "b 4", "c", "set x 1", "p x > 5", "c")
+
+	assert.Contains(t, output, "Breakpoint set at test-files/0012-debug.yap:4\n")
+	assert.Contains(t, output, "x = 1\n(yap) false\n")
+	assert.True(t, strings.HasSuffix(output, "(yap) small\ndone\n"))
+}
+
+func TestDebugListAndErrors(t *testing.T) {
+	output := debug(t, nil, "b 5", "p missing", "frobnicate", "l", "q")
+
+	assert.Contains(t, output, "no statement on line 5\n")
+	assert.Contains(t, output, "error: undefined variable: missing\n")
+	assert.Contains(t, output, `unknown command "frobnicate"`)
+	assert.Contains(t, output, "\n =>    2 |   - x: 10\n")
+	assert.Contains(t, output, "\n       5 |   then:\n")
+	assert.NotContains(t, output, "done")
+}
+
+func TestDebugQuitOnEndOfInput(t *testing.T) {
+	output := debug(t, nil)
+
+	assert.Equal(t, " =>    2 |   - x: 10\n(yap) \n", output)
+}
@@ -0,0 +1,53 @@
+package test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/trace"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTraced(t *testing.T) {
+	fp := filepath.Join(test_util.TestFilesDir, test_util.IfThenElseYAP)
+	tracePath := filepath.Join(t.TempDir(), "trace.jsonl")
+
+	opts := commands.RunOptions{Trace: &commands.TraceOptions{Path: tracePath, Format: trace.FormatJSON}}
+	require.NoError(t, commands.RunCmdWithOptions(context.Background(), []string{fp}, opts, vm.WithStdout(io.Discard)))
+
+	data, err := os.ReadFile(tracePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `{"pc":0,"op":"SET","line":2,"var":"x","new":10}`)
+	assert.Contains(t, string(data), `"op":"JUMP_IF_FALSE","line":4,"cond":true}`)
+}
+
+func TestRunTracedInvalidLines(t *testing.T) {
+	fp := filepath.Join(test_util.TestFilesDir, test_util.IfThenElseYAP)
+	tracePath := filepath.Join(t.TempDir(), "trace.txt")
+
+	opts := commands.RunOptions{Trace: &commands.TraceOptions{Path: tracePath, Format: trace.FormatText, Lines: "9-1"}}
+	err := commands.RunCmdWithOptions(context.Background(), []string{fp}, opts)
+
+	assert.EqualError(t, err, `invalid line range "9-1"`)
+}
+
+func TestRunTracedUnknownFormatKeepsFile(t *testing.T) {
+	fp := filepath.Join(test_util.TestFilesDir, test_util.IfThenElseYAP)
+	tracePath := filepath.Join(t.TempDir(), "trace.txt")
+	require.NoError(t, os.WriteFile(tracePath, []byte("previous trace\n"), 0o644))
+
+	opts := commands.RunOptions{Trace: &commands.TraceOptions{Path: tracePath, Format: "xml"}}
+	err := commands.RunCmdWithOptions(context.Background(), []string{fp}, opts)
+	assert.EqualError(t, err, `unknown trace format "xml", expected text or json`)
+
+	data, err := os.ReadFile(tracePath)
+	require.NoError(t, err)
+	assert.Equal(t, "previous trace\n", string(data))
+}
@@ -0,0 +1,41 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverageMergesRuns(t *testing.T) {
+	fp := filepath.Join(test_util.TestFilesDir, test_util.IfThenElseYAP)
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "out.cov")
+	lcov := filepath.Join(dir, "lcov.info")
+
+	opts := commands.RunOptions{Coverage: profile}
+	for i := 0; i < 2; i++ {
+		require.NoError(t, commands.RunCmdWithOptions(context.Background(), []string{fp}, opts, vm.WithStdout(io.Discard)))
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, commands.CoverageCmd([]string{profile}, commands.CoverageOptions{LCOV: lcov}, &out))
+	report := out.String()
+
+	assert.Contains(t, report, "test-files/0009-if-then-else.yap: 71.4% of statements (5/7), 50.0% of branches (2/4)\n")
+	assert.Contains(t, report, "        2:    2:  - x: 10\n")
+	assert.Contains(t, report, "    #####:   11:        - print: \"it must be huge!\"\n")
+
+	data, err := os.ReadFile(lcov)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "BRDA:4,0,0,2\nBRDA:4,0,1,0\nBRDA:7,1,0,2\nBRDA:7,1,1,0\n")
+	assert.Contains(t, string(data), "LF:7\nLH:5\n")
+}
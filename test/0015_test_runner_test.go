@@ -0,0 +1,58 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAssertions(t *testing.T) {
+	fp := filepath.Join(test_util.TestFilesDir, test_util.AssertYAP)
+	var out bytes.Buffer
+
+	require.NoError(t, commands.RunCmd([]string{fp}, vm.WithStdout(&out)))
+	assert.Equal(t, "done\n", out.String())
+}
+
+func TestTestCmd(t *testing.T) {
+	dir := filepath.Join(test_util.TestFilesDir, test_util.TestsDir)
+	junit := filepath.Join(t.TempDir(), "report.xml")
+	var out bytes.Buffer
+
+	err := commands.TestCmd([]string{dir}, commands.TestOptions{JUnit: junit}, &out)
+	assert.EqualError(t, err, "2 of 3 test file(s) failed")
+
+	report := out.String()
+	assert.Contains(t, report, "FAIL test-files/0015-tests/error_test.yap (")
+	assert.Contains(t, report, "    test-files/0015-tests/error_test.yap:1:1: expected an error, but the block completed\n")
+	assert.Contains(t, report, "ok   test-files/0015-tests/math_test.yap (")
+	assert.Contains(t, report, "    test-files/0015-tests/nested/strings_test.yap:5:1: assertion failed: greeting should be goodbye\n"+
+		"    output:\n"+
+		"        hello\n")
+	assert.Contains(t, report, "\n1 passed, 2 failed\n")
+	assert.NotContains(t, report, "helper.yap")
+
+	data, err := os.ReadFile(junit)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<testsuites tests="3" failures="2"`)
+}
+
+func TestTestCmdRunFilter(t *testing.T) {
+	dir := filepath.Join(test_util.TestFilesDir, test_util.TestsDir)
+	var out bytes.Buffer
+
+	require.NoError(t, commands.TestCmd([]string{dir}, commands.TestOptions{Run: "math"}, &out))
+	assert.Contains(t, out.String(), "ok   test-files/0015-tests/math_test.yap (")
+	assert.Contains(t, out.String(), "\n1 passed, 0 failed\n")
+
+	err := commands.TestCmd([]string{dir}, commands.TestOptions{Run: "("}, io.Discard)
+	assert.ErrorContains(t, err, "invalid --run pattern")
+}
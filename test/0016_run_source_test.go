@@ -0,0 +1,40 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStdin(t *testing.T) {
+	var out bytes.Buffer
+	opts := commands.RunOptions{Stdin: strings.NewReader("- set:\n  - x: 4\n- print: x * 2\n")}
+
+	require.NoError(t, commands.RunCmdWithOptions(context.Background(), []string{commands.StdinArg}, opts, vm.WithStdout(&out)))
+	assert.Equal(t, "8\n", out.String())
+}
+
+func TestRunInline(t *testing.T) {
+	var out bytes.Buffer
+	opts := commands.RunOptions{Eval: `- print: "hi", 1 + 2`}
+
+	require.NoError(t, commands.RunCmdWithOptions(context.Background(), nil, opts, vm.WithStdout(&out)))
+	assert.Equal(t, "hi 3\n", out.String())
+}
+
+func TestRunInlineErrorsUseDisplayName(t *testing.T) {
+	opts := commands.RunOptions{Eval: "- print: missing"}
+
+	err := commands.RunCmdWithOptions(context.Background(), nil, opts)
+	assert.EqualError(t, err, "error running program: <inline>:1:1: error: undefined variable: missing")
+
+	opts.Coverage = "out.cov"
+	err = commands.RunCmdWithOptions(context.Background(), nil, opts)
+	assert.EqualError(t, err, "coverage can only be recorded for a program file")
+}
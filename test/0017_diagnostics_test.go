@@ -0,0 +1,115 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/diagnostics"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckJSON(t *testing.T) {
+	fp := filepath.Join(test_util.TestFilesDir, test_util.CheckErrorsYAP)
+	var out bytes.Buffer
+
+	err := commands.CheckCmd([]string{fp}, diagnostics.Options{Format: diagnostics.FormatJSON}, &out)
+	assert.EqualError(t, err, "check failed: 2 error(s)")
+	var reported *commands.ReportedError
+	assert.ErrorAs(t, err, &reported, "the report is on stdout already")
+
+	var report diagnostics.Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Equal(t, diagnostics.SchemaVersion, report.Version)
+	assert.Equal(t, diagnostics.Summary{Errors: 2, Text: "2 error(s)"}, report.Summary)
+	require.Len(t, report.Diagnostics, 2)
+
+	d := report.Diagnostics[0]
+	assert.Regexp(t, `^E\d{4}$`, d.Code)
+	assert.Equal(t, "error", d.Severity)
+	assert.Equal(t, `undefined variable "nmae"`, d.Message)
+	assert.Equal(t, &diagnostics.Position{File: fp, Line: 3, Column: 10}, d.Position)
+}
+
+func TestCheckSARIF(t *testing.T) {
+	fp := filepath.Join(test_util.TestFilesDir, test_util.CheckErrorsYAP)
+	var out bytes.Buffer
+
+	require.Error(t, commands.CheckCmd([]string{fp}, diagnostics.Options{Format: diagnostics.FormatSARIF}, &out))
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	require.Len(t, log.Runs[0].Results, 2)
+
+	result := log.Runs[0].Results[1]
+	assert.Equal(t, "error", result.Level)
+	assert.Regexp(t, `^E\d{4}$`, result.RuleID)
+	assert.Equal(t, filepath.ToSlash(fp), result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 5, result.Locations[0].PhysicalLocation.Region.StartLine)
+}
+
+func TestReportRunErrorJSON(t *testing.T) {
+	err := commands.RunCmdWithOptions(context.Background(), nil, commands.RunOptions{Eval: "- print: 1 / 0"})
+	require.Error(t, err)
+
+	var out bytes.Buffer
+	commands.ReportError(&out, err, diagnostics.Options{Format: diagnostics.FormatJSON})
+
+	var report diagnostics.Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Len(t, report.Diagnostics, 1)
+	assert.Equal(t, "runtime", report.Diagnostics[0].Phase)
+	assert.Equal(t, "division by zero", report.Diagnostics[0].Message)
+	assert.Equal(t, commands.InlineName, report.Diagnostics[0].Position.File)
+}
+
+// Errors that are not YAP errors, e.g. a missing file, keep the selected format
+func TestReportOtherErrorJSON(t *testing.T) {
+	var out bytes.Buffer
+	commands.ReportError(&out, errors.New("open missing.yap: no such file or directory"), diagnostics.Options{Format: diagnostics.FormatJSON})
+
+	var report diagnostics.Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Len(t, report.Diagnostics, 1)
+	assert.Empty(t, report.Diagnostics[0].Code)
+	assert.Equal(t, "open missing.yap: no such file or directory", report.Diagnostics[0].Message)
+}
+
+func TestCheckText(t *testing.T) {
+	fp := filepath.Join(test_util.TestFilesDir, test_util.CheckErrorsYAP)
+	var out bytes.Buffer
+
+	require.Error(t, commands.CheckCmd([]string{fp}, diagnostics.Options{Format: diagnostics.FormatText}, &out))
+	assert.Contains(t, out.String(), " --> "+fp+":3:10\n"+
+		"  |\n"+
+		"3 | - print: nmae\n"+
+		"  |          ^\n")
+	assert.True(t, strings.HasSuffix(out.String(), "\n2 error(s)\n"))
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/check"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// firstError returns the first error yap check or yap run report for src
+func firstError(src string) *yaperror.YapError {
+	var yerr *yaperror.YapError
+	prog, err := parser.NewParserFromBytes([]byte(src), "example.yap").Parse()
+	if err != nil {
+		errors.As(err, &yerr)
+		return yerr
+	}
+	if errs := check.Check(prog).Errors.Errors(); len(errs) > 0 {
+		return errs[0]
+	}
+	program, err := build.New().Build(prog.Statements)
+	if err == nil {
+		err = vm.New(program, vm.WithStdout(io.Discard)).Run()
+	}
+	errors.As(err, &yerr)
+	return yerr
+}
+
+func TestExplainExamples(t *testing.T) {
+	for _, code := range yaperror.ErrorCodes() {
+		e, _ := yaperror.Explain(code)
+		if e.Erroneous == "" {
+			continue
+		}
+		t.Run(code.String(), func(t *testing.T) {
+			yerr := firstError(e.Erroneous)
+			require.NotNil(t, yerr, "erroneous example does not fail")
+			assert.Equal(t, code, yerr.Code, yerr.Error())
+
+			assert.Nil(t, firstError(e.Corrected), "corrected example fails")
+		})
+	}
+}
+
+func TestExplainCmd(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, commands.ExplainCmd([]string{"e3001"}, &out))
+	assert.Contains(t, out.String(), "E3001: undefined variable\n\n")
+	assert.Contains(t, out.String(), "\nErroneous example:\n\n    - set:\n      - name: \"YAP\"\n    - print: nmae\n")
+
+	out.Reset()
+	require.NoError(t, commands.ExplainCmd(nil, &out))
+	assert.Contains(t, out.String(), "E2000  unexpected token\n")
+
+	assert.EqualError(t, commands.ExplainCmd([]string{"E9999"}, &out), "unknown error code E9999, run yap explain to list all codes")
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/diagnostics"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunImport(t *testing.T) {
+	fp := test_util.GetTestFilepath(filepath.Join(test_util.ImportDir, "main.yap"), "..")
+
+	var out bytes.Buffer
+	require.NoError(t, commands.RunCmdWithOptions(context.Background(), []string{fp}, commands.RunOptions{}, vm.WithStdout(&out)))
+	assert.Equal(t, "loading util\nhello from util 21\n42\n", out.String())
+}
+
+func TestRunImportFromSearchPath(t *testing.T) {
+	lib := test_util.GetTestFilepath(filepath.Join(test_util.ImportDir, "lib"), "..")
+	t.Setenv(build.SearchPathEnv, lib)
+
+	var out bytes.Buffer
+	opts := commands.RunOptions{Eval: "- import: \"util.yap\"\n- print: util.answer + 1\n"}
+	require.NoError(t, commands.RunCmdWithOptions(context.Background(), nil, opts, vm.WithStdout(&out)))
+	assert.Equal(t, "loading util\n22\n", out.String())
+}
+
+func TestRunImportCycle(t *testing.T) {
+	fp := test_util.GetTestFilepath(filepath.Join(test_util.ImportDir, "cycle_a.yap"), "..")
+
+	err := commands.RunCmdWithOptions(context.Background(), []string{fp}, commands.RunOptions{})
+
+	var yerr *yaperror.YapError
+	require.True(t, errors.As(err, &yerr), err)
+	assert.Equal(t, yaperror.ErrImportCycle, yerr.Code)
+	assert.Contains(t, yerr.Message, "cycle_a.yap -> "+filepath.Join(test_util.TestFilesDir, test_util.ImportDir, "cycle_b.yap"))
+}
+
+// check loads imports like run, so missing modules and cycles are reported
+// without running the program
+func TestCheckImportErrors(t *testing.T) {
+	tests := []struct {
+		file string
+		code yaperror.ErrorCode
+	}{
+		{"missing.yap", yaperror.ErrModuleNotFound},
+		{"cycle_a.yap", yaperror.ErrImportCycle},
+	}
+	for _, tt := range tests {
+		fp := test_util.GetTestFilepath(filepath.Join(test_util.ImportDir, tt.file), "..")
+		var out bytes.Buffer
+
+		err := commands.CheckCmd([]string{fp}, diagnostics.Options{Format: diagnostics.FormatJSON}, &out)
+		require.Error(t, err, tt.file)
+
+		var report diagnostics.Report
+		require.NoError(t, json.Unmarshal(out.Bytes(), &report), tt.file)
+		require.Len(t, report.Diagnostics, 1, tt.file)
+		assert.Equal(t, tt.code.String(), report.Diagnostics[0].Code, tt.file)
+	}
+
+	fp := test_util.GetTestFilepath(filepath.Join(test_util.ImportDir, "main.yap"), "..")
+	assert.NoError(t, commands.CheckCmd([]string{fp}, diagnostics.Options{}, &bytes.Buffer{}))
+}
+
+func TestCheckImportFromSearchPath(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "main.yap")
+	require.NoError(t, os.WriteFile(fp, []byte("- import: \"util.yap\"\n- print: util.answer\n"), 0o644))
+
+	require.Error(t, commands.CheckCmd([]string{fp}, diagnostics.Options{}, &bytes.Buffer{}))
+
+	t.Setenv(build.SearchPathEnv, test_util.GetTestFilepath(filepath.Join(test_util.ImportDir, "lib"), ".."))
+	assert.NoError(t, commands.CheckCmd([]string{fp}, diagnostics.Options{}, &bytes.Buffer{}))
+}
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/diagnostics"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMath(t *testing.T) {
+	fp := test_util.GetTestFilepath(test_util.MathYAP, "..")
+
+	var out bytes.Buffer
+	err := commands.RunCmdWithOptions(context.Background(), []string{fp}, commands.RunOptions{}, vm.WithStdout(&out))
+	assert.Contains(t, out.String(), "4611686018427387904\n")
+
+	var yerr *yaperror.YapError
+	require.True(t, errors.As(err, &yerr), err)
+	assert.Equal(t, yaperror.ErrInvalidType, yerr.Code)
+	assert.Equal(t, 16, yerr.Position.Line)
+	assert.Equal(t, 10, yerr.Position.Column)
+	assert.Equal(t, 26, yerr.Span.End.Column)
+}
+
+// Calls are only checked when the program runs
+func TestCheckMath(t *testing.T) {
+	fp := test_util.GetTestFilepath(test_util.MathYAP, "..")
+
+	var out bytes.Buffer
+	require.NoError(t, commands.CheckCmd([]string{fp}, diagnostics.Options{}, &out))
+}
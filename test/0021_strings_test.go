@@ -0,0 +1,36 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/diagnostics"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A failed conversion points at the call, also in machine-readable output
+func TestRunStringsConversionError(t *testing.T) {
+	fp := test_util.GetTestFilepath(test_util.StringsYAP, "..")
+
+	err := commands.RunCmdWithOptions(context.Background(), []string{fp}, commands.RunOptions{}, vm.WithStdout(&bytes.Buffer{}))
+	require.Error(t, err)
+
+	var out bytes.Buffer
+	commands.ReportError(&out, err, diagnostics.Options{Format: diagnostics.FormatJSON})
+
+	var report diagnostics.Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Len(t, report.Diagnostics, 1)
+	d := report.Diagnostics[0]
+	assert.Equal(t, "E4006", d.Code)
+	assert.Equal(t, `cannot convert "twelve" to int`, d.Message)
+	assert.Equal(t, 16, d.Position.Line)
+	assert.Equal(t, 10, d.Position.Column)
+	assert.Equal(t, 23, d.Span.End.Column)
+}
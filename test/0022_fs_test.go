@@ -0,0 +1,63 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reportYAP = `- import: "fs"
+- call: fs.write_lines("report.txt", fs.list_dir("."))
+- for: name
+  in: fs.read_lines("report.txt")
+  do:
+    - call: fs.append_line("report.txt", "seen " + name)
+- print: fs.read_lines("report.txt")
+`
+
+// An embedder keeps a program in a directory by running it with a RootFS
+func TestRunFSInRoot(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "input.txt"), []byte("x"), 0o644))
+	fsys, err := stdlib.NewRootFS(dir)
+	require.NoError(t, err)
+	defer fsys.Close()
+
+	var out bytes.Buffer
+	opts := commands.RunOptions{Eval: reportYAP}
+	require.NoError(t, commands.RunCmdWithOptions(context.Background(), nil, opts, vm.WithStdout(&out), vm.WithFS(fsys)))
+	assert.Equal(t, "[\"input.txt\", \"seen input.txt\"]\n", out.String())
+
+	data, err := os.ReadFile(filepath.Join(dir, "report.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "input.txt\nseen input.txt\n", string(data))
+
+	opts = commands.RunOptions{Eval: "- import: \"fs\"\n- call: fs.write_file(\"../escape.txt\", \"x\")\n"}
+	err = commands.RunCmdWithOptions(context.Background(), nil, opts, vm.WithFS(fsys))
+
+	var yerr *yaperror.YapError
+	require.True(t, errors.As(err, &yerr), err)
+	assert.Equal(t, yaperror.ErrIOError, yerr.Code)
+	assert.Contains(t, yerr.Message, `cannot write "../escape.txt"`)
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(dir), "escape.txt"))
+}
+
+// The loop reads the file once, so the lines it appends are not visited
+func TestRunFSInMemory(t *testing.T) {
+	fsys := stdlib.NewMemFS(map[string]string{"a.txt": "", "b.txt": ""})
+
+	var out bytes.Buffer
+	opts := commands.RunOptions{Eval: reportYAP}
+	require.NoError(t, commands.RunCmdWithOptions(context.Background(), nil, opts, vm.WithStdout(&out), vm.WithFS(fsys)))
+	assert.Equal(t, "[\"a.txt\", \"b.txt\", \"seen a.txt\", \"seen b.txt\"]\n", out.String())
+}
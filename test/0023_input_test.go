@@ -0,0 +1,42 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const greetYAP = `- input: name
+  prompt: "Name? "
+- print: "Hello " + name
+- print: read_all_stdin()
+`
+
+func TestRunInput(t *testing.T) {
+	var out bytes.Buffer
+	opts := commands.RunOptions{Eval: greetYAP}
+	stdin := strings.NewReader("Ada\nrest")
+	require.NoError(t, commands.RunCmdWithOptions(context.Background(), nil, opts, vm.WithStdout(&out), vm.WithStdin(stdin)))
+	assert.Equal(t, "Name? Hello Ada\nrest\n", out.String())
+}
+
+// Reading past the end of the input is an error of its own
+func TestRunInputEOF(t *testing.T) {
+	var out bytes.Buffer
+	opts := commands.RunOptions{Eval: greetYAP}
+	err := commands.RunCmdWithOptions(context.Background(), nil, opts, vm.WithStdout(&out), vm.WithStdin(strings.NewReader("")))
+
+	var yerr *yaperror.YapError
+	require.True(t, errors.As(err, &yerr), err)
+	assert.Equal(t, yaperror.ErrEndOfInput, yerr.Code)
+	assert.Equal(t, 1, yerr.Position.Line)
+	assert.Equal(t, "Name? ", out.String())
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const greetArgsYAP = `- import: "strings"
+- if: strings.join(args, "") == ""
+  then:
+    - print: "usage: greet name..."
+      stderr: True
+    - exit: 2
+- for: name
+  in: args
+  do:
+    - print: env_or("GREETING", "Hello"), name
+`
+
+// The arguments after the file are the program's args
+func TestRunArgs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "greet.yap")
+	require.NoError(t, os.WriteFile(file, []byte(greetArgsYAP), 0o644))
+	lookup := func(name string) (string, bool) { return "Hi", name == "GREETING" }
+
+	var out bytes.Buffer
+	require.NoError(t, commands.RunCmd([]string{file, "Ada", "--loud"}, vm.WithStdout(&out), vm.WithEnvLookup(lookup)))
+	assert.Equal(t, "Hi Ada\nHi --loud\n", out.String())
+
+	var stderr bytes.Buffer
+	err := commands.RunCmd([]string{file}, vm.WithStdout(&out), vm.WithStderr(&stderr))
+	var exit *commands.ExitError
+	require.True(t, errors.As(err, &exit), err)
+	assert.Equal(t, 2, exit.Code)
+	assert.Equal(t, "usage: greet name...\n", stderr.String())
+}
+
+// Inline source gets all arguments, and an exit code survives attached tools
+func TestRunInlineArgsExit(t *testing.T) {
+	var out bytes.Buffer
+	opts := commands.RunOptions{Eval: "- print: args\n- exit: 5\n", Trace: &commands.TraceOptions{Path: filepath.Join(t.TempDir(), "trace.txt"), Format: trace.FormatText}}
+	err := commands.RunCmdWithOptions(context.Background(), []string{"a", "b"}, opts, vm.WithStdout(&out))
+
+	assert.Equal(t, &commands.ExitError{Code: 5}, err)
+	assert.Equal(t, "[\"a\", \"b\"]\n", out.String())
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/ir"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runsPerProgram is how many goroutines execute each compiled program at once
+const runsPerProgram = 8
+
+type compiledTestFile struct {
+	name    string
+	program []ir.Instruction
+	input   string // stdin of every run, from the .in golden file if any
+	output  string // expected output of a single run
+	err     string // expected run error, empty if the program succeeds
+}
+
+// runProgram executes program in its own VM with input as its stdin and
+// returns what it printed
+func runProgram(program []ir.Instruction, input string) (string, string) {
+	var out, errOut bytes.Buffer
+	v := vm.New(program, vm.WithStdin(strings.NewReader(input)), vm.WithStdout(&out), vm.WithStderr(&errOut))
+	if err := v.Run(); err != nil {
+		return out.String() + errOut.String(), err.Error()
+	}
+	return out.String() + errOut.String(), ""
+}
+
+// Compiles every program in test-files once and runs each of them from many
+// goroutines at the same time. Run with -race to detect shared mutable state.
+func TestConcurrentRuns(t *testing.T) {
+	entries, err := os.ReadDir(test_util.TestFilesDir)
+	require.NoError(t, err)
+
+	files := []*compiledTestFile{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), commands.FileExtYAP) {
+			continue
+		}
+
+		p := parser.NewParser(filepath.Join(test_util.TestFilesDir, entry.Name()))
+		ast, err := p.Parse()
+		if err != nil {
+			// Programs that fail to parse have nothing to run
+			continue
+		}
+		program, err := build.New().Build(ast.Statements)
+		require.NoError(t, err, entry.Name())
+
+		input, err := os.ReadFile(filepath.Join(test_util.TestFilesDir, strings.TrimSuffix(entry.Name(), commands.FileExtYAP)+".in"))
+		if err != nil && !os.IsNotExist(err) {
+			require.NoError(t, err, entry.Name())
+		}
+
+		output, runErr := runProgram(program, string(input))
+		files = append(files, &compiledTestFile{
+			name:    entry.Name(),
+			program: program,
+			input:   string(input),
+			output:  output,
+			err:     runErr,
+		})
+	}
+	require.NotEmpty(t, files)
+
+	var wg sync.WaitGroup
+	for _, file := range files {
+		for i := 0; i < runsPerProgram; i++ {
+			wg.Add(1)
+			go func(file *compiledTestFile) {
+				defer wg.Done()
+
+				output, runErr := runProgram(file.program, file.input)
+				assert.Equal(t, file.output, output, file.name)
+				assert.Equal(t, file.err, runErr, file.name)
+			}(file)
+		}
+	}
+	wg.Wait()
+}
+
+// Runs the full command for every program concurrently, each into its own buffer
+func TestConcurrentRunCmd(t *testing.T) {
+	expected := map[string]string{
+		test_util.OneLinePrintYAP:      "hello world\n",
+		test_util.SetPrintYAP:          "5\n10\n",
+		test_util.SetPrintBinaryExpYAP: "5\n20\nhello world!\n",
+		test_util.IfThenElseYAP:        "x is big\nwell not that big\n",
+		test_util.PrintMultipleYAP:     "name: YAP\nx = 10\n1, 2, 3\nno newline here\n",
+	}
+
+	var wg sync.WaitGroup
+	for name, want := range expected {
+		for i := 0; i < runsPerProgram; i++ {
+			wg.Add(1)
+			go func(name, want string) {
+				defer wg.Done()
+
+				var out bytes.Buffer
+				args := []string{filepath.Join(test_util.TestFilesDir, name)}
+				assert.NoError(t, commands.RunCmd(args, vm.WithStdout(&out)), name)
+				assert.Equal(t, want, out.String(), name)
+			}(name, want)
+		}
+	}
+	wg.Wait()
+}
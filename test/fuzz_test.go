@@ -0,0 +1,49 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/stdlib"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+)
+
+const (
+	fuzzFile     = "fuzz.yap"
+	fuzzMaxSteps = 10_000
+)
+
+// Compiling and running any input must not panic, and every failure must be
+// a positioned error
+func FuzzCompileRun(f *testing.F) {
+	test_util.AddFuzzSeeds(f, "..")
+	f.Fuzz(func(t *testing.T, src []byte) {
+		ast, err := parser.NewParserFromReader(bytes.NewReader(src), fuzzFile).Parse()
+		if err != nil {
+			test_util.RequireYapError(t, err, fuzzFile)
+			return
+		}
+		program, err := build.New().Build(ast.Statements)
+		if err != nil {
+			test_util.RequireYapError(t, err, fuzzFile)
+			return
+		}
+
+		// Fuzzed programs must not touch the real filesystem or wait for input
+		v := vm.New(program,
+			vm.WithFS(stdlib.NewMemFS(nil)),
+			vm.WithStdin(strings.NewReader("")),
+			vm.WithStdout(io.Discard),
+			vm.WithStderr(io.Discard),
+			vm.WithMaxSteps(fuzzMaxSteps),
+		)
+		if yerr := v.Run(); yerr != nil {
+			test_util.RequireYapError(t, yerr, fuzzFile)
+		}
+	})
+}
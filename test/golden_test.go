@@ -0,0 +1,134 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rlamalama/YAP/cmd/yap/commands"
+	"github.com/rlamalama/YAP/internal/backend/build"
+	"github.com/rlamalama/YAP/internal/backend/vm"
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/rlamalama/YAP/internal/frontend/parser"
+	test_util "github.com/rlamalama/YAP/test/test-util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Regenerate the golden files with: go test ./test -run TestGolden -update
+var update = flag.Bool("update", false, "rewrite the golden .out and .err files of test-files")
+
+const (
+	goldenOutExt = ".out"
+	goldenErrExt = ".err"
+	goldenInExt  = ".in"
+)
+
+// Runs every program in test-files and compares what it printed with the
+// sibling .out file and the diagnostics it raised with the .err file. A
+// missing .err file means no diagnostics are expected. Programs read their
+// input from the sibling .in file, or get an empty input without one
+func TestGolden(t *testing.T) {
+	files := []string{}
+	err := filepath.WalkDir(test_util.TestFilesDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(p, commands.FileExtYAP) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		name, _ := filepath.Rel(test_util.TestFilesDir, file)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			base := strings.TrimSuffix(file, commands.FileExtYAP)
+			output, diagnostics := runGolden(file, readGolden(t, base+goldenInExt))
+
+			if *update {
+				writeGolden(t, base+goldenOutExt, output, true)
+				writeGolden(t, base+goldenErrExt, diagnostics, false)
+				return
+			}
+
+			assert.Equal(t, readGolden(t, base+goldenOutExt), output, "stdout")
+			assert.Equal(t, readGolden(t, base+goldenErrExt), diagnostics, "diagnostics")
+		})
+	}
+}
+
+// runGolden compiles and runs file with input. It returns everything the
+// program printed, stdout and stderr interleaved, and its diagnostics one per
+// line
+func runGolden(file, input string) (string, string) {
+	ast, err := parser.NewParser(file).Parse()
+	if err != nil {
+		return "", formatDiagnostics(err)
+	}
+	program, err := build.New().Build(ast.Statements)
+	if err != nil {
+		return "", formatDiagnostics(err)
+	}
+
+	var out bytes.Buffer
+	v := vm.New(program, vm.WithStdin(strings.NewReader(input)), vm.WithStdout(&out), vm.WithStderr(&out))
+	if yerr := v.Run(); yerr != nil {
+		return out.String(), formatDiagnostics(yerr)
+	}
+	return out.String(), ""
+}
+
+// formatDiagnostics writes each error as "line:col: severity[code]: message".
+// File names are left out so goldens do not depend on the working directory
+func formatDiagnostics(err error) string {
+	var yerrs []*yaperror.YapError
+	var list *yaperror.ErrorList
+	var yerr *yaperror.YapError
+	switch {
+	case errors.As(err, &list):
+		yerrs = list.Errors()
+	case errors.As(err, &yerr):
+		yerrs = []*yaperror.YapError{yerr}
+	default:
+		return fmt.Sprintf("error: %v\n", err)
+	}
+
+	var sb strings.Builder
+	for _, e := range yerrs {
+		fmt.Fprintf(&sb, "%d:%d: %s[%d]: %s\n", e.Position.Line, e.Position.Column, e.Severity, e.Code, e.Message)
+	}
+	return sb.String()
+}
+
+func readGolden(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ""
+	}
+	require.NoError(t, err)
+	return string(data)
+}
+
+// writeGolden writes content to path. Empty optional goldens are removed
+// instead of written
+func writeGolden(t *testing.T, path, content string, required bool) {
+	t.Helper()
+	if content == "" && !required {
+		err := os.Remove(path)
+		if !errors.Is(err, fs.ErrNotExist) {
+			require.NoError(t, err)
+		}
+		return
+	}
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
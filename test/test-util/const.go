@@ -19,4 +19,13 @@ const (
 	EmptyThenYAP             = "0009-empty-then.yap"
 	HangingElseYAP           = "0009-hanging-else.yap"
 	HangingThenYAP           = "0009-hanging-then.yap"
+	PrintMultipleYAP         = "0010-print-multiple.yap"
+	FmtUnformattedYAP        = "0011-fmt-unformatted.yap"
+	DebugYAP                 = "0012-debug.yap"
+	AssertYAP                = "0015-assert.yap"
+	TestsDir                 = "0015-tests"
+	CheckErrorsYAP           = "0017-check-errors.yap"
+	ImportDir                = "0019-import"
+	MathYAP                  = "0020-math.yap"
+	StringsYAP               = "0021-strings.yap"
 )
@@ -0,0 +1,50 @@
+package test_util
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yaperror "github.com/rlamalama/YAP/internal/error"
+	"github.com/stretchr/testify/require"
+)
+
+// AddFuzzSeeds adds every .yap program in test-files to the seed corpus of f
+func AddFuzzSeeds(f *testing.F, prefix string) {
+	dir := filepath.Join(prefix, TestDir, TestFilesDir)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".yap") {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		f.Add(data)
+		return nil
+	})
+	require.NoError(f, err)
+}
+
+// RequireYapError fails t unless err is a *YapError, or a list of them, that
+// points to a line and column of file
+func RequireYapError(t *testing.T, err error, file string) {
+	t.Helper()
+	var list *yaperror.ErrorList
+	if errors.As(err, &list) {
+		require.NotZero(t, list.Len(), "empty error list")
+		for _, e := range list.Errors() {
+			RequireYapError(t, e, file)
+		}
+		return
+	}
+
+	var yerr *yaperror.YapError
+	require.True(t, errors.As(err, &yerr), "%T is not a *YapError: %v", err, err)
+	require.Equal(t, file, yerr.Position.File, "error %q has the wrong file", yerr.Message)
+	require.GreaterOrEqual(t, yerr.Position.Line, 1, "error %q has no line", yerr.Message)
+	require.GreaterOrEqual(t, yerr.Position.Column, 1, "error %q has no column", yerr.Message)
+}
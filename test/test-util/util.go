@@ -1,8 +1,6 @@
 package test_util
 
 import (
-	"bytes"
-	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -23,29 +21,3 @@ func OpenTestFile(t *testing.T, testFile string, prefix string) *os.File {
 func GetTestFilepath(testFile string, prefix string) string {
 	return filepath.Join(prefix, TestDir, TestFilesDir, testFile)
 }
-
-func CaptureStdout(t *testing.T, fn func()) string {
-	t.Helper()
-
-	old := os.Stdout
-
-	r, w, err := os.Pipe()
-	if err != nil {
-		t.Fatalf("pipe: %v", err)
-	}
-
-	os.Stdout = w
-
-	fn()
-
-	w.Close()
-	os.Stdout = old
-
-	var buf bytes.Buffer
-	_, err = io.Copy(&buf, r)
-	if err != nil {
-		t.Fatalf("copy: %v", err)
-	}
-
-	return buf.String()
-}